@@ -0,0 +1,62 @@
+// Package checkpoint persists the progress of a crawl pass so a pass that was
+// interrupted, e.g. by a restart of the crawler, can be resumed where it
+// stopped.
+package checkpoint
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/report"
+)
+
+// Checkpoint is the progress of an unfinished crawl pass.
+type Checkpoint struct {
+	// The pass the checkpoint belongs to.
+	PassID uuid.UUID
+
+	// The [FromID, ToID) range of links crawled by the pass.
+	FromID uuid.UUID
+	ToID   uuid.UUID
+
+	// Links retrieved before this time are crawled by a pass that scans the
+	// graph. It is kept so that a resumed pass crawls the same links.
+	RetrievedBefore time.Time
+
+	// Every link up to and including LastID, in the order the pass reads
+	// them from the graph, has been processed. uuid.Nil if none has.
+	LastID uuid.UUID
+
+	// The lease of the frontier batch of the pass. Zero if the pass scans
+	// the graph.
+	LeasedUntil time.Time
+
+	// The counts of the pass so far, StartedAt is the start of the pass.
+	Counts report.Pass
+}
+
+// Store is implemented by persistent checkpoint backends.
+type Store interface {
+	// LoadCheckpoint returns the checkpoint of the unfinished pass over the
+	// [fromID, toID) range, nil if there is none.
+	LoadCheckpoint(fromID, toID uuid.UUID) (*Checkpoint, error)
+
+	// SaveCheckpoint stores the checkpoint of a pass, replacing the
+	// previous checkpoint of the same pass.
+	SaveCheckpoint(c *Checkpoint) error
+
+	// ClearCheckpoint removes the checkpoint of a finished pass.
+	ClearCheckpoint(passID uuid.UUID) error
+}
+
+// Next returns the smallest ID after id, the start of the range of links that
+// a resumed pass still has to read.
+func Next(id uuid.UUID) uuid.UUID {
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i]++
+		if id[i] != 0 {
+			break
+		}
+	}
+	return id
+}
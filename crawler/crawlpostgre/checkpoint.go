@@ -0,0 +1,77 @@
+package crawlpostgre
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/checkpoint"
+)
+
+var _ checkpoint.Store = (*postgre)(nil)
+
+// LoadCheckpoint implements checkpoint.Store.
+func (p *postgre) LoadCheckpoint(fromID, toID uuid.UUID) (*checkpoint.Checkpoint, error) {
+	var c checkpoint.Checkpoint
+	var leasedUntil sql.NullTime
+	var counts []byte
+	err := p.db.QueryRowxContext(context.TODO(), loadCheckpointQuery, fromID, toID).Scan(
+		&c.PassID,
+		&c.FromID,
+		&c.ToID,
+		&c.RetrievedBefore,
+		&c.LastID,
+		&leasedUntil,
+		&counts,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load checkpoint: %v", err)
+	}
+	if err := json.Unmarshal(counts, &c.Counts); err != nil {
+		return nil, fmt.Errorf("load checkpoint: %v", err)
+	}
+	c.LeasedUntil = leasedUntil.Time
+	return &c, nil
+}
+
+// SaveCheckpoint implements checkpoint.Store.
+func (p *postgre) SaveCheckpoint(c *checkpoint.Checkpoint) error {
+	counts, err := json.Marshal(c.Counts)
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %v", err)
+	}
+	var leasedUntil sql.NullTime
+	if !c.LeasedUntil.IsZero() {
+		leasedUntil = sql.NullTime{Time: c.LeasedUntil.UTC(), Valid: true}
+	}
+
+	_, err = p.db.ExecContext(context.TODO(), saveCheckpointQuery,
+		c.PassID,
+		c.FromID,
+		c.ToID,
+		c.RetrievedBefore.UTC(),
+		c.LastID,
+		leasedUntil,
+		counts,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ClearCheckpoint implements checkpoint.Store.
+func (p *postgre) ClearCheckpoint(passID uuid.UUID) error {
+	_, err := p.db.ExecContext(context.TODO(), clearCheckpointQuery, passID)
+	if err != nil {
+		return fmt.Errorf("clear checkpoint: %v", err)
+	}
+	return nil
+}
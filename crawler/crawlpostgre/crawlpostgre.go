@@ -0,0 +1,36 @@
+// Package crawlpostgre stores the crawler's own state in Postgres. It does not
+// read the tables of the graph and index services, the links of the frontier
+// and their PageRank scores are handed to it by the crawler.
+package crawlpostgre
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type postgre struct {
+	db *sqlx.DB
+}
+
+// New creates the crawler store and migrates its tables.
+func New(db *sqlx.DB) (*postgre, error) {
+	p := postgre{
+		db: db,
+	}
+
+	if err := p.migrate(); err != nil {
+		return nil, fmt.Errorf("crawlpostgre migrate: %v", err)
+	}
+	return &p, nil
+}
+
+func (p *postgre) migrate() error {
+	for _, query := range migrations {
+		if _, err := p.db.ExecContext(context.TODO(), query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,497 @@
+package crawlpostgre
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/event"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/report"
+	"github.com/odit-bit/se/crawler/submission"
+	"github.com/odit-bit/se/crawler/trap"
+)
+
+var maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+
+var dropTables = `
+	DROP TABLE IF EXISTS frontier, crawl_state, crawl_passes, crawl_checkpoints, crawl_trap_hosts, crawl_events, crawl_submissions CASCADE;
+`
+
+func Test_crawlpostgre(t *testing.T) {
+	// IMPORT !!
+	// _ "github.com/jackc/pgx/v5/stdlib"
+	db, err := sqlx.Connect("pgx", "host=localhost dbname=postgres password=test user=postgres")
+	if err != nil {
+		t.Fatal("open db conn:", err)
+	}
+	defer db.Close()
+
+	setup := func(t *testing.T) *postgre {
+		p, err := New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	teardown := func() { _, _ = db.ExecContext(context.TODO(), dropTables) }
+
+	t.Run("frontier priority order", func(t *testing.T) {
+		defer teardown()
+		test_frontier_priority(t, setup(t))
+	})
+	t.Run("frontier lease and ack", func(t *testing.T) {
+		defer teardown()
+		test_frontier_lease(t, setup(t))
+	})
+	t.Run("frontier refresh range and batched push", func(t *testing.T) {
+		defer teardown()
+		test_frontier_range(t, setup(t))
+	})
+	t.Run("crawl state", func(t *testing.T) {
+		defer teardown()
+		test_crawl_state(t, setup(t))
+	})
+	t.Run("pass reports", func(t *testing.T) {
+		defer teardown()
+		test_pass_reports(t, setup(t))
+	})
+	t.Run("pass checkpoints", func(t *testing.T) {
+		defer teardown()
+		test_checkpoints(t, setup(t))
+	})
+	t.Run("trap hosts", func(t *testing.T) {
+		defer teardown()
+		test_trap_hosts(t, setup(t))
+	})
+	t.Run("event outbox", func(t *testing.T) {
+		defer teardown()
+		test_event_outbox(t, setup(t))
+	})
+	t.Run("submissions", func(t *testing.T) {
+		defer teardown()
+		test_submissions(t, setup(t))
+	})
+}
+
+func newLink(url string, retrievedAt time.Time) frontier.Link {
+	return frontier.Link{ID: uuid.New(), URL: url, RetrievedAt: retrievedAt}
+}
+
+func scoring(recrawl time.Duration) frontier.Scoring {
+	return frontier.Scoring{Weights: frontier.DefaultWeights, RecrawlInterval: recrawl}
+}
+
+func test_frontier_priority(t *testing.T, p *postgre) {
+	recrawl := 24 * time.Hour
+	now := time.Now()
+
+	seed := newLink("https://seed.example.com", time.Time{})
+	crawled := newLink("https://crawled.example.com", now.Add(-2*recrawl))
+	crawled.Rank = 0.1
+	ranked := newLink("https://ranked.example.com", now.Add(-2*recrawl))
+	ranked.Rank = 1
+	fresh := newLink("https://fresh.example.com", now)
+
+	if err := p.Refresh([]frontier.Link{seed, crawled, ranked, fresh}, scoring(recrawl)); err != nil {
+		t.Fatal(err)
+	}
+	// the seed is submitted by a user
+	if _, err := p.Submit(seed.ID, seed.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Refresh([]frontier.Link{seed, crawled, ranked, fresh}, scoring(recrawl)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []uuid.UUID{seed.ID, ranked.ID, crawled.ID}
+	if len(entries) != len(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", len(entries), len(expect), "fresh link should not be due")
+	}
+	for i, e := range entries {
+		if e.LinkID != expect[i] {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", e.URL, expect[i], fmt.Sprint("wrong order at ", i))
+		}
+	}
+	if !entries[0].Submitted || entries[1].Submitted {
+		t.Fatalf("\ngot:%v \nmessage:%v", entries, "only the submitted link is a submission")
+	}
+}
+
+func test_frontier_lease(t *testing.T, p *postgre) {
+	la := newLink("https://a.example.com", time.Time{})
+	a := la.ID
+	if err := p.Refresh([]frontier.Link{la}, scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// newly discovered link keeps the smallest depth
+	b := uuid.New()
+	err := p.Push([]frontier.Entry{{LinkID: b, URL: "https://b.example.com", Depth: 3}}, scoring(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Push([]frontier.Entry{{LinkID: b, URL: "https://b.example.com", Depth: 1}}, scoring(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a link the refresh added before the crawler pushed it takes the
+	// pushed depth
+	lc := newLink("https://c.example.com", time.Time{})
+	if err := p.Refresh([]frontier.Link{lc}, scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	err = p.Push([]frontier.Entry{{LinkID: lc.ID, URL: lc.URL, Depth: 2}}, scoring(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(entries), 3)
+	}
+	for _, e := range entries {
+		if e.LinkID == b && e.Depth != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v", e.Depth, 1)
+		}
+		if e.LinkID == lc.ID && e.Depth != 2 {
+			t.Fatalf("\ngot:%v \nexpect:%v", e.Depth, 2)
+		}
+	}
+
+	// leased entries are not handed out twice
+	entries, err = p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", len(entries), 0, "leased entries dequeued again")
+	}
+
+	// acknowledged entries come back when due
+	if err := p.Ack(a, time.Now().Add(-time.Second), scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Ack(b, time.Now().Add(time.Hour), scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].LinkID != a {
+		t.Fatalf("\ngot:%v \nexpect:%v", entries, a)
+	}
+
+	// a sitemap hint makes a scheduled entry due again
+	err = p.Push([]frontier.Entry{{LinkID: b, URL: "https://b.example.com", Depth: 1, DueAt: time.Now().Add(-time.Second)}}, scoring(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].LinkID != b {
+		t.Fatalf("\ngot:%v \nexpect:%v", entries, b)
+	}
+}
+
+func test_frontier_range(t *testing.T, p *postgre) {
+	a := newLink("https://a.example.com", time.Time{})
+	b := newLink("https://b.example.com", time.Time{})
+	from, to := a.ID, b.ID
+	if bytes.Compare(from[:], to[:]) > 0 {
+		from, to = to, from
+	}
+	if err := p.Refresh([]frontier.Link{a, b}, scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the entries in the dequeued range are leased
+	entries, err := p.Dequeue(from, to, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].LinkID != from {
+		t.Fatalf("\ngot:%v \nexpect:%v", entries, from)
+	}
+
+	// the entries of a push are written at once, the same link is merged
+	c, d := uuid.New(), uuid.New()
+	err = p.Push([]frontier.Entry{
+		{LinkID: d, URL: "https://d.example.com", Depth: 2},
+		{LinkID: c, URL: "https://c.example.com", Depth: 4},
+		{LinkID: c, URL: "https://c.example.com", Depth: 1},
+	}, scoring(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].LinkID != to {
+		t.Fatalf("\ngot:%v \nexpect:%v", entries, to)
+	}
+	// a new entry is scored when it is pushed, the shallower link first
+	if entries[1].LinkID != c || entries[1].Depth != 1 || entries[1].Priority <= entries[2].Priority {
+		t.Fatalf("\ngot:%v \nmessage:%v", entries, "expected c at depth 1 before the deeper link")
+	}
+}
+
+func test_crawl_state(t *testing.T, p *postgre) {
+	la := newLink("https://a.example.com", time.Now().Add(-time.Hour))
+	a := la.ID
+
+	_, found, err := p.LookupState(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Fatalf("\ngot:%v \nexpect:%v", found, false)
+	}
+
+	expect := recrawl.State{
+		ETag:         `"abc"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+		ContentHash:  1 << 63,
+		Interval:     90 * time.Minute,
+		NextCrawlAt:  time.Now().Add(90 * time.Minute).UTC().Truncate(time.Microsecond),
+	}
+	if err := p.SaveState(a, expect); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := p.LookupState(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || got != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+
+	// the frontier picks up the adaptive schedule instead of the fixed
+	// recrawl interval
+	if err := p.Refresh([]frontier.Link{la}, scoring(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", len(entries), 0, "link is not due before next_crawl_at")
+	}
+}
+
+func test_pass_reports(t *testing.T, p *postgre) {
+	start := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		r := &report.Pass{
+			StartedAt:  start.Add(time.Duration(i) * time.Hour),
+			FinishedAt: start.Add(time.Duration(i)*time.Hour + time.Minute),
+			Fetched:    int64(i),
+			Bytes:      1024,
+		}
+		if err := p.SavePass(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	passes, err := p.RecentPasses(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passes) != 2 || passes[0].Fetched != 2 || passes[1].Fetched != 1 {
+		t.Fatalf("\ngot:%v \nmessage:%v", passes, "expected the two newest passes first")
+	}
+	if d := passes[0].Duration(); d != time.Minute {
+		t.Fatalf("\ngot:%v \nexpect:%v", d, time.Minute)
+	}
+}
+
+func test_checkpoints(t *testing.T, p *postgre) {
+	la, lb := newLink("https://a.example.com", time.Time{}), newLink("https://b.example.com", time.Time{})
+	a, b := la.ID, lb.ID
+	if err := p.Refresh([]frontier.Link{la, lb}, scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].LeasedUntil.IsZero() {
+		t.Fatalf("\ngot:%v \nmessage:%v", entries, "expected two leased entries")
+	}
+
+	c, err := p.LoadCheckpoint(uuid.Nil, maxUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != nil {
+		t.Fatalf("\ngot:%v \nexpect:%v", c, nil)
+	}
+
+	expect := &checkpoint.Checkpoint{
+		PassID:          uuid.New(),
+		FromID:          uuid.Nil,
+		ToID:            maxUUID,
+		RetrievedBefore: time.Now().UTC().Truncate(time.Microsecond),
+		LastID:          a,
+		LeasedUntil:     entries[0].LeasedUntil,
+		Counts:          report.Pass{StartedAt: time.Now().UTC().Truncate(time.Second), Links: 1, Fetched: 1},
+	}
+	if err := p.SaveCheckpoint(expect); err != nil {
+		t.Fatal(err)
+	}
+	expect.LastID = b
+	if err := p.SaveCheckpoint(expect); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.LoadCheckpoint(uuid.Nil, maxUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || fmt.Sprint(*got) != fmt.Sprint(*expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+
+	// the unacknowledged entries of the interrupted pass are handed out again
+	if err := p.Ack(a, time.Now().Add(time.Hour), scoring(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(uuid.Nil, maxUUID, got.LeasedUntil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = p.Dequeue(uuid.Nil, maxUUID, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].LinkID != b {
+		t.Fatalf("\ngot:%v \nexpect:%v", entries, b)
+	}
+
+	if err := p.ClearCheckpoint(expect.PassID); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := p.LoadCheckpoint(uuid.Nil, maxUUID); err != nil || c != nil {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", c, err, nil)
+	}
+}
+
+func test_trap_hosts(t *testing.T, p *postgre) {
+	at := time.Now().UTC().Truncate(time.Second)
+	if err := p.FlagHost(trap.Flag{Host: "a.com", Reason: trap.URLPattern, FlaggedAt: at}); err != nil {
+		t.Fatal(err)
+	}
+	// the first flag of a host is kept
+	if err := p.FlagHost(trap.Flag{Host: "a.com", Reason: trap.HostBudget, FlaggedAt: at.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := p.FlaggedHosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 1 || flags[0].Reason != trap.URLPattern || !flags[0].FlaggedAt.Equal(at) {
+		t.Fatalf("\ngot:%v \nexpect:%v", flags, trap.URLPattern)
+	}
+}
+
+func test_event_outbox(t *testing.T, p *postgre) {
+	at := time.Now().UTC().Truncate(time.Second)
+	fetched := &event.PageFetched{Link: event.Link{ID: uuid.New(), URL: "https://a.com/", At: at}, Status: 200}
+	indexed := &event.DocumentIndexed{Link: event.Link{ID: fetched.ID, URL: fetched.URL, At: at}, Title: "a"}
+	for _, e := range []event.Event{fetched, indexed} {
+		if err := p.Publish(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := p.UnpublishedEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != event.PageFetchedType || events[1].Type != event.DocumentIndexedType {
+		t.Fatalf("\ngot:%v \nmessage:%v", events, "expected the events in publishing order")
+	}
+	e, err := event.Decode(events[1].Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.(*event.DocumentIndexed); got.Title != "a" || got.ID != fetched.ID {
+		t.Fatalf("\ngot:%+v \nexpect:%+v", got, indexed)
+	}
+
+	if err := p.MarkPublished(events[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	events, err = p.UnpublishedEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != event.DocumentIndexedType {
+		t.Fatalf("\ngot:%v \nmessage:%v", events, "published event still pending")
+	}
+}
+
+func test_submissions(t *testing.T, p *postgre) {
+	first, second := uuid.New(), uuid.New()
+
+	sub, err := p.Submit(first, "https://a.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Submit(second, "https://b.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := p.Claim(10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != sub.ID || claimed[0].State != submission.Fetching {
+		t.Fatalf("\ngot:%v \nmessage:%v", claimed, "expected both submissions, oldest first")
+	}
+	// leased submissions are not claimed again
+	if claimed, err := p.Claim(10, time.Minute); err != nil || len(claimed) != 0 {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", claimed, err, 0)
+	}
+	// released submissions are
+	if err := p.Requeue([]uuid.UUID{claimed[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := p.Claim(10, time.Minute); err != nil || len(again) != 1 || again[0].ID != claimed[1].ID {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", again, err, claimed[1].ID)
+	}
+
+	if err := p.Finish(sub.ID, submission.Failed, "HTTP 404"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Submission(sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.State != submission.Failed || got.Reason != "HTTP 404" || got.LinkID != first {
+		t.Fatalf("\ngot:%+v \nexpect:%v", got, submission.Failed)
+	}
+	if got, err := p.Submission(uuid.New()); err != nil || got != nil {
+		t.Fatalf("\ngot:%v %v \nmessage:%v", got, err, "unknown submission found")
+	}
+}
@@ -0,0 +1,201 @@
+package crawlpostgre
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/frontier"
+)
+
+var _ frontier.Store = (*postgre)(nil)
+var _ frontier.Releaser = (*postgre)(nil)
+
+// Refresh implements frontier.Store.
+func (p *postgre) Refresh(links []frontier.Link, sc frontier.Scoring) error {
+	// a statement can not update the same row twice
+	byID := make(map[uuid.UUID]frontier.Link, len(links))
+	for _, l := range links {
+		byID[l.ID] = l
+	}
+	links = links[:0:0]
+	for _, l := range byID {
+		links = append(links, l)
+	}
+	sort.Slice(links, func(i, j int) bool { return bytes.Compare(links[i].ID[:], links[j].ID[:]) < 0 })
+
+	tx, err := p.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return fmt.Errorf("frontier refresh: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	w := sc.Weights
+	for rows := links; len(rows) > 0; {
+		n := min(len(rows), max_batch_rows)
+		args := make([]any, 0, 4+6*n)
+		args = append(args, w.Pagerank, w.Freshness, w.Depth, w.Submitted)
+		for _, l := range rows[:n] {
+			due := now
+			if !l.RetrievedAt.IsZero() {
+				due = l.RetrievedAt.UTC().Add(sc.RecrawlInterval)
+			}
+			freshness := l.Freshness(now, sc.RecrawlInterval)
+			args = append(args, l.ID, l.URL, due, l.Rank, freshness, w.Score(l.Rank, freshness, 0, false))
+		}
+		query := fmt.Sprintf(frontierRefreshQuery, valuesList(4, n, "(%s::uuid, %s::text, %s::timestamp, %s::double precision, %s::double precision, %s::double precision)"))
+		if _, err := tx.ExecContext(context.TODO(), query, args...); err != nil {
+			return fmt.Errorf("frontier refresh: %v", err)
+		}
+		rows = rows[n:]
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("frontier refresh: %v", err)
+	}
+	return nil
+}
+
+// Dequeue implements frontier.Store.
+func (p *postgre) Dequeue(fromID, toID uuid.UUID, n int, lease time.Duration) ([]frontier.Entry, error) {
+	now := time.Now().UTC()
+	rows, err := p.db.QueryxContext(context.TODO(), frontierDequeueQuery, fromID, toID, now, n, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("frontier dequeue: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []frontier.Entry
+	for rows.Next() {
+		var e frontier.Entry
+		if err := rows.Scan(&e.LinkID, &e.URL, &e.Depth, &e.Submitted, &e.Priority, &e.LeasedUntil); err != nil {
+			return nil, fmt.Errorf("frontier dequeue: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("frontier dequeue: %v", err)
+	}
+
+	// RETURNING does not keep the order of the sub-select
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Priority > entries[j].Priority })
+	return entries, nil
+}
+
+// Push implements frontier.Store.
+func (p *postgre) Push(entries []frontier.Entry, sc frontier.Scoring) error {
+	// a statement can not update the same row twice, entries of the same
+	// link are merged the way an existing entry would be
+	byID := make(map[uuid.UUID]*frontier.Entry, len(entries))
+	var keep, earlier []*frontier.Entry
+	for _, e := range entries {
+		prev, ok := byID[e.LinkID]
+		if !ok {
+			e := e
+			byID[e.LinkID] = &e
+			continue
+		}
+		prev.Depth = min(prev.Depth, e.Depth)
+		prev.Submitted = prev.Submitted || e.Submitted
+		if prev.DueAt.IsZero() || !e.DueAt.IsZero() && e.DueAt.Before(prev.DueAt) {
+			prev.DueAt = e.DueAt
+		}
+	}
+	for _, e := range byID {
+		if e.DueAt.IsZero() {
+			keep = append(keep, e)
+		} else {
+			earlier = append(earlier, e)
+		}
+	}
+
+	tx, err := p.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return fmt.Errorf("frontier push: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, group := range []struct {
+		entries []*frontier.Entry
+		due     string
+	}{{keep, frontierKeepDue}, {earlier, frontierEarlierDue}} {
+		// rows are written in ID order so concurrent pushes lock the same
+		// rows in the same order
+		sort.Slice(group.entries, func(i, j int) bool {
+			return bytes.Compare(group.entries[i].LinkID[:], group.entries[j].LinkID[:]) < 0
+		})
+		for rows := group.entries; len(rows) > 0; {
+			n := min(len(rows), max_batch_rows)
+			w := sc.Weights
+			args := make([]any, 0, 4+6*n)
+			args = append(args, w.Pagerank, w.Freshness, w.Depth, w.Submitted)
+			for _, e := range rows[:n] {
+				due := now
+				if !e.DueAt.IsZero() {
+					due = e.DueAt.UTC()
+				}
+				// a new entry has no rank yet and was never retrieved
+				args = append(args, e.LinkID, e.URL, e.Depth, e.Submitted, due, w.Score(0, 1, e.Depth, e.Submitted))
+			}
+			query := fmt.Sprintf(frontierPushQuery, valuesList(4, n, "(%s, %s, %s::int, %s::boolean, %s::timestamp, %s::double precision)"), group.due)
+			if _, err := tx.ExecContext(context.TODO(), query, args...); err != nil {
+				return fmt.Errorf("frontier push: %v", err)
+			}
+			rows = rows[n:]
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("frontier push: %v", err)
+	}
+	return nil
+}
+
+// Ack implements frontier.Store.
+func (p *postgre) Ack(linkID uuid.UUID, nextDue time.Time, sc frontier.Scoring) error {
+	// the freshness the entry has once it is due again
+	freshness := 1.0
+	if sc.RecrawlInterval > 0 {
+		freshness = math.Min(time.Until(nextDue).Seconds()/sc.RecrawlInterval.Seconds(), 1)
+	}
+	w := sc.Weights
+	_, err := p.db.ExecContext(context.TODO(), frontierAckQuery, linkID, nextDue.UTC(),
+		math.Max(freshness, 0), w.Pagerank, w.Freshness, w.Depth)
+	if err != nil {
+		return fmt.Errorf("frontier ack: %v", err)
+	}
+	return nil
+}
+
+// Release implements frontier.Releaser.
+func (p *postgre) Release(fromID, toID uuid.UUID, leasedUntil time.Time) error {
+	_, err := p.db.ExecContext(context.TODO(), frontierReleaseQuery, fromID, toID, leasedUntil.UTC())
+	if err != nil {
+		return fmt.Errorf("frontier release: %v", err)
+	}
+	return nil
+}
+
+// the number of rows written per INSERT statement, a statement takes at most
+// 65535 parameters
+var max_batch_rows = 1000
+
+// valuesList returns a VALUES list of n rows, row is the format of a row with
+// a %s per column. The parameters are numbered from offset+1.
+func valuesList(offset, n int, row string) string {
+	cols := strings.Count(row, "%s")
+	rows := make([]string, n)
+	params := make([]any, cols)
+	for i := range rows {
+		for j := range params {
+			params[j] = fmt.Sprintf("$%d", offset+i*cols+j+1)
+		}
+		rows[i] = fmt.Sprintf(row, params...)
+	}
+	return strings.Join(rows, ", ")
+}
@@ -0,0 +1,74 @@
+package crawlpostgre
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/odit-bit/se/crawler/event"
+)
+
+var _ event.Sink = (*postgre)(nil)
+
+// OutboxEvent is a crawl event stored in the outbox.
+type OutboxEvent struct {
+	// The position of the event in the outbox.
+	ID int64
+
+	event.Record
+}
+
+// Publish stores e in the outbox, see UnpublishedEvents. It implements
+// linkcrawler.EventSink.
+func (p *postgre) Publish(e event.Event) error {
+	r, err := event.Encode(e)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(context.TODO(), insertEventQuery,
+		string(r.Type),
+		e.LinkID(),
+		e.Time().UTC(),
+		[]byte(r.Data),
+	)
+	if err != nil {
+		return fmt.Errorf("publish event: %v", err)
+	}
+	return nil
+}
+
+// UnpublishedEvents returns the n oldest events of the outbox that are not
+// marked as published. A relay delivers them to other services and then
+// marks them with MarkPublished.
+func (p *postgre) UnpublishedEvents(n int) ([]OutboxEvent, error) {
+	rows, err := p.db.QueryxContext(context.TODO(), unpublishedEventsQuery, n)
+	if err != nil {
+		return nil, fmt.Errorf("unpublished events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var typ string
+		var data []byte
+		if err := rows.Scan(&e.ID, &typ, &data); err != nil {
+			return nil, fmt.Errorf("unpublished events: %v", err)
+		}
+		e.Type, e.Data = event.Type(typ), data
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkPublished marks the outbox events of ids as published.
+func (p *postgre) MarkPublished(ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := p.db.ExecContext(context.TODO(), markEventsPublishedQuery, ids, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("mark events published: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package crawlpostgre
+
+var migrations = []string{
+	createFrontierTableQuery,
+	createFrontierIndexQuery,
+	alterFrontierScoreQuery,
+	alterFrontierProvisionalQuery,
+	createCrawlStateTableQuery,
+	createPassesTableQuery,
+	alterPassesCountsQuery,
+	createCheckpointsTableQuery,
+	createTrapHostsTableQuery,
+	createEventsTableQuery,
+	createEventsPendingIndexQuery,
+	createSubmissionsTableQuery,
+	createSubmissionsPendingIndexQuery,
+	dropFrontierLinkReferenceQuery,
+	dropCrawlStateLinkReferenceQuery,
+	dropSubmissionsLinkReferenceQuery,
+}
+
+const createFrontierTableQuery = `
+	CREATE TABLE IF NOT EXISTS frontier(
+		link_id UUID PRIMARY KEY,
+		url text NOT NULL,
+		depth int NOT NULL DEFAULT 0,
+		submitted boolean NOT NULL DEFAULT false,
+		priority double precision NOT NULL DEFAULT 0,
+		due_at TIMESTAMP NOT NULL,
+		leased_until TIMESTAMP
+	);
+`
+
+const createFrontierIndexQuery = `
+	CREATE INDEX IF NOT EXISTS frontier_priority_idx ON frontier (priority DESC)
+`
+
+// the normalized PageRank score and freshness the priority of an entry is
+// computed from, so that it can be scored again when it is pushed or
+// acknowledged without joining the documents
+const alterFrontierScoreQuery = `
+	ALTER TABLE frontier
+	ADD COLUMN IF NOT EXISTS rank double precision NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS freshness double precision NOT NULL DEFAULT 1;
+`
+
+// an entry the refresh added at depth 0 is provisional until it is crawled,
+// the crawler may have discovered its link and push it with its real depth
+const alterFrontierProvisionalQuery = `
+	ALTER TABLE frontier
+	ADD COLUMN IF NOT EXISTS provisional boolean NOT NULL DEFAULT false;
+`
+
+const createCrawlStateTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_state(
+		link_id UUID PRIMARY KEY,
+		etag text NOT NULL DEFAULT '',
+		last_modified text NOT NULL DEFAULT '',
+		content_hash bigint NOT NULL DEFAULT 0,
+		interval_secs double precision NOT NULL,
+		next_crawl_at TIMESTAMP NOT NULL
+	);
+`
+
+// $1..$4 the pagerank, freshness, depth and submitted weights, %s is the
+// VALUES list of (link_id, url, due_at, rank, freshness, priority) rows. A
+// link that is not part of the frontier yet was added outside of the crawler,
+// unless a push of the crawler is on its way. It is added at depth 0 until it
+// is pushed and keeps its adaptive schedule if it was crawled before. An
+// existing entry keeps its depth and is scored again with the new rank and
+// freshness.
+const frontierRefreshQuery = `
+	INSERT INTO frontier (link_id, url, depth, provisional, submitted, due_at, rank, freshness, priority)
+	SELECT v.link_id, v.url, 0, true, false, COALESCE(s.next_crawl_at, v.due_at), v.rank, v.freshness, v.priority
+	FROM (VALUES %s) AS v (link_id, url, due_at, rank, freshness, priority)
+	LEFT JOIN crawl_state s ON s.link_id = v.link_id
+	ON CONFLICT (link_id) DO UPDATE
+	SET rank = EXCLUDED.rank,
+		freshness = EXCLUDED.freshness,
+		priority = $1 * EXCLUDED.rank + $2 * EXCLUDED.freshness + $3 / (1 + frontier.depth)
+			+ $4 * (CASE WHEN frontier.submitted THEN 1 ELSE 0 END)
+`
+
+const frontierDequeueQuery = `
+	UPDATE frontier
+	SET leased_until = $5
+	WHERE link_id IN (
+		SELECT link_id FROM frontier
+		WHERE link_id >= $1 AND link_id < $2
+			AND due_at <= $3
+			AND (leased_until IS NULL OR leased_until < $3)
+		ORDER BY priority DESC
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING link_id, url, depth, submitted, priority, leased_until
+`
+
+// $1..$4 the pagerank, freshness, depth and submitted weights, %[1]s is the
+// VALUES list of (link_id, url, depth, submitted, due_at, priority) rows and
+// %[2]s the due time of an existing entry. A new entry is scored by the
+// caller, an existing one is scored again with its smaller depth, or the
+// pushed depth if it is provisional, and stays a submission once it was
+// submitted.
+const frontierPushQuery = `
+	INSERT INTO frontier (link_id, url, depth, submitted, due_at, priority)
+	VALUES %[1]s
+	ON CONFLICT (link_id) DO UPDATE
+	SET depth = ` + frontierPushedDepth + `,
+		provisional = false,
+		submitted = frontier.submitted OR EXCLUDED.submitted,
+		due_at = %[2]s,
+		priority = $1 * frontier.rank + $2 * frontier.freshness + $3 / (1 + ` + frontierPushedDepth + `)
+			+ $4 * (CASE WHEN frontier.submitted OR EXCLUDED.submitted THEN 1 ELSE 0 END)
+`
+
+// the depth of an existing entry once it is pushed
+const frontierPushedDepth = "CASE WHEN frontier.provisional THEN EXCLUDED.depth ELSE LEAST(frontier.depth, EXCLUDED.depth) END"
+
+// an existing entry keeps its due time unless the pushed entry is due earlier
+const (
+	frontierKeepDue    = "frontier.due_at"
+	frontierEarlierDue = "LEAST(frontier.due_at, EXCLUDED.due_at)"
+)
+
+// $3 the freshness of the entry once it is due, $4..$6 the pagerank,
+// freshness and depth weights
+const frontierAckQuery = `
+	UPDATE frontier
+	SET leased_until = NULL, submitted = false, provisional = false, due_at = $2,
+		freshness = $3,
+		priority = $4 * rank + $5 * $3 + $6 / (1 + depth)
+	WHERE link_id = $1
+`
+
+const frontierReleaseQuery = `
+	UPDATE frontier
+	SET leased_until = NULL
+	WHERE link_id >= $1 AND link_id < $2 AND leased_until = $3
+`
+
+const lookupCrawlStateQuery = `
+	SELECT etag, last_modified, content_hash, interval_secs, next_crawl_at
+	FROM crawl_state
+	WHERE link_id = $1
+`
+
+const upsertCrawlStateQuery = `
+	INSERT INTO crawl_state (link_id, etag, last_modified, content_hash, interval_secs, next_crawl_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (link_id) DO UPDATE
+	SET etag = EXCLUDED.etag,
+		last_modified = EXCLUDED.last_modified,
+		content_hash = EXCLUDED.content_hash,
+		interval_secs = EXCLUDED.interval_secs,
+		next_crawl_at = EXCLUDED.next_crawl_at
+`
+
+// one row per finished crawl pass
+const createPassesTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_passes(
+		id bigserial PRIMARY KEY,
+		started_at TIMESTAMP NOT NULL,
+		finished_at TIMESTAMP NOT NULL,
+		links bigint NOT NULL,
+		fetched bigint NOT NULL,
+		failed bigint NOT NULL,
+		not_modified bigint NOT NULL,
+		disallowed bigint NOT NULL,
+		out_of_scope bigint NOT NULL,
+		indexed bigint NOT NULL,
+		duplicates bigint NOT NULL,
+		non_canonical bigint NOT NULL,
+		discovered bigint NOT NULL,
+		bytes bigint NOT NULL
+	);
+`
+
+const alterPassesCountsQuery = `
+	ALTER TABLE crawl_passes
+	ADD COLUMN IF NOT EXISTS removed bigint NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS redirects bigint NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS trapped bigint NOT NULL DEFAULT 0;
+`
+
+const insertPassQuery = `
+	INSERT INTO crawl_passes (started_at, finished_at, links, fetched, failed, not_modified,
+		disallowed, out_of_scope, indexed, duplicates, non_canonical, discovered, bytes, removed, redirects, trapped)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+`
+
+const recentPassesQuery = `
+	SELECT started_at, finished_at, links, fetched, failed, not_modified,
+		disallowed, out_of_scope, indexed, duplicates, non_canonical, discovered, bytes, removed, redirects, trapped
+	FROM crawl_passes
+	ORDER BY started_at DESC
+	LIMIT $1
+`
+
+// one row per unfinished crawl pass, counts is the JSON of its report.Pass
+const createCheckpointsTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_checkpoints(
+		pass_id UUID PRIMARY KEY,
+		from_id UUID NOT NULL,
+		to_id UUID NOT NULL,
+		retrieved_before TIMESTAMP NOT NULL,
+		last_id UUID NOT NULL,
+		leased_until TIMESTAMP,
+		counts jsonb NOT NULL,
+		saved_at TIMESTAMP NOT NULL
+	);
+`
+
+const loadCheckpointQuery = `
+	SELECT pass_id, from_id, to_id, retrieved_before, last_id, leased_until, counts
+	FROM crawl_checkpoints
+	WHERE from_id = $1 AND to_id = $2
+	ORDER BY saved_at DESC
+	LIMIT 1
+`
+
+const saveCheckpointQuery = `
+	INSERT INTO crawl_checkpoints (pass_id, from_id, to_id, retrieved_before, last_id, leased_until, counts, saved_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (pass_id) DO UPDATE
+	SET last_id = EXCLUDED.last_id,
+		leased_until = EXCLUDED.leased_until,
+		counts = EXCLUDED.counts,
+		saved_at = EXCLUDED.saved_at
+`
+
+const clearCheckpointQuery = `
+	DELETE FROM crawl_checkpoints WHERE pass_id = $1
+`
+
+// one row per host flagged as a crawler trap
+const createTrapHostsTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_trap_hosts(
+		host text PRIMARY KEY,
+		reason text NOT NULL,
+		flagged_at TIMESTAMP NOT NULL
+	);
+`
+
+const flagHostQuery = `
+	INSERT INTO crawl_trap_hosts (host, reason, flagged_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (host) DO NOTHING
+`
+
+const flaggedHostsQuery = `
+	SELECT host, reason, flagged_at FROM crawl_trap_hosts
+`
+
+// the outbox of crawl events, events are relayed in id order and marked as
+// published afterwards
+const createEventsTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_events(
+		id bigserial PRIMARY KEY,
+		type text NOT NULL,
+		link_id UUID NOT NULL,
+		occurred_at TIMESTAMP NOT NULL,
+		payload jsonb NOT NULL,
+		published_at TIMESTAMP
+	);
+`
+
+const createEventsPendingIndexQuery = `
+	CREATE INDEX IF NOT EXISTS crawl_events_pending_idx ON crawl_events (id) WHERE published_at IS NULL
+`
+
+const insertEventQuery = `
+	INSERT INTO crawl_events (type, link_id, occurred_at, payload)
+	VALUES ($1, $2, $3, $4)
+`
+
+const unpublishedEventsQuery = `
+	SELECT id, type, payload FROM crawl_events
+	WHERE published_at IS NULL
+	ORDER BY id
+	LIMIT $1
+`
+
+const markEventsPublishedQuery = `
+	UPDATE crawl_events SET published_at = $2
+	WHERE id = ANY($1)
+`
+
+// one row per link submitted by a user, see submission.State for the states
+const createSubmissionsTableQuery = `
+	CREATE TABLE IF NOT EXISTS crawl_submissions(
+		id UUID PRIMARY KEY,
+		link_id UUID NOT NULL,
+		url text NOT NULL,
+		state text NOT NULL,
+		reason text NOT NULL DEFAULT '',
+		submitted_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		leased_until TIMESTAMP
+	);
+`
+
+const createSubmissionsPendingIndexQuery = `
+	CREATE INDEX IF NOT EXISTS crawl_submissions_pending_idx ON crawl_submissions (submitted_at)
+	WHERE state IN ('queued', 'fetching')
+`
+
+// the tables of the crawler store used to reference the links table of the
+// graph service
+const dropFrontierLinkReferenceQuery = `
+	ALTER TABLE frontier DROP CONSTRAINT IF EXISTS frontier_link_id_fkey
+`
+
+const dropCrawlStateLinkReferenceQuery = `
+	ALTER TABLE crawl_state DROP CONSTRAINT IF EXISTS crawl_state_link_id_fkey
+`
+
+const dropSubmissionsLinkReferenceQuery = `
+	ALTER TABLE crawl_submissions DROP CONSTRAINT IF EXISTS crawl_submissions_link_id_fkey
+`
+
+const insertSubmissionQuery = `
+	INSERT INTO crawl_submissions (id, link_id, url, state, submitted_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $5)
+`
+
+// a submitted link becomes a due seed of the frontier, it is scored as a
+// submission on the next refresh
+const frontierSubmitQuery = `
+	INSERT INTO frontier (link_id, url, depth, submitted, due_at)
+	VALUES ($1, $2, 0, true, $3)
+	ON CONFLICT (link_id) DO UPDATE
+	SET depth = 0,
+		provisional = false,
+		submitted = true,
+		due_at = LEAST(frontier.due_at, EXCLUDED.due_at)
+`
+
+const submissionQuery = `
+	SELECT id, link_id, url, state, reason, submitted_at, updated_at
+	FROM crawl_submissions
+	WHERE id = $1
+`
+
+// $1 the number of submissions, $2 now, $3 the end of the lease
+const claimSubmissionsQuery = `
+	UPDATE crawl_submissions
+	SET state = 'fetching', updated_at = $2, leased_until = $3
+	WHERE id IN (
+		SELECT id FROM crawl_submissions
+		WHERE state = 'queued' OR (state = 'fetching' AND leased_until < $2)
+		ORDER BY submitted_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, link_id, url, state, reason, submitted_at, updated_at
+`
+
+// only submissions that are still claimed are requeued
+const requeueSubmissionsQuery = `
+	UPDATE crawl_submissions
+	SET state = 'queued', updated_at = $2, leased_until = NULL
+	WHERE id = ANY($1::uuid[]) AND state = 'fetching'
+`
+
+const finishSubmissionQuery = `
+	UPDATE crawl_submissions
+	SET state = $2, reason = $3, updated_at = $4, leased_until = NULL
+	WHERE id = $1
+`
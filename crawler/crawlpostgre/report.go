@@ -0,0 +1,73 @@
+package crawlpostgre
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/odit-bit/se/crawler/report"
+)
+
+var _ report.Store = (*postgre)(nil)
+
+// SavePass implements report.Store.
+func (p *postgre) SavePass(r *report.Pass) error {
+	_, err := p.db.ExecContext(context.TODO(), insertPassQuery,
+		r.StartedAt.UTC(),
+		r.FinishedAt.UTC(),
+		r.Links,
+		r.Fetched,
+		r.Failed,
+		r.NotModified,
+		r.Disallowed,
+		r.OutOfScope,
+		r.Indexed,
+		r.Duplicates,
+		r.NonCanonical,
+		r.Discovered,
+		r.Bytes,
+		r.Removed,
+		r.Redirects,
+		r.Trapped,
+	)
+	if err != nil {
+		return fmt.Errorf("save pass report: %v", err)
+	}
+	return nil
+}
+
+// RecentPasses returns the summaries of the last n passes, newest first.
+func (p *postgre) RecentPasses(n int) ([]report.Pass, error) {
+	rows, err := p.db.QueryxContext(context.TODO(), recentPassesQuery, n)
+	if err != nil {
+		return nil, fmt.Errorf("recent pass reports: %v", err)
+	}
+	defer rows.Close()
+
+	var passes []report.Pass
+	for rows.Next() {
+		var r report.Pass
+		err := rows.Scan(
+			&r.StartedAt,
+			&r.FinishedAt,
+			&r.Links,
+			&r.Fetched,
+			&r.Failed,
+			&r.NotModified,
+			&r.Disallowed,
+			&r.OutOfScope,
+			&r.Indexed,
+			&r.Duplicates,
+			&r.NonCanonical,
+			&r.Discovered,
+			&r.Bytes,
+			&r.Removed,
+			&r.Redirects,
+			&r.Trapped,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("recent pass reports: %v", err)
+		}
+		passes = append(passes, r)
+	}
+	return passes, rows.Err()
+}
@@ -0,0 +1,52 @@
+package crawlpostgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/recrawl"
+)
+
+var _ recrawl.Store = (*postgre)(nil)
+
+// LookupState implements recrawl.Store.
+func (p *postgre) LookupState(linkID uuid.UUID) (recrawl.State, bool, error) {
+	var s recrawl.State
+	var hash int64
+	var secs float64
+	err := p.db.QueryRowxContext(context.TODO(), lookupCrawlStateQuery, linkID).Scan(
+		&s.ETag,
+		&s.LastModified,
+		&hash,
+		&secs,
+		&s.NextCrawlAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return recrawl.State{}, false, nil
+		}
+		return recrawl.State{}, false, fmt.Errorf("lookup crawl state: %v", err)
+	}
+	s.ContentHash = uint64(hash)
+	s.Interval = time.Duration(secs * float64(time.Second))
+	return s, true, nil
+}
+
+// SaveState implements recrawl.Store.
+func (p *postgre) SaveState(linkID uuid.UUID, s recrawl.State) error {
+	_, err := p.db.ExecContext(context.TODO(), upsertCrawlStateQuery,
+		linkID,
+		s.ETag,
+		s.LastModified,
+		int64(s.ContentHash),
+		s.Interval.Seconds(),
+		s.NextCrawlAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("save crawl state: %v", err)
+	}
+	return nil
+}
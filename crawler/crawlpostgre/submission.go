@@ -0,0 +1,118 @@
+package crawlpostgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+var _ submission.Store = (*postgre)(nil)
+
+// Submit implements submission.Store. The link is also recorded as a submission
+// in the frontier.
+func (p *postgre) Submit(linkID uuid.UUID, url string) (*submission.Submission, error) {
+	now := time.Now().UTC()
+	s := &submission.Submission{
+		ID:          uuid.New(),
+		LinkID:      linkID,
+		URL:         url,
+		State:       submission.Queued,
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	tx, err := p.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("submit link: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(context.TODO(), insertSubmissionQuery, s.ID, s.LinkID, s.URL, string(s.State), now); err != nil {
+		return nil, fmt.Errorf("submit link: %v", err)
+	}
+	if _, err := tx.ExecContext(context.TODO(), frontierSubmitQuery, s.LinkID, s.URL, now); err != nil {
+		return nil, fmt.Errorf("submit link: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("submit link: %v", err)
+	}
+	return s, nil
+}
+
+// Submission implements submission.Store.
+func (p *postgre) Submission(id uuid.UUID) (*submission.Submission, error) {
+	s, err := scanSubmission(p.db.QueryRowxContext(context.TODO(), submissionQuery, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("submission: %v", err)
+	}
+	return s, nil
+}
+
+// Claim implements submission.Store.
+func (p *postgre) Claim(n int, lease time.Duration) ([]submission.Submission, error) {
+	now := time.Now().UTC()
+	rows, err := p.db.QueryxContext(context.TODO(), claimSubmissionsQuery, n, now, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("claim submissions: %v", err)
+	}
+	defer rows.Close()
+
+	var subs []submission.Submission
+	for rows.Next() {
+		s, err := scanSubmission(rows)
+		if err != nil {
+			return nil, fmt.Errorf("claim submissions: %v", err)
+		}
+		subs = append(subs, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim submissions: %v", err)
+	}
+
+	// RETURNING does not keep the order of the sub-select
+	sort.Slice(subs, func(i, j int) bool { return subs[i].SubmittedAt.Before(subs[j].SubmittedAt) })
+	return subs, nil
+}
+
+// Finish implements submission.Store.
+func (p *postgre) Finish(id uuid.UUID, state submission.State, reason string) error {
+	_, err := p.db.ExecContext(context.TODO(), finishSubmissionQuery, id, string(state), reason, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("finish submission: %v", err)
+	}
+	return nil
+}
+
+// Requeue implements submission.Store.
+func (p *postgre) Requeue(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+	_, err := p.db.ExecContext(context.TODO(), requeueSubmissionsQuery, strIDs, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("release submissions: %v", err)
+	}
+	return nil
+}
+
+func scanSubmission(row sqlx.ColScanner) (*submission.Submission, error) {
+	var s submission.Submission
+	var state string
+	if err := row.Scan(&s.ID, &s.LinkID, &s.URL, &state, &s.Reason, &s.SubmittedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.State = submission.State(state)
+	return &s, nil
+}
@@ -0,0 +1,41 @@
+package crawlpostgre
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/odit-bit/se/crawler/trap"
+)
+
+var _ trap.Store = (*postgre)(nil)
+
+// FlagHost implements trap.Store. A host that is already flagged keeps its
+// first flag.
+func (p *postgre) FlagHost(f trap.Flag) error {
+	_, err := p.db.ExecContext(context.TODO(), flagHostQuery, f.Host, string(f.Reason), f.FlaggedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("flag trap host: %v", err)
+	}
+	return nil
+}
+
+// FlaggedHosts implements trap.Store.
+func (p *postgre) FlaggedHosts() ([]trap.Flag, error) {
+	rows, err := p.db.QueryxContext(context.TODO(), flaggedHostsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("flagged trap hosts: %v", err)
+	}
+	defer rows.Close()
+
+	var flags []trap.Flag
+	for rows.Next() {
+		var f trap.Flag
+		var reason string
+		if err := rows.Scan(&f.Host, &reason, &f.FlaggedAt); err != nil {
+			return nil, fmt.Errorf("flagged trap hosts: %v", err)
+		}
+		f.Reason = trap.Reason(reason)
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
@@ -0,0 +1,128 @@
+// Package event defines the events the crawler publishes about the links it
+// discovers, fetches and indexes, and the sinks that deliver them to other
+// services: an in-process channel, a JSON-lines file or, in crawlpostgre, a
+// Postgres outbox table.
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type names the type of an event.
+type Type string
+
+const (
+	LinkDiscoveredType  Type = "link_discovered"
+	PageFetchedType     Type = "page_fetched"
+	DocumentIndexedType Type = "document_indexed"
+	FetchFailedType     Type = "fetch_failed"
+)
+
+// Event is a crawl event, one of *LinkDiscovered, *PageFetched,
+// *DocumentIndexed and *FetchFailed.
+type Event interface {
+	Type() Type
+
+	// Time returns when the event occurred.
+	Time() time.Time
+
+	// LinkID returns the link the event is about.
+	LinkID() uuid.UUID
+}
+
+// Link identifies the link of an event and when the event occurred.
+type Link struct {
+	ID  uuid.UUID `json:"link_id"`
+	URL string    `json:"url"`
+	At  time.Time `json:"at"`
+}
+
+func (l Link) Time() time.Time   { return l.At }
+func (l Link) LinkID() uuid.UUID { return l.ID }
+
+// LinkDiscovered is published for every link found on a crawled page that was
+// not in the link graph before.
+type LinkDiscovered struct {
+	Link
+
+	// The link of the page the link was found on.
+	SourceID uuid.UUID `json:"source_id"`
+}
+
+// PageFetched is published for every page request that was answered. The
+// final URL is set if the request was redirected.
+type PageFetched struct {
+	Link
+
+	Status      int           `json:"status"`
+	FinalURL    string        `json:"final_url,omitempty"`
+	MediaType   string        `json:"media_type,omitempty"`
+	Bytes       int64         `json:"bytes"`
+	NotModified bool          `json:"not_modified,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// DocumentIndexed is published for every document written to the index.
+type DocumentIndexed struct {
+	Link
+
+	Title string `json:"title"`
+}
+
+// FetchFailed is published for every page request that failed or was answered
+// with an HTTP error status.
+type FetchFailed struct {
+	Link
+
+	// The class of the error, e.g. "timeout", "dns" or "http".
+	Class string `json:"class"`
+
+	// The HTTP status, zero if the request was not answered.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (*LinkDiscovered) Type() Type  { return LinkDiscoveredType }
+func (*PageFetched) Type() Type     { return PageFetchedType }
+func (*DocumentIndexed) Type() Type { return DocumentIndexedType }
+func (*FetchFailed) Type() Type     { return FetchFailedType }
+
+// Record is the JSON representation of an event.
+type Record struct {
+	Type Type            `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Encode returns the record of e.
+func Encode(e Event) (Record, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Record{}, fmt.Errorf("encode %s event: %v", e.Type(), err)
+	}
+	return Record{Type: e.Type(), Data: data}, nil
+}
+
+// Decode returns the event of r.
+func Decode(r Record) (Event, error) {
+	var e Event
+	switch r.Type {
+	case LinkDiscoveredType:
+		e = &LinkDiscovered{}
+	case PageFetchedType:
+		e = &PageFetched{}
+	case DocumentIndexedType:
+		e = &DocumentIndexed{}
+	case FetchFailedType:
+		e = &FetchFailed{}
+	default:
+		return nil, fmt.Errorf("decode event: unknown type %q", r.Type)
+	}
+	if err := json.Unmarshal(r.Data, e); err != nil {
+		return nil, fmt.Errorf("decode %s event: %v", r.Type, err)
+	}
+	return e, nil
+}
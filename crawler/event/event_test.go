@@ -0,0 +1,82 @@
+package event
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_event(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	link := Link{ID: uuid.New(), URL: "https://a.com/", At: at}
+	events := []Event{
+		&LinkDiscovered{Link: link, SourceID: uuid.New()},
+		&PageFetched{Link: link, Status: 200, MediaType: "text/html", Bytes: 512, Duration: time.Second},
+		&DocumentIndexed{Link: link, Title: "a"},
+		&FetchFailed{Link: link, Class: "timeout", Error: "deadline exceeded"},
+	}
+
+	t.Run("test_encode_decode", func(t *testing.T) {
+		for _, e := range events {
+			r, err := Encode(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type() != e.Type() || fmt.Sprint(got) != fmt.Sprint(e) {
+				t.Fatalf("\ngot:%+v \nexpect:%+v", got, e)
+			}
+		}
+		if _, err := Decode(Record{Type: "unknown", Data: []byte("{}")}); err == nil {
+			t.Fatal("unknown event type decoded")
+		}
+	})
+
+	t.Run("test_json_lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := NewJSONLines(&buf)
+		for _, e := range events {
+			if err := sink.Publish(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var types []Type
+		sc := bufio.NewScanner(&buf)
+		for sc.Scan() {
+			var r Record
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				t.Fatal(err)
+			}
+			types = append(types, r.Type)
+		}
+		expect := []Type{LinkDiscoveredType, PageFetchedType, DocumentIndexedType, FetchFailedType}
+		if fmt.Sprint(types) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", types, expect)
+		}
+	})
+
+	t.Run("test_channel", func(t *testing.T) {
+		sink := NewChannel(2)
+		var multi Sink = Multi{sink}
+		for _, e := range events {
+			if err := multi.Publish(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := sink.Dropped(); got != 2 {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, 2)
+		}
+		if e := <-sink.Events(); e.Type() != LinkDiscoveredType {
+			t.Fatalf("\ngot:%v \nexpect:%v", e.Type(), LinkDiscoveredType)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+)
+
+// Channel delivers events to a consumer in the same process. The crawler
+// never waits for the consumer, events that do not fit in the buffer of the
+// channel are dropped.
+type Channel struct {
+	ch      chan Event
+	dropped atomic.Int64
+}
+
+// NewChannel creates a channel sink that buffers size events.
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan Event, size)}
+}
+
+// Events returns the channel the events are delivered on.
+func (c *Channel) Events() <-chan Event {
+	return c.ch
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (c *Channel) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Publish implements linkcrawler.EventSink.
+func (c *Channel) Publish(e Event) error {
+	select {
+	case c.ch <- e:
+	default:
+		c.dropped.Add(1)
+	}
+	return nil
+}
+
+// JSONLines writes every event as a line of JSON, the Record of the event,
+// e.g. to a file that is tailed by another service.
+type JSONLines struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLines creates a sink writing to w. Writes are not buffered.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{enc: json.NewEncoder(w)}
+}
+
+// Publish implements linkcrawler.EventSink.
+func (j *JSONLines) Publish(e Event) error {
+	r, err := Encode(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(r); err != nil {
+		return fmt.Errorf("write %s event: %v", e.Type(), err)
+	}
+	return nil
+}
+
+// Sink is implemented by the sinks of this package, it matches
+// linkcrawler.EventSink.
+type Sink interface {
+	Publish(e Event) error
+}
+
+// Multi publishes every event to each of its sinks.
+type Multi []Sink
+
+// Publish implements linkcrawler.EventSink.
+func (m Multi) Publish(e Event) error {
+	var err error
+	for _, s := range m {
+		err = multierr.Append(err, s.Publish(e))
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+)
+
+// the encodings of simple PDF fonts, by character code
+var (
+	winAnsiEncoding  = newEncoding(cp1252High)
+	macRomanEncoding = newEncoding(macRomanHigh)
+)
+
+// the characters of the codes 0x80 to 0x9F of Windows-1252, the rest of the
+// upper half is Latin-1
+const cp1252High = "€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ"
+
+// the characters of the codes 0x80 to 0xFF of Mac OS Roman
+const macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»…\u00a0ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ\uf8ffÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+
+// newEncoding returns an encoding that is ASCII in the lower half and high
+// from 0x80 on, Latin-1 where high is too short.
+func newEncoding(high string) [256]rune {
+	var enc [256]rune
+	for c := 0x20; c < 0x7F; c++ {
+		enc[c] = rune(c)
+	}
+	enc['\t'], enc['\n'], enc['\r'] = ' ', '\n', '\n'
+	for c := 0xA0; c < 0x100; c++ {
+		enc[c] = rune(c)
+	}
+	for i, r := range []rune(high) {
+		enc[0x80+i] = r
+	}
+	return enc
+}
+
+// baseEncoding returns the encoding of a predefined encoding name. Fonts
+// with the StandardEncoding or without an encoding are decoded as
+// WinAnsiEncoding, they differ in only a few punctuation characters.
+func baseEncoding(name pdfName) [256]rune {
+	if name == "MacRomanEncoding" {
+		return macRomanEncoding
+	}
+	return winAnsiEncoding
+}
+
+// the Unicode characters of the glyph names used in the Differences of
+// font encodings, besides the names of single letters and uniXXXX names
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#',
+	"dollar": '$', "percent": '%', "ampersand": '&', "quotesingle": '\'',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+',
+	"comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=',
+	"greater": '>', "question": '?', "at": '@', "bracketleft": '[',
+	"backslash": '\\', "bracketright": ']', "asciicircum": '^',
+	"underscore": '_', "grave": '`', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "quoteleft": '‘',
+	"quoteright": '’', "quotedblleft": '“', "quotedblright": '”',
+	"quotesinglbase": '‚', "quotedblbase": '„', "endash": '–',
+	"emdash": '—', "bullet": '•', "ellipsis": '…', "dagger": '†',
+	"daggerdbl": '‡', "degree": '°', "copyright": '©',
+	"registered": '®', "trademark": '™', "section": '§',
+	"paragraph": '¶', "minus": '−', "nbspace": ' ', "Euro": '€',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ',
+	"dotlessi": 'ı', "germandbls": 'ß', "divide": '÷', "ydieresis": 'ÿ',
+}
+
+func init() {
+	// the Latin-1 letters from 0xC0 to 0xDE and their lowercase forms
+	upper := []string{
+		"Agrave", "Aacute", "Acircumflex", "Atilde", "Adieresis", "Aring", "AE", "Ccedilla",
+		"Egrave", "Eacute", "Ecircumflex", "Edieresis", "Igrave", "Iacute", "Icircumflex", "Idieresis",
+		"Eth", "Ntilde", "Ograve", "Oacute", "Ocircumflex", "Otilde", "Odieresis", "multiply",
+		"Oslash", "Ugrave", "Uacute", "Ucircumflex", "Udieresis", "Yacute", "Thorn",
+	}
+	for i, name := range upper {
+		glyphNames[name] = rune(0xC0 + i)
+		if name != "multiply" {
+			glyphNames[strings.ToLower(name)] = rune(0xE0 + i)
+		}
+	}
+	glyphNames["OE"], glyphNames["oe"] = 'Œ', 'œ'
+}
+
+// glyphRune returns the character of a glyph name, e.g. "eacute", "uni00E9"
+// or "e.sc".
+func glyphRune(name string) (rune, bool) {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hex, ok := strings.CutPrefix(name, prefix); ok && len(hex) >= 4 && len(hex) <= 6 {
+			if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return rune(v), true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+// Package extract turns fetched documents that are not HTML, such as PDF,
+// plain text and Markdown files, into the title, text and links that are
+// indexed for them. Extractors are registered by media type in a Registry.
+package extract
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// the maximum number of bytes of extracted text kept per document, a search
+// index can not analyze arbitrarily long texts
+const maxContent = 512 << 10
+
+// the maximum number of bytes of a title taken from the text of a document
+const maxTitle = 200
+
+// Document is what an extractor found in a document.
+type Document struct {
+	Title   string
+	Content string
+
+	// The URLs the document links to, as written in it. Relative URLs are
+	// resolved against the URL of the document by the caller.
+	Links []string
+}
+
+// Extractor extracts a Document from the body of a response.
+type Extractor interface {
+	Extract(r io.Reader) (*Document, error)
+}
+
+// ExtractorFunc adapts a function to the Extractor interface.
+type ExtractorFunc func(r io.Reader) (*Document, error)
+
+func (f ExtractorFunc) Extract(r io.Reader) (*Document, error) { return f(r) }
+
+// Registry maps media types to extractors.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+	extensions map[string]string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: make(map[string]Extractor),
+		extensions: make(map[string]string),
+	}
+}
+
+// Default returns a registry with the built-in extractors for PDF, plain
+// text and Markdown.
+func Default() *Registry {
+	reg := NewRegistry()
+	reg.Register("application/pdf", ExtractorFunc(PDF), ".pdf")
+	reg.Register("text/plain", ExtractorFunc(Text), ".txt")
+	reg.Register("text/markdown", ExtractorFunc(Markdown), ".md", ".markdown")
+	reg.Register("text/x-markdown", ExtractorFunc(Markdown))
+	return reg
+}
+
+// Register registers e for mediaType. The file name extensions, e.g. ".pdf",
+// identify documents of mediaType that are served with a generic content
+// type.
+func (reg *Registry) Register(mediaType string, e Extractor, extensions ...string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.extractors[mediaType] = e
+	for _, ext := range extensions {
+		reg.extensions[strings.ToLower(ext)] = mediaType
+	}
+}
+
+// Lookup returns the extractor registered for mediaType.
+func (reg *Registry) Lookup(mediaType string) (Extractor, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	e, ok := reg.extractors[mediaType]
+	return e, ok
+}
+
+// MediaType returns the media type of a document served with the
+// Content-Type header contentType from rawURL. Documents served without a
+// content type or with a generic one, such as application/octet-stream or
+// text/plain for a README.md, are identified by the extension of their
+// path. A document without a content type and a known extension is taken to
+// be HTML.
+func (reg *Registry) MediaType(contentType, rawURL string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = ""
+	}
+	mt = strings.ToLower(mt)
+
+	switch mt {
+	case "", "application/octet-stream", "binary/octet-stream", "text/plain":
+		if byExt, ok := reg.byExtension(rawURL); ok {
+			return byExt
+		}
+	}
+	if mt == "" {
+		return "text/html"
+	}
+	return mt
+}
+
+func (reg *Registry) byExtension(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	mt, ok := reg.extensions[strings.ToLower(path.Ext(u.Path))]
+	return mt, ok
+}
+
+// Extract extracts the document of mediaType from r with the registered
+// extractor. The content of the document is truncated to 512KiB.
+func (reg *Registry) Extract(mediaType string, r io.Reader) (*Document, error) {
+	e, ok := reg.Lookup(mediaType)
+	if !ok {
+		return nil, fmt.Errorf("extract: no extractor for %s", mediaType)
+	}
+	doc, err := e.Extract(r)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", mediaType, err)
+	}
+	doc.Content = truncate(doc.Content, maxContent)
+	return doc, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// collapse replaces every run of white space in s with a single space.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// firstLine returns the first non-empty line of text, the fallback title of
+// documents without one.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = collapse(line); line != "" {
+			return truncate(line, maxTitle)
+		}
+	}
+	return ""
+}
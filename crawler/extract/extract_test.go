@@ -0,0 +1,136 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_media_type(t *testing.T) {
+	reg := Default()
+	tests := []struct {
+		contentType string
+		url         string
+		expect      string
+	}{
+		{"application/pdf", "https://example.com/a", "application/pdf"},
+		{"application/octet-stream", "https://example.com/manual.PDF", "application/pdf"},
+		{"text/plain; charset=utf-8", "https://example.com/README.md", "text/markdown"},
+		{"text/plain", "https://example.com/notes", "text/plain"},
+		{"TEXT/HTML; charset=utf-8", "https://example.com/a.pdf", "text/html"},
+		{"", "https://example.com/", "text/html"},
+		{"image/png", "https://example.com/a.png", "image/png"},
+	}
+
+	for _, tt := range tests {
+		if got := reg.MediaType(tt.contentType, tt.url); got != tt.expect {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, tt.expect, tt.contentType+" "+tt.url)
+		}
+	}
+
+	t.Run("test_unknown_media_type", func(t *testing.T) {
+		if _, err := reg.Extract("image/png", strings.NewReader("")); err == nil {
+			t.Fatal("expected an error for a media type without extractor")
+		}
+	})
+}
+
+func Test_text(t *testing.T) {
+	t.Run("test_title", func(t *testing.T) {
+		doc, err := Default().Extract("text/plain", strings.NewReader("\n\n  Release   notes \nversion 2\tis out\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Title != "Release notes" || doc.Content != "Release notes version 2 is out" {
+			t.Fatalf("\ngot:%q %q", doc.Title, doc.Content)
+		}
+	})
+
+	t.Run("test_latin1", func(t *testing.T) {
+		doc, err := Text(strings.NewReader("caf\xe9"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Content != "café" {
+			t.Fatalf("\ngot:%v \nexpect:%v", doc.Content, "café")
+		}
+	})
+
+	t.Run("test_truncate", func(t *testing.T) {
+		doc, err := Default().Extract("text/plain", strings.NewReader(strings.Repeat("é", maxContent)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Content) != maxContent || !strings.HasSuffix(doc.Content, "é") {
+			t.Fatalf("\ngot:%v \nexpect:%v", len(doc.Content), maxContent)
+		}
+	})
+}
+
+func Test_markdown(t *testing.T) {
+	md := "## Overview\n" +
+		"\n" +
+		"Crawler *guide*\n" +
+		"===\n" +
+		"\n" +
+		"Read the [docs](https://example.com/docs \"Docs\") or see <https://example.com/faq>.\n" +
+		"\n" +
+		"![logo](logo.png)\n" +
+		"\n" +
+		"- set `max_failures` to __3__ &amp; wait\n" +
+		"> quoted [ref link][1]\n" +
+		"\n" +
+		"| a | b |\n" +
+		"|---|---|\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"# not a heading\")\n" +
+		"```\n" +
+		"\n" +
+		"[1]: https://example.com/ref\n"
+
+	doc, err := Markdown(strings.NewReader(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `Overview Crawler guide Read the docs or see https://example.com/faq. logo set max_failures to 3 & wait quoted ref link a b fmt.Println("# not a heading")`
+	if doc.Content != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", doc.Content, expect)
+	}
+	if doc.Title != "Crawler guide" {
+		t.Fatalf("\ngot:%v \nexpect:%v", doc.Title, "Crawler guide")
+	}
+	links := fmt.Sprint(doc.Links)
+	if links != "[https://example.com/docs https://example.com/faq https://example.com/ref]" {
+		t.Fatalf("\ngot:%v", links)
+	}
+
+	t.Run("test_front_matter", func(t *testing.T) {
+		doc, err := Markdown(strings.NewReader("---\ntitle: \"Release notes\"\ndate: 2023-01-01\n---\n# Changes\ntext\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Title != "Release notes" || doc.Content != "Changes text" {
+			t.Fatalf("\ngot:%q %q", doc.Title, doc.Content)
+		}
+	})
+}
+
+func Test_html(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title> Crawler
+	docs </title><style>p{color:red}</style></head>
+<body><script>var x = "<p>";</script><h1>Setup</h1><p>Read the <a href="/guide">guide</a> first.</p>
+<a href="https://example.com/faq">FAQ</a></body></html>`
+
+	doc, err := HTML(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Crawler docs" || doc.Content != "Setup Read the guide first. FAQ" {
+		t.Fatalf("\ngot:%q %q", doc.Title, doc.Content)
+	}
+	if fmt.Sprint(doc.Links) != "[/guide https://example.com/faq]" {
+		t.Fatalf("\ngot:%v", doc.Links)
+	}
+}
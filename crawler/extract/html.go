@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTML extracts the <title>, the text of the body and the <a href> links of
+// an HTML page. HTML pages are not registered in the Default registry, the
+// crawler extracts them along with the page info of the same response.
+func HTML(r io.Reader) (*Document, error) {
+	doc := &Document{}
+	var title, text strings.Builder
+
+	// the depth of elements whose text is not content, e.g. <script>
+	skip := 0
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			doc.Title = truncate(collapse(title.String()), maxTitle)
+			doc.Content = collapse(text.String())
+			return doc, nil
+
+		case html.TextToken:
+			switch {
+			case inTitle:
+				title.Write(z.Text())
+			case skip == 0:
+				text.Write(z.Text())
+				text.WriteByte(' ')
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch a := atom.Lookup(name); a {
+			case atom.Title:
+				inTitle = true
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head:
+				skip++
+			case atom.A:
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" && len(val) > 0 {
+						doc.Links = append(doc.Links, string(val))
+					}
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head:
+				if skip > 0 {
+					skip--
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,1053 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrEncrypted is returned for encrypted PDF files, their text can not be
+// read without the key.
+var ErrEncrypted = errors.New("encrypted PDF")
+
+// the maximum number of bytes a PDF stream is decoded to
+const maxStream = 32 << 20
+
+// PDF extracts the text of the pages of a PDF file, in page order. Its title
+// is the title of the document information dictionary or the first line of
+// text, its links are the URIs of the link annotations.
+//
+// The text is read from the text showing operators of the page content
+// streams, decoded with the ToUnicode map or the encoding of the font. Only
+// the Flate, ASCIIHex and ASCII85 filters are supported, text in streams
+// with other filters and text drawn as images is not found.
+func PDF(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if f.encrypted() {
+		return nil, ErrEncrypted
+	}
+
+	doc := &Document{}
+	w := &textWriter{}
+	for _, p := range f.pages() {
+		f.showPage(p, w)
+		w.newline()
+		doc.Links = append(doc.Links, f.pageLinks(p.dict)...)
+	}
+
+	text := w.String()
+	doc.Title = truncate(collapse(f.title()), maxTitle)
+	if doc.Title == "" {
+		doc.Title = firstLine(text)
+	}
+	doc.Content = collapse(text)
+	return doc, nil
+}
+
+// PDF objects, numbers are float64, booleans bool and null nil.
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+// pdfFile holds the objects of a PDF file by object number. Objects are
+// found by scanning the file, not by reading the cross-reference table, so
+// files with a broken table can still be read. A later definition of an
+// object replaces an earlier one, as with incremental updates.
+type pdfFile struct {
+	objects  map[int]any
+	trailers []pdfDict
+	fonts    map[pdfRef]*pdfFont
+}
+
+var (
+	pdfObjHeader     = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfTrailerHeader = regexp.MustCompile(`trailer\s*<<`)
+)
+
+func parsePDF(data []byte) (*pdfFile, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+
+	f := &pdfFile{objects: make(map[int]any), fonts: make(map[pdfRef]*pdfFont)}
+	var objStms []*pdfStream
+	for pos := 0; ; {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{data: data, pos: pos + loc[1], refs: true}
+		obj, err := l.object(0)
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if d, ok := obj.(pdfDict); ok {
+			if s, ok := l.stream(d); ok {
+				obj = s
+				switch d["Type"] {
+				case pdfName("ObjStm"):
+					objStms = append(objStms, s)
+				case pdfName("XRef"):
+					f.trailers = append(f.trailers, d)
+				}
+			}
+		}
+		f.objects[num] = obj
+		pos = l.pos
+	}
+
+	for _, loc := range pdfTrailerHeader.FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: loc[1] - 2, refs: true}
+		if d, err := l.object(0); err == nil {
+			if d, ok := d.(pdfDict); ok {
+				f.trailers = append(f.trailers, d)
+			}
+		}
+	}
+
+	for _, s := range objStms {
+		f.readObjectStream(s)
+	}
+	return f, nil
+}
+
+// readObjectStream adds the objects compressed into an object stream that
+// are not defined directly in the file.
+func (f *pdfFile) readObjectStream(s *pdfStream) {
+	data, err := f.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := f.number(s.dict["N"])
+	first, _ := f.number(s.dict["First"])
+	if first < 0 || int(first) > len(data) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		num, err := header.object(0)
+		if err != nil {
+			return
+		}
+		offset, err := header.object(0)
+		if err != nil {
+			return
+		}
+		objNum, ok1 := num.(float64)
+		objOffset, ok2 := offset.(float64)
+		if !ok1 || !ok2 || int(first+objOffset) >= len(data) {
+			return
+		}
+		if _, ok := f.objects[int(objNum)]; ok {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: int(first + objOffset), refs: true}
+		if obj, err := l.object(0); err == nil {
+			f.objects[int(objNum)] = obj
+		}
+	}
+}
+
+// resolve follows references to the object they point to.
+func (f *pdfFile) resolve(obj any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.objects[ref.num]
+	}
+	return nil
+}
+
+// dict returns the dictionary of obj, or of the stream obj is.
+func (f *pdfFile) dict(obj any) pdfDict {
+	switch v := f.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+func (f *pdfFile) array(obj any) pdfArray {
+	a, _ := f.resolve(obj).(pdfArray)
+	return a
+}
+
+func (f *pdfFile) number(obj any) (float64, bool) {
+	n, ok := f.resolve(obj).(float64)
+	return n, ok
+}
+
+func (f *pdfFile) name(obj any) pdfName {
+	n, _ := f.resolve(obj).(pdfName)
+	return n
+}
+
+func (f *pdfFile) encrypted() bool {
+	for _, t := range f.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// title returns the title of the document information dictionary.
+func (f *pdfFile) title() string {
+	for i := len(f.trailers) - 1; i >= 0; i-- {
+		info := f.dict(f.trailers[i]["Info"])
+		if s, ok := f.resolve(info["Title"]).(pdfString); ok {
+			return decodeText(s)
+		}
+	}
+	return ""
+}
+
+// decode applies the filters of a stream to its data.
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []pdfName{v}
+	case pdfArray:
+		for _, e := range v {
+			filters = append(filters, f.name(e))
+		}
+	}
+
+	data := s.data
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data = decodeHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			err = fmt.Errorf("unsupported filter %s", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib or raw deflate data. The data read before an
+// error is kept, streams of damaged files are often truncated.
+func inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxStream))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeHex(data []byte) []byte {
+	out := make([]byte, 0, len(data)/2)
+	var b byte
+	odd := false
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if odd {
+			out = append(out, b<<4|v)
+		} else {
+			b = v
+		}
+		odd = !odd
+	}
+	if odd {
+		out = append(out, b<<4)
+	}
+	return out
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	return io.ReadAll(io.LimitReader(ascii85.NewDecoder(bytes.NewReader(data)), maxStream))
+}
+
+// pdfPage is a page dictionary with the resources it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages of the document in order. Without a page tree
+// every page object is returned, by object number.
+func (f *pdfFile) pages() []pdfPage {
+	var pages []pdfPage
+	visited := make(map[any]bool)
+	var walk func(node any, resources pdfDict)
+	walk = func(node any, resources pdfDict) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		d := f.dict(node)
+		if d == nil {
+			return
+		}
+		if res := f.dict(d["Resources"]); res != nil {
+			resources = res
+		}
+		if kids, ok := d["Kids"]; ok {
+			for _, kid := range f.array(kids) {
+				walk(kid, resources)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: d, resources: resources})
+	}
+
+	for i := len(f.trailers) - 1; i >= 0 && len(pages) == 0; i-- {
+		catalog := f.dict(f.trailers[i]["Root"])
+		walk(catalog["Pages"], nil)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0)
+	for num, obj := range f.objects {
+		if d, ok := obj.(pdfDict); ok && d["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		d := f.objects[num].(pdfDict)
+		pages = append(pages, pdfPage{dict: d, resources: f.dict(d["Resources"])})
+	}
+	return pages
+}
+
+// pageLinks returns the URIs of the link annotations of a page.
+func (f *pdfFile) pageLinks(page pdfDict) []string {
+	var links []string
+	for _, annot := range f.array(page["Annots"]) {
+		a := f.dict(annot)
+		if f.name(a["Subtype"]) != "Link" {
+			continue
+		}
+		action := f.dict(a["A"])
+		if f.name(action["S"]) != "URI" {
+			continue
+		}
+		if uri, ok := f.resolve(action["URI"]).(pdfString); ok {
+			links = append(links, string(uri))
+		}
+	}
+	return links
+}
+
+// showPage writes the text of a page to w.
+func (f *pdfFile) showPage(p pdfPage, w *textWriter) {
+	var content []byte
+	contents := f.resolve(p.dict["Contents"])
+	streams := pdfArray{contents}
+	if a, ok := contents.(pdfArray); ok {
+		streams = a
+	}
+	for _, s := range streams {
+		s, ok := f.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+	f.show(content, p.resources, w, 0)
+}
+
+// show interprets the text operators of a content stream. Text positioning
+// is only used to tell where lines and words break.
+func (f *pdfFile) show(content []byte, resources pdfDict, w *textWriter, depth int) {
+	fonts := f.dict(resources["Font"])
+	var font *pdfFont
+	var lastY float64
+	var operands []any
+
+	l := &pdfLexer{data: content}
+	for {
+		obj, err := l.object(0)
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			if len(operands) < 64 {
+				operands = append(operands, obj)
+			}
+			continue
+		}
+
+		last := func(i int) any {
+			if i >= len(operands) {
+				return nil
+			}
+			return operands[len(operands)-1-i]
+		}
+		switch op {
+		case "Tf":
+			if name, ok := last(1).(pdfName); ok {
+				font = f.font(fonts[name])
+			}
+		case "Tj":
+			w.show(font, last(0))
+		case "'":
+			w.newline()
+			w.show(font, last(0))
+		case `"`:
+			w.newline()
+			w.show(font, last(0))
+		case "TJ":
+			a, _ := last(0).(pdfArray)
+			for _, e := range a {
+				// a large negative adjustment, in thousandths of a text
+				// space unit, separates words
+				if n, ok := e.(float64); ok && n < -180 {
+					w.space()
+					continue
+				}
+				w.show(font, e)
+			}
+		case "Td", "TD":
+			if ty, _ := last(0).(float64); ty != 0 {
+				w.newline()
+			} else {
+				w.space()
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if y, ok := last(0).(float64); ok && y != lastY {
+				lastY = y
+				w.newline()
+			} else {
+				w.space()
+			}
+		case "ET":
+			w.space()
+		case "BI":
+			l.skipInlineImage()
+		case "Do":
+			name, _ := last(0).(pdfName)
+			xobj, ok := f.resolve(f.dict(resources["XObject"])[name]).(*pdfStream)
+			if !ok || f.name(xobj.dict["Subtype"]) != "Form" || depth >= 8 {
+				break
+			}
+			if data, err := f.decode(xobj); err == nil {
+				res := f.dict(xobj.dict["Resources"])
+				if res == nil {
+					res = resources
+				}
+				f.show(data, res, w, depth+1)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// textWriter collects the text shown on the pages.
+type textWriter struct {
+	strings.Builder
+}
+
+func (w *textWriter) show(font *pdfFont, s any) {
+	if str, ok := s.(pdfString); ok && font != nil {
+		w.WriteString(font.decode(str))
+	}
+}
+
+func (w *textWriter) space() {
+	if w.Len() > 0 && !strings.HasSuffix(w.String(), " ") && !strings.HasSuffix(w.String(), "\n") {
+		w.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newline() {
+	if w.Len() > 0 && !strings.HasSuffix(w.String(), "\n") {
+		w.WriteByte('\n')
+	}
+}
+
+// pdfFont decodes the strings shown with a font to text.
+type pdfFont struct {
+	// nil if the font has no ToUnicode map
+	cmap *toUnicode
+
+	// composite fonts use multi-byte codes for glyph IDs that can only be
+	// decoded with a ToUnicode map, simple fonts use one byte codes
+	composite bool
+	encoding  [256]rune
+}
+
+func (f *pdfFile) font(obj any) *pdfFont {
+	ref, isRef := obj.(pdfRef)
+	if font, ok := f.fonts[ref]; isRef && ok {
+		return font
+	}
+
+	d := f.dict(obj)
+	font := &pdfFont{
+		composite: f.name(d["Subtype"]) == "Type0",
+		encoding:  winAnsiEncoding,
+	}
+	if s, ok := f.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := f.decode(s); err == nil {
+			font.cmap = parseToUnicode(data)
+		}
+	}
+
+	switch enc := f.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		font.encoding = baseEncoding(enc)
+	case pdfDict:
+		font.encoding = baseEncoding(f.name(enc["BaseEncoding"]))
+		code := 0
+		for _, e := range f.array(enc["Differences"]) {
+			switch v := f.resolve(e).(type) {
+			case float64:
+				code = int(v)
+			case pdfName:
+				if r, ok := glyphRune(string(v)); ok && code >= 0 && code < 256 {
+					font.encoding[code] = r
+				}
+				code++
+			}
+		}
+	}
+
+	if isRef {
+		f.fonts[ref] = font
+	}
+	return font
+}
+
+func (font *pdfFont) decode(s pdfString) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if font.cmap != nil {
+			n := font.cmap.codeLength(s[i:], font.composite)
+			if text, ok := font.cmap.chars[string(s[i:i+n])]; ok {
+				b.WriteString(text)
+				i += n
+				continue
+			}
+			if font.composite {
+				i += n
+				continue
+			}
+		} else if font.composite {
+			// glyph IDs without a map to Unicode
+			return b.String()
+		}
+		if r := font.encoding[s[i]]; r != 0 {
+			b.WriteRune(r)
+		}
+		i++
+	}
+	return b.String()
+}
+
+// toUnicode is a ToUnicode CMap, it maps character codes to text.
+type toUnicode struct {
+	// the byte length of the codes of each codespace range
+	ranges []codespace
+	chars  map[string]string
+}
+
+type codespace struct {
+	lo, hi []byte
+}
+
+func parseToUnicode(data []byte) *toUnicode {
+	cmap := &toUnicode{chars: make(map[string]string)}
+	l := &pdfLexer{data: data}
+	var operands []any
+	for {
+		obj, err := l.object(0)
+		if err != nil {
+			return cmap
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cmap.ranges = append(cmap.ranges, codespace{lo: lo, hi: hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					cmap.chars[string(src)] = decodeUTF16(dst, true)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cmap.addRange(lo, hi, operands[i+2])
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// addRange maps the codes from lo to hi to consecutive text, starting with
+// dst, or to the texts of the array dst.
+func (cmap *toUnicode) addRange(lo, hi []byte, dst any) {
+	from, to := codeValue(lo), codeValue(hi)
+	if to < from || to-from > 0xFFFF {
+		return
+	}
+	code := make([]byte, len(lo))
+	for c := from; c <= to; c++ {
+		for i := range code {
+			code[len(code)-1-i] = byte(c >> (8 * i))
+		}
+		switch d := dst.(type) {
+		case pdfString:
+			if len(d) < 2 {
+				return
+			}
+			units := make([]uint16, len(d)/2)
+			for i := range units {
+				units[i] = uint16(d[2*i])<<8 | uint16(d[2*i+1])
+			}
+			units[len(units)-1] += uint16(c - from)
+			cmap.chars[string(code)] = string(utf16.Decode(units))
+		case pdfArray:
+			if int(c-from) >= len(d) {
+				return
+			}
+			if s, ok := d[c-from].(pdfString); ok {
+				cmap.chars[string(code)] = decodeUTF16(s, true)
+			}
+		}
+	}
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// codeLength returns the byte length of the code at the start of s.
+func (cmap *toUnicode) codeLength(s []byte, composite bool) int {
+	for _, r := range cmap.ranges {
+		n := len(r.lo)
+		if n > len(s) {
+			continue
+		}
+		in := true
+		for i := 0; i < n; i++ {
+			if s[i] < r.lo[i] || s[i] > r.hi[i] {
+				in = false
+				break
+			}
+		}
+		if in {
+			return n
+		}
+	}
+	if composite && len(s) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// pdfLexer reads PDF objects and content stream operators from data.
+type pdfLexer struct {
+	data []byte
+	pos  int
+
+	// read "num gen R" as a reference, references do not occur in content
+	// streams
+	refs bool
+}
+
+func isPDFSpace(b byte) bool {
+	return b == 0 || b == '\t' || b == '\n' || b == '\f' || b == '\r' || b == ' '
+}
+
+func isPDFDelim(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch b := l.data[l.pos]; {
+		case isPDFSpace(b):
+			l.pos++
+		case b == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next object. Operators, keywords and the closing
+// delimiters of arrays and dictionaries are returned as a pdfKeyword.
+func (l *pdfLexer) object(depth int) (any, error) {
+	if depth > 64 {
+		return nil, errors.New("objects nested too deep")
+	}
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	switch b := l.data[l.pos]; b {
+	case '/':
+		return l.name(), nil
+	case '(':
+		return l.literal(), nil
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.dict(depth)
+		}
+		return l.hex(), nil
+	case '[':
+		l.pos++
+		return l.array(depth)
+	case '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return pdfKeyword(">>"), nil
+	case ']', ')', '{', '}':
+		l.pos++
+		return pdfKeyword([]byte{b}), nil
+	}
+
+	tok := l.regular()
+	if n, err := strconv.ParseFloat(tok, 64); err == nil {
+		if l.refs {
+			if ref, ok := l.ref(tok); ok {
+				return ref, nil
+			}
+		}
+		return n, nil
+	}
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(tok), nil
+}
+
+// ref reads the rest of a "num gen R" reference starting with num.
+func (l *pdfLexer) ref(num string) (pdfRef, bool) {
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return pdfRef{}, false
+	}
+	save := l.pos
+	l.skipSpace()
+	gen, err := strconv.Atoi(l.regular())
+	if err == nil {
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+			(l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelim(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{num: n, gen: gen}, true
+		}
+	}
+	l.pos = save
+	return pdfRef{}, false
+}
+
+func (l *pdfLexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start && l.pos < len(l.data) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	raw := l.regular()
+	if !strings.Contains(raw, "#") {
+		return pdfName(raw)
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			hi, ok1 := hexValue(raw[i+1])
+			lo, ok2 := hexValue(raw[i+2])
+			if ok1 && ok2 {
+				b.WriteByte(hi<<4 | lo)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(raw[i])
+	}
+	return pdfName(b.String())
+}
+
+func (l *pdfLexer) literal() pdfString {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+		case '\r':
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hex() pdfString {
+	l.pos++
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		end = len(l.data) - l.pos
+	}
+	s := decodeHex(l.data[l.pos : l.pos+end])
+	l.pos += end + 1
+	return s
+}
+
+func (l *pdfLexer) array(depth int) (pdfArray, error) {
+	a := pdfArray{}
+	for {
+		obj, err := l.object(depth + 1)
+		if err != nil {
+			return a, err
+		}
+		if obj == pdfKeyword("]") {
+			return a, nil
+		}
+		a = append(a, obj)
+	}
+}
+
+func (l *pdfLexer) dict(depth int) (pdfDict, error) {
+	d := pdfDict{}
+	for {
+		key, err := l.object(depth + 1)
+		if err != nil {
+			return d, err
+		}
+		if key == pdfKeyword(">>") {
+			return d, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue
+		}
+		value, err := l.object(depth + 1)
+		if err != nil {
+			return d, err
+		}
+		if value == pdfKeyword(">>") {
+			return d, nil
+		}
+		d[name] = value
+	}
+}
+
+// stream reads the data of the stream following the stream dictionary d.
+// The data ends after /Length bytes if that is followed by endstream,
+// otherwise at the next endstream.
+func (l *pdfLexer) stream(d pdfDict) (*pdfStream, bool) {
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return nil, false
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+
+	end := -1
+	if n, ok := d["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[start+int(n):], "\r\n\t\f\x00 ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + int(n)
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			i = len(l.data) - start
+		}
+		end = start + i
+		for _, eol := range []string{"\r\n", "\n", "\r"} {
+			if bytes.HasSuffix(l.data[start:end], []byte(eol)) {
+				end -= len(eol)
+				break
+			}
+		}
+	}
+
+	l.pos = end
+	if i := bytes.Index(l.data[end:], []byte("endstream")); i >= 0 {
+		l.pos = end + i + len("endstream")
+	}
+	return &pdfStream{dict: d, data: l.data[start:end]}, true
+}
+
+// skipInlineImage skips the data of an inline image, up to its EI operator.
+func (l *pdfLexer) skipInlineImage() {
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		at := l.pos + i
+		l.pos = at + 2
+		if at > 0 && isPDFSpace(l.data[at-1]) && (l.pos == len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildPDF writes a PDF file of objects, numbered from 1, with a
+// cross-reference table and a trailer pointing to the catalog in object 1.
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		if obj != "" {
+			fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+		}
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+func pdfStreamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(s string) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.Bytes()
+}
+
+func utf16Hex(s string) string {
+	var b bytes.Buffer
+	b.Write([]byte{0xFE, 0xFF})
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.Write([]byte{byte(u >> 8), byte(u)})
+	}
+	return "<" + hex.EncodeToString(b.Bytes()) + ">"
+}
+
+func testPDF(trailer string) []byte {
+	fontF1 := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica " +
+		"/Encoding << /BaseEncoding /WinAnsiEncoding /Differences [39 /quoteright] >> >>"
+	objStmHeader := "5 0 "
+
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfchar
+<0003> <0020>
+endbfchar
+1 beginbfrange
+<0041> <005A> <0041>
+endbfrange
+endcmap
+end end`
+
+	objects := []string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		3: "<< /Type /Page /Parent 2 0 R /Contents 7 0 R " +
+			"/Annots [<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com/spec) >> >>] >>",
+		4: "<< /Type /Page /Parent 2 0 R /Contents [8 0 R 9 0 R] >>",
+		5: "",
+		6: "<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 11 0 R >>",
+		7: pdfStreamObject("/Filter /FlateDecode", deflate(
+			`BT /F1 12 Tf 72 720 Td (User Guide) Tj 0 -14 Td [(Caf) -10 (\351 con) -20 (figuration)] TJ `+
+				`0 -14 Td [(It\047s) -300 (\(simple\))] TJ ET`)),
+		8: pdfStreamObject("", []byte(`BT /F2 11 Tf 1 0 0 1 72 600 Tm <0050004400460003004300410050> Tj ET`)),
+		9: pdfStreamObject("/Filter /ASCIIHexDecode", []byte(hex.EncodeToString(
+			[]byte("BI /W 1 /H 1 /BPC 8 /CS /G ID \x00) EI\nBT /F1 10 Tf T* (final line) Tj ET"))+">")),
+		10: pdfStreamObject(fmt.Sprintf("/Type /ObjStm /N 1 /First %d /Filter /FlateDecode", len(objStmHeader)),
+			deflate(objStmHeader+fontF1)),
+		11: pdfStreamObject("", []byte(cmap)),
+		12: "<< /Title " + utf16Hex("Guide für PDF") + " /Producer (test) >>",
+	}
+	return buildPDF(objects[1:], trailer)
+}
+
+func Test_pdf(t *testing.T) {
+	doc, err := PDF(bytes.NewReader(testPDF("/Info 12 0 R")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "User Guide Café configuration It’s (simple) PDF CAP final line"
+	if doc.Content != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", doc.Content, expect)
+	}
+	if doc.Title != "Guide für PDF" {
+		t.Fatalf("\ngot:%v \nexpect:%v", doc.Title, "Guide für PDF")
+	}
+	if fmt.Sprint(doc.Links) != "[https://example.com/spec]" {
+		t.Fatalf("\ngot:%v \nexpect:%v", doc.Links, "[https://example.com/spec]")
+	}
+
+	t.Run("test_title_from_text", func(t *testing.T) {
+		doc, err := PDF(bytes.NewReader(testPDF("")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Title != "User Guide" {
+			t.Fatalf("\ngot:%v \nexpect:%v", doc.Title, "User Guide")
+		}
+	})
+
+	t.Run("test_encrypted", func(t *testing.T) {
+		_, err := PDF(bytes.NewReader(testPDF("/Encrypt << /Filter /Standard >>")))
+		if !errors.Is(err, ErrEncrypted) {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, ErrEncrypted)
+		}
+	})
+
+	t.Run("test_not_pdf", func(t *testing.T) {
+		if _, err := PDF(strings.NewReader("<html></html>")); err == nil {
+			t.Fatal("expected an error for a file that is not a PDF")
+		}
+	})
+}
+
+func Test_mac_roman(t *testing.T) {
+	if n := len([]rune(macRomanHigh)); n != 128 {
+		t.Fatalf("\ngot:%v \nexpect:%v", n, 128)
+	}
+	if macRomanEncoding[0x8E] != 'é' || winAnsiEncoding[0x92] != '’' || winAnsiEncoding[0xE9] != 'é' {
+		t.Fatal("wrong encoding table")
+	}
+}
@@ -0,0 +1,188 @@
+package extract
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text extracts a plain text document. Its title is the first non-empty
+// line.
+func Text(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := decodeText(data)
+	return &Document{Title: firstLine(text), Content: collapse(text)}, nil
+}
+
+// decodeText decodes UTF-8 and, if marked by a byte order mark, UTF-16 text.
+// Anything else is decoded as Latin-1.
+func decodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+var (
+	mdFence      = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdSetext     = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdRefDef     = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*<?([^\s>]+)>?`)
+	mdQuote      = regexp.MustCompile(`^\s*(?:>\s?)+`)
+	mdListMarker = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	mdTableRule  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]*)\]\(\s*<?([^\s)>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	mdRefLink    = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutolink   = regexp.MustCompile(`<((?:https?|ftp)://[^>\s]+)>`)
+	mdTag        = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdEmphasis   = regexp.MustCompile("\\*+|~~|`+|\\b_+|_+\\b")
+)
+
+// Markdown extracts a Markdown document. Its title is the title of the YAML
+// front matter, the first top level heading or the first non-empty line, in
+// that order. The content is the text without the markup, the text of code
+// blocks is kept.
+func Markdown(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(decodeText(data), "\r\n", "\n"), "\n")
+
+	doc := &Document{}
+	i := frontMatter(lines, doc)
+
+	var content []string
+	heading, headingLevel := "", 7
+	addHeading := func(text string, level int) {
+		text = doc.inline(text)
+		content = append(content, text)
+		if level < headingLevel {
+			heading, headingLevel = text, level
+		}
+	}
+
+	fence := ""
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if m[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			content = append(content, line)
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			addHeading(m[2], len(m[1]))
+			continue
+		}
+		if strings.TrimSpace(line) != "" && i+1 < len(lines) && mdSetext.MatchString(lines[i+1]) && !mdListMarker.MatchString(line) {
+			level := 2
+			if strings.Contains(lines[i+1], "=") {
+				level = 1
+			}
+			addHeading(line, level)
+			i++
+			continue
+		}
+		if m := mdRefDef.FindStringSubmatch(line); m != nil {
+			doc.Links = append(doc.Links, m[1])
+			continue
+		}
+		if mdRule.MatchString(line) || mdTableRule.MatchString(line) && strings.Contains(line, "-") {
+			continue
+		}
+
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mdListMarker.ReplaceAllString(line, "")
+		line = strings.ReplaceAll(line, "|", " ")
+		content = append(content, doc.inline(line))
+	}
+
+	doc.Content = collapse(strings.Join(content, "\n"))
+	if doc.Title == "" {
+		doc.Title = heading
+	}
+	if doc.Title == "" {
+		doc.Title = firstLine(doc.Content)
+	}
+	doc.Title = truncate(collapse(doc.Title), maxTitle)
+	return doc, nil
+}
+
+// frontMatter reads the title of the YAML front matter at the start of lines
+// and returns the index of the first line after it.
+func frontMatter(lines []string, doc *Document) int {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0
+	}
+	title := ""
+	for i := 1; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); t == "---" || t == "..." {
+			doc.Title = title
+			return i + 1
+		}
+		if key, value, ok := strings.Cut(lines[i], ":"); ok && strings.TrimSpace(key) == "title" {
+			title = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	// not front matter but a rule at the start of the document
+	return 0
+}
+
+// inline removes the inline markup of a line of Markdown and records the
+// URLs it links to.
+func (doc *Document) inline(line string) string {
+	line = mdImage.ReplaceAllString(line, "$1")
+	for _, m := range mdLink.FindAllStringSubmatch(line, -1) {
+		doc.Links = append(doc.Links, m[2])
+	}
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdRefLink.ReplaceAllString(line, "$1")
+	for _, m := range mdAutolink.FindAllStringSubmatch(line, -1) {
+		doc.Links = append(doc.Links, m[1])
+	}
+	line = mdAutolink.ReplaceAllString(line, "$1")
+	line = mdTag.ReplaceAllString(line, "")
+	line = mdEmphasis.ReplaceAllString(line, "")
+	return html.UnescapeString(line)
+}
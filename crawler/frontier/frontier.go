@@ -0,0 +1,136 @@
+// Package frontier defines the crawl frontier, a persistent priority queue of
+// links that are due to be crawled.
+package frontier
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry is a link in the frontier.
+type Entry struct {
+	LinkID uuid.UUID
+	URL    string
+
+	// The number of hops from the nearest seed link.
+	Depth int
+
+	// Whether the link was submitted by a user rather than discovered by
+	// the crawler.
+	Submitted bool
+
+	// The score the entry was dequeued with.
+	Priority float64
+
+	// If not zero, Push makes an existing entry due no later than DueAt.
+	DueAt time.Time
+
+	// The end of the lease the entry was dequeued with. Every entry of a
+	// batch shares the same lease.
+	LeasedUntil time.Time
+}
+
+// Link is what the link graph and the index know about a link when its entry
+// is refreshed.
+type Link struct {
+	ID  uuid.UUID
+	URL string
+
+	// The PageRank score of the link relative to the best ranked link.
+	Rank float64
+
+	// The time the link was last retrieved, zero if it never was.
+	RetrievedAt time.Time
+}
+
+// Freshness returns the time since the last retrieval of l relative to
+// interval, capped at 1. Links that were never retrieved score 1.
+func (l Link) Freshness(now time.Time, interval time.Duration) float64 {
+	if l.RetrievedAt.IsZero() || interval <= 0 {
+		return 1
+	}
+	return math.Max(math.Min(now.Sub(l.RetrievedAt).Seconds()/interval.Seconds(), 1), 0)
+}
+
+// Weights controls how much each signal contributes to the priority of an
+// entry. Every signal is normalized to the range [0, 1] before weighting.
+type Weights struct {
+	// The PageRank score of the link relative to the best ranked link.
+	Pagerank float64
+
+	// The time since the link was last retrieved relative to the recrawl
+	// interval. Links that were never retrieved score 1.
+	Freshness float64
+
+	// 1/(1+depth), favouring links close to a seed.
+	Depth float64
+
+	// 1 for user-submitted links, 0 otherwise.
+	Submitted float64
+}
+
+// Score returns the priority of an entry, rank is the normalized PageRank
+// score and freshness the normalized time since its last retrieval.
+func (w Weights) Score(rank, freshness float64, depth int, submitted bool) float64 {
+	score := w.Pagerank*rank + w.Freshness*freshness + w.Depth/float64(1+depth)
+	if submitted {
+		score += w.Submitted
+	}
+	return score
+}
+
+// DefaultWeights ranks user submissions first, followed by high ranked and
+// stale pages.
+var DefaultWeights = Weights{
+	Pagerank:  1.0,
+	Freshness: 0.5,
+	Depth:     0.5,
+	Submitted: 2.0,
+}
+
+// Scoring is how the priority of entries is computed.
+type Scoring struct {
+	Weights Weights
+
+	// The interval the freshness of a link is relative to.
+	RecrawlInterval time.Duration
+}
+
+// Store is implemented by persistent frontier backends. Entries are scored
+// when they are pushed and acknowledged, Refresh only needs to run now and
+// then to pick up new PageRank scores and links added outside of the crawler.
+// The links and their scores are handed to the store, it does not read the
+// link graph or the index.
+type Store interface {
+	// Refresh adds the links that are not yet part of the frontier and
+	// recomputes the priority of the entries of the links. A new entry
+	// becomes due once its link was last retrieved longer than the recrawl
+	// interval ago, unless the link has a recrawl schedule of its own.
+	Refresh(links []Link, s Scoring) error
+
+	// Dequeue leases up to n due entries in the [fromID, toID) range,
+	// highest priority first. Leased entries are not returned again until
+	// the lease expires or they are acknowledged.
+	Dequeue(fromID, toID uuid.UUID, n int, lease time.Duration) ([]Entry, error)
+
+	// Push adds newly discovered links to the frontier. Existing entries
+	// keep the smaller of both depths and the earlier due time if DueAt is
+	// set. An entry Refresh added before the link was pushed and that was
+	// not crawled since takes the pushed depth, the refresh does not know
+	// the depth of a link.
+	Push(entries []Entry, s Scoring) error
+
+	// Ack marks a leased entry as crawled and schedules it again at
+	// nextDue. Its freshness is the time between now and nextDue.
+	Ack(linkID uuid.UUID, nextDue time.Time, s Scoring) error
+}
+
+// Releaser is implemented by frontier stores that can end a lease before it
+// expires, e.g. to hand out the batch of an interrupted pass again.
+type Releaser interface {
+	// Release ends the lease of the entries in the [fromID, toID) range that
+	// were leased until leasedUntil and have not been acknowledged.
+	Release(fromID, toID uuid.UUID, leasedUntil time.Time) error
+}
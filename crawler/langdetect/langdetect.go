@@ -0,0 +1,141 @@
+// Package langdetect detects the language of a text offline by comparing its
+// character trigrams with the trigram profiles of known languages (Cavnar and
+// Trenkle, "N-Gram-Based Text Categorization"). The profiles are built from
+// the sample texts in the corpus directory, one file per ISO 639-1 code.
+package langdetect
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed corpus/*.txt
+var corpus embed.FS
+
+const (
+	// the number of most frequent trigrams kept in a profile
+	profileSize = 300
+
+	// the number of bytes of a text that are looked at
+	maxSample = 16 << 10
+
+	// texts with fewer distinct trigrams are not classified
+	minTrigrams = 20
+)
+
+// profile maps a trigram to its rank, most frequent first.
+type profile map[string]int
+
+var (
+	loadOnce sync.Once
+	profiles map[string]profile
+)
+
+func load() {
+	profiles = make(map[string]profile)
+	files, err := corpus.ReadDir("corpus")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		data, err := corpus.ReadFile(path.Join("corpus", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		profiles[strings.TrimSuffix(f.Name(), ".txt")] = newProfile(string(data))
+	}
+}
+
+// Languages returns the ISO 639-1 codes of the languages that can be
+// detected.
+func Languages() []string {
+	loadOnce.Do(load)
+	langs := make([]string, 0, len(profiles))
+	for lang := range profiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in. It
+// reports false if the text is too short to tell.
+func Detect(text string) (string, bool) {
+	loadOnce.Do(load)
+	if len(text) > maxSample {
+		text = text[:maxSample]
+	}
+
+	p := newProfile(text)
+	if len(p) < minTrigrams {
+		return "", false
+	}
+
+	best, bestDistance := "", -1
+	for _, lang := range Languages() {
+		if d := distance(p, profiles[lang]); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = lang, d
+		}
+	}
+	return best, true
+}
+
+// distance is the out-of-place measure of the Cavnar and Trenkle paper: the
+// sum of the rank differences of the trigrams of p in the language profile,
+// trigrams missing from it count as the maximum difference.
+func distance(p, lang profile) int {
+	d := 0
+	for gram, rank := range p {
+		langRank, ok := lang[gram]
+		if !ok {
+			d += profileSize
+			continue
+		}
+		if langRank > rank {
+			d += langRank - rank
+		} else {
+			d += rank - langRank
+		}
+	}
+	return d
+}
+
+// newProfile ranks the most frequent trigrams of the words of text. Words are
+// padded with a space on both sides so prefixes and suffixes are counted.
+func newProfile(text string) profile {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	p := make(profile, len(grams))
+	for rank, gram := range grams {
+		p[gram] = rank
+	}
+	return p
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && r != '\''
+}
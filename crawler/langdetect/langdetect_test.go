@@ -0,0 +1,44 @@
+package langdetect
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_detect(t *testing.T) {
+	tests := []struct {
+		expect string
+		text   string
+	}{
+		{"en", "The weather was nice yesterday, so we walked to the beach and watched the boats coming back to the harbour."},
+		{"id", "Cuaca kemarin sangat cerah, jadi kami berjalan ke pantai dan melihat perahu-perahu yang kembali ke pelabuhan."},
+		{"de", "Das Wetter war gestern schön, also sind wir zum Strand gelaufen und haben die Boote beobachtet, die in den Hafen zurückkamen."},
+		{"fr", "Il faisait beau hier, alors nous avons marché jusqu'à la plage et regardé les bateaux qui rentraient au port."},
+		{"es", "Ayer hizo buen tiempo, así que caminamos hasta la playa y miramos los barcos que volvían al puerto."},
+		{"pt", "Ontem o tempo estava bom, então caminhamos até a praia e vimos os barcos que voltavam para o porto."},
+		{"it", "Ieri il tempo era bello, così siamo andati a piedi alla spiaggia e abbiamo guardato le barche che tornavano al porto."},
+		{"nl", "Het weer was gisteren mooi, dus we liepen naar het strand en keken naar de boten die terugkwamen in de haven."},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("test_%s", tt.expect), func(t *testing.T) {
+			got, ok := Detect(tt.text)
+			if !ok || got != tt.expect {
+				t.Fatalf("\ngot:%v %v \nexpect:%v", got, ok, tt.expect)
+			}
+		})
+	}
+
+	t.Run("test_short_text", func(t *testing.T) {
+		if got, ok := Detect("ok"); ok {
+			t.Fatalf("\ngot:%v \nmessage:%v", got, "short text was classified")
+		}
+	})
+
+	t.Run("test_languages", func(t *testing.T) {
+		expect := "[de en es fr id it nl pt]"
+		if got := fmt.Sprint(Languages()); got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+// anchorGraph is a recordGraph that keeps the anchors of edges by destination
+// URL.
+type anchorGraph struct {
+	*recordGraph
+	anchors map[string]anchor
+}
+
+func (g *anchorGraph) UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error {
+	if err := g.UpsertEdge(edge); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.anchors[g.urls[edge.Dst]] = anchor{text: text, rel: rel}
+	return nil
+}
+
+func (g *anchorGraph) AnchorText(dst uuid.UUID) (string, error) {
+	return "text of " + dst.String(), nil
+}
+
+func Test_anchor_text(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head></head><body>
+			<a href="/docs?utm_source=nav">Read the <b>docs</b></a>
+			<a href="/docs"></a>
+			<a href="https://example.com/" rel="nofollow">sponsor</a>
+			<a href="/plain">plain</a>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	graph := &anchorGraph{recordGraph: newRecordGraph(), anchors: make(map[string]anchor)}
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &linkgraph.Link{URL: srv.URL + "/"}
+	if err := graph.UpsertLink(src); err != nil {
+		t.Fatal(err)
+	}
+	p, err := svc.pages.get(context.Background(), src.URL, recrawl.State{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := webcrawler.NewResource()
+	r.ID = src.ID
+	r.URL = src.URL
+	if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]anchor{
+		srv.URL + "/docs":      {text: "Read the docs"},
+		"https://example.com/": {text: "sponsor", rel: "nofollow"},
+		srv.URL + "/plain":     {text: "plain"},
+	}
+	if fmt.Sprint(graph.anchors) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", graph.anchors, expect)
+	}
+
+	if doc := idx.docs[src.ID]; doc == nil || doc.AnchorText != "text of "+src.ID.String() {
+		t.Fatalf("\ngot:%+v \nexpect:%v \nmessage:%v", doc, "text of "+src.ID.String(), "anchor text not indexed")
+	}
+}
@@ -0,0 +1,74 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/pageinfo"
+	"github.com/odit-bit/se/crawler/warc"
+	"github.com/odit-bit/webcrawler"
+)
+
+// Replay rebuilds the link graph and the index from the responses archived in
+// a WARC file, e.g. after the extractors were improved. Every page answered
+// with 200 OK goes through the same consumer as in a crawl pass, pages are
+// recorded under the URL they were archived with. It returns the number of
+// pages replayed.
+func (la *CrawlService) Replay(r io.Reader) (int, error) {
+	wr, err := warc.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	consumer := la.newConsumer(nil)
+	var n int
+	for {
+		rec, err := wr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if rec.Type() != warc.TypeResponse {
+			continue
+		}
+
+		replayed, err := la.replay(consumer, rec)
+		if err != nil {
+			return n, fmt.Errorf("replay %s: %w", rec.TargetURI(), err)
+		}
+		if replayed {
+			n++
+		}
+	}
+}
+
+// replay hands the page of a response record to the consumer, it reports
+// whether the page was answered.
+func (la *CrawlService) replay(consumer *linkConsumer, rec *warc.Record) (bool, error) {
+	res, err := rec.HTTPResponse()
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	p := &page{info: &pageinfo.Info{}}
+	if err := la.pages.read(p, rec.TargetURI(), res); err != nil {
+		return false, err
+	}
+
+	link := &linkgraph.Link{URL: rec.TargetURI(), RetrievedAt: rec.Date()}
+	if err := la.graphAPI.UpsertLink(link); err != nil {
+		return false, err
+	}
+	r := webcrawler.NewResource()
+	r.ID = link.ID
+	r.URL = link.URL
+	defer r.Put()
+	return true, consumer.upsertResource(r, p)
+}
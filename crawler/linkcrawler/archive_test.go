@@ -0,0 +1,86 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/warc"
+)
+
+func Test_archive_replay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body>Read the <a href="/notes.md">notes</a></body></html>`))
+		case "/notes.md":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("# Notes\n\nsee [home](/)\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	archive, err := warc.NewWriter(dir, "test", 1<<20, "se-crawler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawler, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: fakeIndex{}, Archive: archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/", "/notes.md", "/missing"} {
+		if _, err := crawler.pages.get(context.Background(), srv.URL+path, recrawl.State{}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	if len(files) != 1 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(files), 1)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the archive is replayed into an empty graph and index
+	graph := newRecordGraph()
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := svc.Replay(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", n, 2, "only answered pages are replayed")
+	}
+
+	home, notes := graph.ids[srv.URL+"/"], graph.ids[srv.URL+"/notes.md"]
+	if doc := idx.docs[home]; doc == nil || doc.Title != "Home" || doc.Content != "Read the notes" {
+		t.Fatalf("\ngot:%+v", doc)
+	}
+	if doc := idx.docs[notes]; doc == nil || doc.Title != "Notes" || doc.MediaType != "text/markdown" {
+		t.Fatalf("\ngot:%+v", doc)
+	}
+	if got := graph.outlinks(home); len(got) != 1 || got[0] != srv.URL+"/notes.md" {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, srv.URL+"/notes.md")
+	}
+	if got := graph.outlinks(notes); len(got) != 1 || got[0] != srv.URL+"/" {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, srv.URL+"/")
+	}
+}
@@ -0,0 +1,105 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/graph/outlink"
+	"github.com/odit-bit/webcrawler"
+)
+
+// batchGraph is a recordGraph that counts the batched writes.
+type batchGraph struct {
+	*recordGraph
+	batches atomic.Int64
+}
+
+func (g *batchGraph) UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error {
+	g.batches.Add(1)
+	if err := g.UpsertLink(src); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	delete(g.edges, src.ID)
+	g.mu.Unlock()
+	for _, link := range links {
+		g.mu.Lock()
+		_, known := g.ids[link.URL]
+		g.mu.Unlock()
+		if err := g.UpsertLink(&link.Link); err != nil {
+			return err
+		}
+		link.New = !known
+		if err := g.UpsertEdge(&linkgraph.Edge{Src: src.ID, Dst: link.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_outlinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head></head><body>
+			<a href="/a">a</a> <a href="/b?utm_source=x">b</a> <a href="/b">b</a> <a href="/c">c</a>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	crawl := func(t *testing.T, graph GraphUpdater, rg *recordGraph) []string {
+		svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: newRecordIndex()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// an edge the page no longer links
+		src := &linkgraph.Link{URL: srv.URL + "/"}
+		old := &linkgraph.Link{URL: srv.URL + "/old"}
+		for _, link := range []*linkgraph.Link{src, old} {
+			if err := rg.UpsertLink(link); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rg.UpsertEdge(&linkgraph.Edge{Src: src.ID, Dst: old.ID}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+
+		p, err := svc.pages.get(context.Background(), src.URL, recrawl.State{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := webcrawler.NewResource()
+		r.ID = src.ID
+		r.URL = src.URL
+		if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+			t.Fatal(err)
+		}
+		return rg.outlinks(src.ID)
+	}
+	expect := fmt.Sprint([]string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"})
+
+	t.Run("test_batch", func(t *testing.T) {
+		graph := &batchGraph{recordGraph: newRecordGraph()}
+		if got := crawl(t, graph, graph.recordGraph); fmt.Sprint(got) != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if graph.batches.Load() != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v", graph.batches.Load(), 1)
+		}
+	})
+
+	t.Run("test_one_by_one", func(t *testing.T) {
+		graph := newRecordGraph()
+		if got := crawl(t, graph, graph); fmt.Sprint(got) != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package linkcrawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+// recordGraph is an in-memory GraphUpdater that keeps the upserted links and
+// edges.
+type recordGraph struct {
+	fakeGraph
+
+	mu    sync.Mutex
+	ids   map[string]uuid.UUID
+	urls  map[uuid.UUID]string
+	edges map[uuid.UUID]map[uuid.UUID]time.Time
+}
+
+func newRecordGraph() *recordGraph {
+	return &recordGraph{
+		ids:   make(map[string]uuid.UUID),
+		urls:  make(map[uuid.UUID]string),
+		edges: make(map[uuid.UUID]map[uuid.UUID]time.Time),
+	}
+}
+
+func (g *recordGraph) UpsertLink(link *linkgraph.Link) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if link.ID == uuid.Nil {
+		id, ok := g.ids[link.URL]
+		if !ok {
+			id = uuid.New()
+		}
+		link.ID = id
+	}
+	g.ids[link.URL] = link.ID
+	g.urls[link.ID] = link.URL
+	return nil
+}
+
+func (g *recordGraph) UpsertEdge(edge *linkgraph.Edge) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.edges[edge.Src] == nil {
+		g.edges[edge.Src] = make(map[uuid.UUID]time.Time)
+	}
+	g.edges[edge.Src][edge.Dst] = time.Now()
+	return nil
+}
+
+func (g *recordGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for dst, updated := range g.edges[fromID] {
+		if updated.Before(updatedBefore) {
+			delete(g.edges[fromID], dst)
+		}
+	}
+	return nil
+}
+
+func (g *recordGraph) Links(fromID, toID uuid.UUID, _ time.Time) (linkgraph.LinkIterator, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var entries []frontier.Entry
+	for id, u := range g.urls {
+		if bytes.Compare(id[:], fromID[:]) >= 0 && bytes.Compare(id[:], toID[:]) < 0 {
+			entries = append(entries, frontier.Entry{LinkID: id, URL: u})
+		}
+	}
+	return &entryIterator{entries: entries}, nil
+}
+
+// linksExcept returns the sorted URLs of all links but src.
+func (g *recordGraph) linksExcept(src uuid.UUID) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []string
+	for id, u := range g.urls {
+		if id != src {
+			out = append(out, u)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// outlinks returns the sorted destination URLs of src.
+func (g *recordGraph) outlinks(src uuid.UUID) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []string
+	for dst := range g.edges[src] {
+		out = append(out, g.urls[dst])
+	}
+	sort.Strings(out)
+	return out
+}
+
+func Test_canonical_links(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/print" {
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/article?utm_source=feed"></head><body></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><head></head><body></body></html>`)
+	}))
+	defer srv.Close()
+
+	crawl := func(t *testing.T, graph *recordGraph, idx *recordIndex, rawURL string, found []string) uuid.UUID {
+		svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := &linkgraph.Link{URL: rawURL}
+		if err := graph.UpsertLink(src); err != nil {
+			t.Fatal(err)
+		}
+		p, err := svc.pages.get(context.Background(), src.URL, recrawl.State{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p.doc.Links = found
+
+		r := webcrawler.NewResource()
+		r.ID = src.ID
+		r.URL = src.URL
+		if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+			t.Fatal(err)
+		}
+		return src.ID
+	}
+
+	t.Run("test_found_urls", func(t *testing.T) {
+		graph, idx := newRecordGraph(), newRecordIndex()
+		src := crawl(t, graph, idx, srv.URL+"/home", []string{
+			"HTTP://Example.com:80/a?b=2&a=1",
+			"http://example.com/a?a=1&b=2&utm_medium=x#top",
+			"http://example.com/x/../b",
+			"mailto:someone@example.com",
+		})
+
+		expect := []string{"http://example.com/a?a=1&b=2", "http://example.com/b"}
+		if got := graph.linksExcept(src); fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if idx.n != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", idx.n, 1, "page was not indexed")
+		}
+	})
+
+	t.Run("test_rel_canonical", func(t *testing.T) {
+		graph, idx := newRecordGraph(), newRecordIndex()
+		src := crawl(t, graph, idx, srv.URL+"/print", []string{"http://example.com/other"})
+
+		expect := []string{srv.URL + "/article"}
+		if got := graph.outlinks(src); fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if got := graph.linksExcept(src); fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, expect, "links of a non-canonical page were followed")
+		}
+		if idx.n != 0 {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", idx.n, 0, "non-canonical page was indexed")
+		}
+	})
+}
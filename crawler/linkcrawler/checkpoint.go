@@ -0,0 +1,144 @@
+package linkcrawler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/report"
+)
+
+// passProgress tracks which links of a pass have been processed. Links are
+// processed out of order, lastID is the last link read from the graph up to
+// which every link has been processed. A nil *passProgress does nothing.
+type passProgress struct {
+	mu      sync.Mutex
+	lastID  uuid.UUID
+	pending []uuid.UUID
+	done    map[uuid.UUID]bool
+}
+
+func newPassProgress(lastID uuid.UUID) *passProgress {
+	return &passProgress{lastID: lastID, done: make(map[uuid.UUID]bool)}
+}
+
+// read records that a link was read from the graph.
+func (pp *passProgress) read(linkID uuid.UUID) {
+	if pp == nil {
+		return
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.pending = append(pp.pending, linkID)
+}
+
+// processed records that a link read from the graph was processed.
+func (pp *passProgress) processed(linkID uuid.UUID) {
+	if pp == nil {
+		return
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.done[linkID] = true
+	for len(pp.pending) > 0 && pp.done[pp.pending[0]] {
+		pp.lastID = pp.pending[0]
+		delete(pp.done, pp.lastID)
+		pp.pending = pp.pending[1:]
+	}
+}
+
+// last returns the last link up to which every link has been processed.
+func (pp *passProgress) last() uuid.UUID {
+	if pp == nil {
+		return uuid.Nil
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return pp.lastID
+}
+
+// passCheckpoint returns the checkpoint of the unfinished pass over the
+// [fromID, toID) range. If there is none, it returns the checkpoint of a new
+// pass, or nil if resumeOnly is set.
+func (la *CrawlService) passCheckpoint(fromID, toID uuid.UUID, resumeOnly bool) *checkpoint.Checkpoint {
+	if la.cfg.Checkpoints != nil {
+		c, err := la.cfg.Checkpoints.LoadCheckpoint(fromID, toID)
+		if err != nil {
+			// the pass starts over, links crawled since it started are
+			// not due again
+			log.Println("crawl checkpoint:", err)
+		}
+		if c != nil {
+			log.Printf("crawl pass: resuming pass %v after link %v", c.PassID, c.LastID)
+			return c
+		}
+	}
+	if resumeOnly {
+		return nil
+	}
+
+	now := time.Now()
+	return &checkpoint.Checkpoint{
+		PassID:          uuid.New(),
+		FromID:          fromID,
+		ToID:            toID,
+		RetrievedBefore: now.Add(-la.cfg.RecrawlInterval),
+		Counts:          report.Pass{StartedAt: now},
+	}
+}
+
+// saveCheckpoint saves the progress of an unfinished pass.
+func (la *CrawlService) saveCheckpoint(c checkpoint.Checkpoint, progress *passProgress, counts func() report.Pass) {
+	if la.cfg.Checkpoints == nil {
+		return
+	}
+	if last := progress.last(); last != uuid.Nil {
+		c.LastID = last
+	}
+	c.Counts = counts()
+	if err := la.cfg.Checkpoints.SaveCheckpoint(&c); err != nil {
+		log.Println("crawl checkpoint:", err)
+	}
+}
+
+// saveCheckpoints saves the progress of a running pass every
+// CheckpointInterval until the returned function is called.
+func (la *CrawlService) saveCheckpoints(c *checkpoint.Checkpoint, progress *passProgress, counts func() report.Pass) func() {
+	if la.cfg.Checkpoints == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(la.cfg.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				la.saveCheckpoint(*c, progress, counts)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// clearCheckpoint removes the checkpoint of a finished pass.
+func (la *CrawlService) clearCheckpoint(c *checkpoint.Checkpoint) {
+	if la.cfg.Checkpoints == nil {
+		return
+	}
+	if err := la.cfg.Checkpoints.ClearCheckpoint(c.PassID); err != nil {
+		log.Println("crawl checkpoint:", err)
+	}
+}
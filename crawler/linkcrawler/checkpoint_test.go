@@ -0,0 +1,201 @@
+package linkcrawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/report"
+	"github.com/odit-bit/se/index/indexapi"
+)
+
+// scanGraph iterates its links in ID order, skipping links retrieved after
+// the given time like the link graph does.
+type scanGraph struct {
+	fakeGraph
+
+	mu        sync.Mutex
+	links     []*linkgraph.Link
+	retrieved map[uuid.UUID]time.Time
+}
+
+func (g *scanGraph) UpsertLink(link *linkgraph.Link) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.retrieved[link.ID] = link.RetrievedAt
+	return nil
+}
+
+func (g *scanGraph) Links(fromID, toID uuid.UUID, before time.Time) (linkgraph.LinkIterator, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var entries []frontier.Entry
+	for _, l := range g.links {
+		if bytes.Compare(l.ID[:], fromID[:]) >= 0 && bytes.Compare(l.ID[:], toID[:]) < 0 &&
+			g.retrieved[l.ID].Before(before) {
+			entries = append(entries, frontier.Entry{LinkID: l.ID, URL: l.URL})
+		}
+	}
+	return &entryIterator{entries: entries}, nil
+}
+
+// memCheckpoints is an in-memory checkpoint.Store.
+type memCheckpoints struct {
+	mu     sync.Mutex
+	passes map[uuid.UUID]checkpoint.Checkpoint
+}
+
+func (m *memCheckpoints) LoadCheckpoint(fromID, toID uuid.UUID) (*checkpoint.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.passes {
+		if c.FromID == fromID && c.ToID == toID {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memCheckpoints) SaveCheckpoint(c *checkpoint.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.passes[c.PassID] = *c
+	return nil
+}
+
+func (m *memCheckpoints) ClearCheckpoint(passID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.passes, passID)
+	return nil
+}
+
+type memReports struct{ passes []report.Pass }
+
+func (m *memReports) SavePass(p *report.Pass) error {
+	m.passes = append(m.passes, *p)
+	return nil
+}
+
+// cancelIndex cancels the crawl after n documents were indexed.
+type cancelIndex struct {
+	mu     sync.Mutex
+	n      int
+	cancel context.CancelFunc
+}
+
+func (ci *cancelIndex) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if ci.n--; ci.n == 0 {
+		ci.cancel()
+	}
+	return uuid.Nil, nil
+}
+
+func (ci *cancelIndex) DeleteDocument(linkID uuid.UUID) error { return nil }
+
+func Test_checkpoint(t *testing.T) {
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/page/") {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		fetched[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>page</title></head></html>"))
+	}))
+	defer srv.Close()
+
+	graph := &scanGraph{retrieved: make(map[uuid.UUID]time.Time)}
+	for i := 0; i < 6; i++ {
+		graph.links = append(graph.links, &linkgraph.Link{ID: uuid.New(), URL: fmt.Sprintf("%s/page/%d", srv.URL, i)})
+	}
+	sort.Slice(graph.links, func(i, j int) bool {
+		return bytes.Compare(graph.links[i].ID[:], graph.links[j].ID[:]) < 0
+	})
+
+	store := &memCheckpoints{passes: make(map[uuid.UUID]checkpoint.Checkpoint)}
+	reports := &memReports{}
+	newService := func(idx DocIndexer) *CrawlService {
+		svc, err := NewWithConfig(Config{
+			GraphAPI:     graph,
+			IndexAPI:     idx,
+			Checkpoints:  store,
+			Reports:      reports,
+			HostMinDelay: 100 * time.Millisecond,
+			HostMaxConns: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svc
+	}
+
+	// the crawler is stopped after the third page
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := newService(&cancelIndex{n: 3, cancel: cancel}).startCrawl(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, _ := store.LoadCheckpoint(minUUID, maxUUID)
+	if cp == nil {
+		t.Fatal("interrupted pass has no checkpoint")
+	}
+	if cp.LastID != graph.links[2].ID || cp.Counts.Indexed != 3 {
+		t.Fatalf("\ngot:%v %v \nexpect:%v %v", cp.LastID, cp.Counts.Indexed, graph.links[2].ID, 3)
+	}
+	if len(reports.passes) != 0 {
+		t.Fatal("interrupted pass was reported as finished")
+	}
+
+	// after a restart the pass is resumed without waiting for the interval
+	if err := newService(fakeIndex{}).startCrawl(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fetched) != len(graph.links) {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", len(fetched), len(graph.links), "not every link was fetched")
+	}
+	for path, n := range fetched {
+		if n != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", n, 1, path+" fetched more than once")
+		}
+	}
+
+	if cp, _ := store.LoadCheckpoint(minUUID, maxUUID); cp != nil {
+		t.Fatal("checkpoint of the finished pass was not cleared")
+	}
+	if len(reports.passes) != 1 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(reports.passes), 1)
+	}
+	r := reports.passes[0]
+	if r.Fetched != 6 || r.Links != 6 || !r.StartedAt.Equal(cp.Counts.StartedAt) {
+		t.Fatalf("\ngot:%+v \nmessage:%v", r, "counts do not continue from the checkpoint")
+	}
+
+	t.Run("test_no_checkpoint", func(t *testing.T) {
+		if err := newService(fakeIndex{}).startCrawl(context.Background(), true); err != nil {
+			t.Fatal(err)
+		}
+		if len(reports.passes) != 1 {
+			t.Fatal("pass started without a checkpoint to resume")
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package linkcrawler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/extract"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/report"
+	"github.com/odit-bit/se/crawler/scope"
+	"github.com/odit-bit/se/crawler/submission"
+	"github.com/odit-bit/se/crawler/trap"
+	"github.com/odit-bit/se/crawler/warc"
+	"github.com/odit-bit/se/graph/canonical"
+	"github.com/odit-bit/se/pagerank/partition"
+	"go.uber.org/multierr"
+)
+
+// Config encapsulates the settings for configuring the crawler service.
+type Config struct {
+	// An API for iterating links and updating links and edges in the link
+	// graph.
+	GraphAPI GraphUpdater
+
+	// An API for indexing the content of crawled links.
+	IndexAPI DocIndexer
+
+	// An API for detecting the partition assignments for this service.
+	// Each instance only crawls the links whose ID falls into its own
+	// slice of the UUID space. If not specified, a single partition
+	// covering the full UUID space will be used instead.
+	PartitionDetector partition.Detector
+
+	// The time between subsequent crawler passes. If not specified, a
+	// default value of 1 minute will be used instead.
+	Interval time.Duration
+
+	// The time before a retrieved link is crawled again. With a Recrawl
+	// store this is only the initial interval of each link. If not
+	// specified, a default value of 7 days will be used instead.
+	RecrawlInterval time.Duration
+
+	// A store for the recrawl state of each link. If specified, the crawler
+	// sends conditional requests and adapts the recrawl interval of each
+	// link to how often its content changes. Otherwise every link is
+	// recrawled after RecrawlInterval.
+	Recrawl recrawl.Store
+
+	// A store the summary of every crawl pass is saved to. If not specified,
+	// the summary is only logged.
+	Reports report.Store
+
+	// A store the progress of every crawl pass is saved to. If specified, a
+	// pass that was interrupted, e.g. by a restart of the crawler, is resumed
+	// where it stopped instead of starting over after Interval. Passes that
+	// scan the graph resume after the last processed link, which requires
+	// the GraphAPI to iterate links in ID order.
+	Checkpoints checkpoint.Store
+
+	// How often the progress of a running pass is saved. If not specified,
+	// a default value of 10 seconds will be used instead.
+	CheckpointInterval time.Duration
+
+	// The bounds of the adaptive recrawl interval. If not specified, default
+	// values of 1 hour and 30 days will be used instead.
+	MinRecrawlInterval time.Duration
+	MaxRecrawlInterval time.Duration
+
+	// The user agent the crawler identifies itself with when evaluating
+	// robots.txt. If not specified, a default value of "se-crawler" will be
+	// used instead.
+	UserAgent string
+
+	// How long a fetched robots.txt file is cached per host. If not
+	// specified, a default value of 24 hours will be used instead.
+	RobotsTTL time.Duration
+
+	// How often the sitemaps of a host are read. Sitemaps are discovered
+	// from the Sitemap lines of robots.txt and the /sitemap.xml of the
+	// host. If not specified, a default value of 24 hours will be used
+	// instead.
+	SitemapInterval time.Duration
+
+	// The minimum time between two requests to the same host. A larger
+	// Crawl-delay in the host's robots.txt takes precedence. If not
+	// specified, a default value of 1 second will be used instead.
+	HostMinDelay time.Duration
+
+	// The maximum number of concurrent requests per host. If not
+	// specified, a default value of 2 will be used instead.
+	HostMaxConns int
+
+	// The number of links buffered ahead of the crawl pipeline so that
+	// links of other hosts can be crawled while one host is throttled. If
+	// not specified, a default value of 1000 will be used instead.
+	Lookahead int
+
+	// The policy deciding which links are crawled and which discovered
+	// links are added to the link graph, e.g. a *scope.Watcher. The depth
+	// of a link is only known when a Frontier is used, a policy with a
+	// maximum depth is rejected without one. If not specified,
+	// scope.Unrestricted will be used instead.
+	Scope ScopePolicy
+
+	// A persistent priority queue to dequeue links from. If not specified,
+	// every pass scans the graph for links that are due for a recrawl.
+	Frontier frontier.Store
+
+	// The number of links dequeued from the frontier per pass. If not
+	// specified, a default value of 500 will be used instead.
+	FrontierBatchSize int
+
+	// How long dequeued links are leased before they are handed out again
+	// if the pass did not complete them. If not specified, a default value
+	// of 1 hour will be used instead.
+	FrontierLease time.Duration
+
+	// The weights used to prioritize frontier links. If not specified,
+	// frontier.DefaultWeights will be used instead.
+	FrontierWeights frontier.Weights
+
+	// How often the frontier entries of the partition are scored again to
+	// pick up new PageRank scores and links added outside of the crawler.
+	// Entries are also scored when they are pushed or acknowledged. If not
+	// specified, a default value of 15 minutes will be used instead.
+	FrontierRefreshInterval time.Duration
+
+	// An API the PageRank scores of frontier links are read from when the
+	// frontier is refreshed. If not specified, every link has a score of 0.
+	Pageranks PagerankSource
+
+	// The maximum number of differing SimHash bits for two pages to be
+	// considered near-duplicates. A negative value disables the check. If
+	// not specified, a default value of 3 will be used instead.
+	DuplicateDistance int
+
+	// Only pages with the same SimHash fingerprint are considered
+	// duplicates, DuplicateDistance is ignored.
+	ExactDuplicates bool
+
+	// The number of consecutive failed fetches after which the document of
+	// a link is removed from the index. Documents of pages answering 410
+	// Gone are removed right away. Failures are only counted when the
+	// GraphAPI implements StatusRecorder. If not specified, a default value
+	// of 3 will be used instead.
+	MaxFailures int
+
+	// The extractors of the documents that are not HTML, by media type. Pages
+	// of other media types are added to the link graph but not indexed. If
+	// not specified, extract.Default() will be used instead.
+	Extractors *extract.Registry
+
+	// The archive the crawler's own requests and responses are written to,
+	// see Replay. If not specified, responses are not archived.
+	Archive *warc.Writer
+
+	// The query parameters removed from discovered URLs before they are
+	// added to the link graph. A trailing '*' matches any parameter with
+	// that prefix. If not specified, canonical.DefaultTrackingParams will be
+	// used instead.
+	TrackingParams []string
+
+	// The heuristics that keep crawler traps, such as calendars and session
+	// IDs in URLs, out of the link graph. Discovered links that look like a
+	// trap are not added and the hosts generating them are flagged, only a
+	// few new links of a flagged host are added per interval. If not
+	// specified, the defaults of trap.Config will be used instead.
+	Traps trap.Config
+
+	// A store flagged hosts are saved to, so that they stay flagged after a
+	// restart. If not specified, flagged hosts are only kept in memory.
+	TrapStore trap.Store
+
+	// A queue of links submitted by users. If specified, the queue is
+	// polled every SubmissionInterval and the submitted links are crawled
+	// right away, alongside a running pass and regardless of the partition
+	// they fall into. If not specified, submitted links wait for the next
+	// pass.
+	Submissions submission.Store
+
+	// How often the Submissions queue is polled. If not specified, a
+	// default value of 2 seconds will be used instead.
+	SubmissionInterval time.Duration
+
+	// A sink the crawler publishes an event to whenever a link is
+	// discovered, a page is fetched or fails to load and a document is
+	// indexed. If not specified, no events are published.
+	Events EventSink
+}
+
+// errDepthWithoutFrontier rejects a scope with a maximum depth for a crawler
+// that does not keep the depth of its links.
+var errDepthWithoutFrontier = errors.New("scope max depth requires a frontier")
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.GraphAPI == nil {
+		err = multierr.Append(err, fmt.Errorf("graph API has not been provided"))
+	}
+	if cfg.IndexAPI == nil {
+		err = multierr.Append(err, fmt.Errorf("index API has not been provided"))
+	}
+	if cfg.PartitionDetector == nil {
+		cfg.PartitionDetector = partition.Fixed{Partition: 0, NumPartitions: 1}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = default_interval
+	}
+	if cfg.RecrawlInterval <= 0 {
+		cfg.RecrawlInterval = default_recrawl_interval
+	}
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = default_checkpoint_interval
+	}
+	if cfg.MinRecrawlInterval <= 0 {
+		cfg.MinRecrawlInterval = default_min_recrawl_interval
+	}
+	if cfg.MaxRecrawlInterval <= 0 {
+		cfg.MaxRecrawlInterval = default_max_recrawl_interval
+	}
+	if cfg.MinRecrawlInterval > cfg.MaxRecrawlInterval {
+		err = multierr.Append(err, fmt.Errorf("min recrawl interval is larger than max recrawl interval"))
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = default_user_agent
+	}
+	if cfg.RobotsTTL <= 0 {
+		cfg.RobotsTTL = default_robots_ttl
+	}
+	if cfg.SitemapInterval <= 0 {
+		cfg.SitemapInterval = default_sitemap_interval
+	}
+	if cfg.HostMinDelay <= 0 {
+		cfg.HostMinDelay = default_host_min_delay
+	}
+	if cfg.HostMaxConns <= 0 {
+		cfg.HostMaxConns = default_host_max_conns
+	}
+	if cfg.Lookahead <= 0 {
+		cfg.Lookahead = default_lookahead
+	}
+	if cfg.Scope == nil {
+		cfg.Scope = scope.Unrestricted
+	}
+	if d, ok := cfg.Scope.(DepthLimiter); ok && d.MaxDepth() > 0 && cfg.Frontier == nil {
+		err = multierr.Append(err, errDepthWithoutFrontier)
+	}
+	if r, ok := cfg.Scope.(ReloadedScope); ok && cfg.Frontier == nil {
+		r.Require(func(s *scope.Scope) error {
+			if s.MaxDepth() > 0 {
+				return errDepthWithoutFrontier
+			}
+			return nil
+		})
+	}
+	if cfg.FrontierBatchSize <= 0 {
+		cfg.FrontierBatchSize = default_frontier_batch_size
+	}
+	if cfg.FrontierLease <= 0 {
+		cfg.FrontierLease = default_frontier_lease
+	}
+	if cfg.FrontierRefreshInterval <= 0 {
+		cfg.FrontierRefreshInterval = default_frontier_refresh_interval
+	}
+	if cfg.FrontierWeights == (frontier.Weights{}) {
+		cfg.FrontierWeights = frontier.DefaultWeights
+	}
+	if cfg.Extractors == nil {
+		cfg.Extractors = extract.Default()
+	}
+	if len(cfg.TrackingParams) == 0 {
+		cfg.TrackingParams = canonical.DefaultTrackingParams
+	}
+	if cfg.ExactDuplicates {
+		cfg.DuplicateDistance = 0
+	} else if cfg.DuplicateDistance == 0 {
+		cfg.DuplicateDistance = default_duplicate_distance
+	}
+	if cfg.SubmissionInterval <= 0 {
+		cfg.SubmissionInterval = default_submission_interval
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = default_max_failures
+	}
+	return err
+}
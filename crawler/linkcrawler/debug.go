@@ -0,0 +1,307 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+	"github.com/odit-bit/se/index/indexapi"
+	"github.com/odit-bit/webcrawler"
+)
+
+// DebugResult describes what the crawler makes of a single URL, see Debug.
+type DebugResult struct {
+	// the canonical form of the requested URL
+	URL string
+
+	InScope       bool
+	RobotsAllowed bool
+
+	// the response to the crawler's own request, the status is zero if the
+	// page was not requested or the request failed
+	Status    int
+	FinalURL  string
+	MediaType string
+	FetchErr  error
+
+	// the robots directives of the page
+	NoIndex  bool
+	NoFollow bool
+
+	// the title and the length of the text extracted from the page
+	Title         string
+	ContentLength int
+
+	// the links, edges and documents the consumer writes for the page. Edges
+	// are pairs of source and destination URL.
+	Links     []string
+	Edges     [][2]string
+	Documents []*indexapi.Document
+
+	// the links and documents were written to the graph and the index
+	Committed bool
+}
+
+// Outlinks returns the canonical, in scope URLs the page links to in the
+// graph.
+func (r *DebugResult) Outlinks() []string {
+	page := r.URL
+	if r.FinalURL != "" {
+		for _, e := range r.Edges {
+			// the page is recorded under the URL it redirected to
+			if e[0] == r.URL {
+				page = e[1]
+			}
+		}
+	}
+	var dsts []string
+	for _, e := range r.Edges {
+		if e[0] == page {
+			dsts = append(dsts, e[1])
+		}
+	}
+	return dsts
+}
+
+// Debug runs a single URL through the crawler and reports the robots
+// and scope decisions, the extracted text and the links, edges and documents
+// that would be written for the page. Nothing is written unless commit is
+// true, the page is then written to the graph and the index as in a crawl
+// pass.
+func (la *CrawlService) Debug(ctx context.Context, rawURL string, commit bool) (*DebugResult, error) {
+	target, err := la.canon.URL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	res := &DebugResult{URL: target}
+
+	res.InScope = la.cfg.Scope.Allowed(target, 0)
+	rules, err := la.robots.Lookup(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("robots: %v", err)
+	}
+	res.RobotsAllowed = rules.Allowed(la.cfg.UserAgent, u.RequestURI())
+	if !res.InScope || !res.RobotsAllowed {
+		return res, nil
+	}
+
+	p, err := la.pages.get(ctx, target, recrawl.State{}, false)
+	res.Status, res.FinalURL, res.MediaType, res.FetchErr = p.status, p.finalURL, p.mediaType, err
+	res.NoIndex, res.NoFollow = p.directives.NoIndex, p.directives.NoFollow
+	if res.FetchErr == nil {
+		res.FetchErr = p.extractErr
+	}
+	if linkstatus.Failed(p.status, err) {
+		return res, nil
+	}
+
+	if p.doc != nil {
+		res.Title, res.ContentLength = p.doc.Title, len(p.doc.Content)
+	}
+
+	// the consumer of a crawl pass, its writes go through the recorder
+	consumer := la.newConsumer(nil)
+	rec := newDebugRecorder(consumer, commit)
+	if !commit {
+		// a dry run leaves no trace, not even in the trap detector or the
+		// metrics
+		consumer.stats = &passStats{dry: true}
+		consumer.traps = la.traps.Clone()
+		consumer.trapStore = nil
+		consumer.events = nil
+	}
+
+	link := &linkgraph.Link{URL: target, RetrievedAt: time.Now()}
+	if err := rec.UpsertLink(link); err != nil {
+		return nil, err
+	}
+	r := webcrawler.NewResource()
+	r.ID, r.URL = link.ID, link.URL
+	defer r.Put()
+
+	if err := consumer.upsertResource(r, p); err != nil {
+		return nil, err
+	}
+	res.Links, res.Edges, res.Documents = rec.links, rec.edges, rec.docs
+	res.Committed = commit
+	return res, nil
+}
+
+// debugRecorder records the writes of a consumer. The writes of a committed
+// run are passed on to the graph and the index the consumer was created with,
+// a dry run only records them.
+type debugRecorder struct {
+	commit bool
+
+	// the graph and the index of the consumer, the optional capabilities
+	// are nil if the graph does not implement them
+	graph   GraphUpdater
+	index   DocIndexer
+	batch   BatchGraph
+	anchors AnchorGraph
+	aliases AliasGraph
+
+	mu   sync.Mutex
+	ids  map[string]uuid.UUID
+	urls map[uuid.UUID]string
+
+	links []string
+	edges [][2]string
+	docs  []*indexapi.Document
+}
+
+// newDebugRecorder puts a recorder between ld and its graph and index, ld
+// keeps the optional capabilities of its graph.
+func newDebugRecorder(ld *linkConsumer, commit bool) *debugRecorder {
+	dr := &debugRecorder{
+		commit:  commit,
+		graph:   ld.GraphUpdater,
+		index:   ld.DocIndexer,
+		batch:   ld.batch,
+		anchors: ld.anchors,
+		aliases: ld.aliases,
+		ids:     make(map[string]uuid.UUID),
+		urls:    make(map[uuid.UUID]string),
+	}
+	ld.GraphUpdater, ld.DocIndexer = dr, dr
+	if ld.batch != nil {
+		ld.batch = dr
+	}
+	if ld.anchors != nil {
+		ld.anchors = dr
+	}
+	if ld.aliases != nil {
+		ld.aliases = dr
+	}
+	return dr
+}
+
+// record records link and sets its ID unless the graph did. It reports
+// whether the link was not recorded before.
+func (dr *debugRecorder) record(link *linkgraph.Link) bool {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if id, ok := dr.ids[link.URL]; ok {
+		if link.ID == uuid.Nil {
+			link.ID = id
+		}
+		return false
+	}
+	if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+	dr.ids[link.URL] = link.ID
+	dr.urls[link.ID] = link.URL
+	dr.links = append(dr.links, link.URL)
+	return true
+}
+
+func (dr *debugRecorder) recordEdge(edge *linkgraph.Edge) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.edges = append(dr.edges, [2]string{dr.urls[edge.Src], dr.urls[edge.Dst]})
+}
+
+func (dr *debugRecorder) UpsertLink(link *linkgraph.Link) error {
+	if dr.commit {
+		if err := dr.graph.UpsertLink(link); err != nil {
+			return err
+		}
+	}
+	dr.record(link)
+	return nil
+}
+
+func (dr *debugRecorder) UpsertEdge(edge *linkgraph.Edge) error {
+	if dr.commit {
+		if err := dr.graph.UpsertEdge(edge); err != nil {
+			return err
+		}
+	}
+	dr.recordEdge(edge)
+	return nil
+}
+
+func (dr *debugRecorder) Links(fromID, toID uuid.UUID, retrieveBefore time.Time) (linkgraph.LinkIterator, error) {
+	return nil, fmt.Errorf("debug recorder: links are not iterated")
+}
+
+func (dr *debugRecorder) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	if dr.commit {
+		return dr.graph.RemoveStaleEdges(fromID, updatedBefore)
+	}
+	return nil
+}
+
+// UpsertOutlinks implements BatchGraph, a dry run reports the links that were
+// not recorded before as new.
+func (dr *debugRecorder) UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error {
+	if dr.commit {
+		if err := dr.batch.UpsertOutlinks(src, links); err != nil {
+			return err
+		}
+	}
+	dr.record(src)
+	for _, l := range links {
+		isNew := dr.record(&l.Link)
+		if !dr.commit {
+			l.New = isNew
+		}
+		dr.recordEdge(&linkgraph.Edge{Src: src.ID, Dst: l.ID})
+	}
+	return nil
+}
+
+func (dr *debugRecorder) UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error {
+	if dr.commit {
+		if err := dr.anchors.UpsertAnchoredEdge(edge, text, rel); err != nil {
+			return err
+		}
+	}
+	dr.recordEdge(edge)
+	return nil
+}
+
+// AnchorText implements AnchorGraph, the anchor text is read from the graph
+// by a dry run as well.
+func (dr *debugRecorder) AnchorText(dst uuid.UUID) (string, error) {
+	return dr.anchors.AnchorText(dst)
+}
+
+func (dr *debugRecorder) UpsertAlias(edge *linkgraph.Edge) error {
+	if dr.commit {
+		if err := dr.aliases.UpsertAlias(edge); err != nil {
+			return err
+		}
+	}
+	dr.recordEdge(edge)
+	return nil
+}
+
+func (dr *debugRecorder) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	dr.mu.Lock()
+	dr.docs = append(dr.docs, doc)
+	dr.mu.Unlock()
+	if dr.commit {
+		return dr.index.IndexDocument(doc, maxDistance)
+	}
+	return uuid.Nil, nil
+}
+
+func (dr *debugRecorder) DeleteDocument(linkID uuid.UUID) error {
+	if dr.commit {
+		return dr.index.DeleteDocument(linkID)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func Test_debug(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/notes.md":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("# Notes\n\nsee [home](/), [home again](/#top) and [a loop](/a/b/a/b/a/b)\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	graph := newRecordGraph()
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test_disallowed", func(t *testing.T) {
+		res, err := svc.Debug(context.Background(), srv.URL+"/private", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RobotsAllowed || res.Status != 0 || len(res.Links) != 0 {
+			t.Fatalf("\ngot:%+v \nmessage:%v", res, "disallowed page was fetched")
+		}
+	})
+
+	t.Run("test_dry_run", func(t *testing.T) {
+		indexed := counterValue(t, indexedPages)
+		res, err := svc.Debug(context.Background(), srv.URL+"/notes.md", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := counterValue(t, indexedPages); got != indexed {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, indexed, "dry run updated the metrics")
+		}
+		if res.Status != http.StatusOK || res.MediaType != "text/markdown" || res.Title != "Notes" {
+			t.Fatalf("\ngot:%v %v %v", res.Status, res.MediaType, res.Title)
+		}
+		if got := res.Outlinks(); len(got) != 1 || got[0] != srv.URL+"/" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, srv.URL+"/")
+		}
+		if len(res.Documents) != 1 || res.Documents[0].URL != srv.URL+"/notes.md" {
+			t.Fatalf("\ngot:%v", res.Documents)
+		}
+		if len(graph.ids) != 0 || len(idx.docs) != 0 || res.Committed {
+			t.Fatalf("\ngot:%v %v \nmessage:%v", graph.ids, idx.docs, "dry run wrote to the graph or the index")
+		}
+	})
+
+	t.Run("test_commit", func(t *testing.T) {
+		res, err := svc.Debug(context.Background(), srv.URL+"/notes.md", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		notes := graph.ids[srv.URL+"/notes.md"]
+		if !res.Committed || idx.docs[notes] == nil || idx.docs[notes].MediaType != "text/markdown" {
+			t.Fatalf("\ngot:%v %v", res.Committed, idx.docs)
+		}
+		// the page is written once and reported as written
+		if idx.n != 1 || len(res.Documents) != 1 {
+			t.Fatalf("\ngot:%v %v \nexpect:%v", idx.n, len(res.Documents), 1)
+		}
+		if got := res.Outlinks(); len(got) != 1 || got[0] != srv.URL+"/" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, srv.URL+"/")
+		}
+		if got := graph.outlinks(notes); len(got) != 1 || got[0] != srv.URL+"/" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, srv.URL+"/")
+		}
+	})
+}
+
+// counterValue returns the current value of c.
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
@@ -0,0 +1,28 @@
+package linkcrawler
+
+import "testing"
+
+func Test_duplicate_distance(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		expect int
+	}{
+		{"test_default", Config{}, default_duplicate_distance},
+		{"test_distance", Config{DuplicateDistance: 5}, 5},
+		{"test_disabled", Config{DuplicateDistance: -1}, -1},
+		{"test_exact", Config{DuplicateDistance: 5, ExactDuplicates: true}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.GraphAPI, tt.cfg.IndexAPI = &fakeGraph{}, fakeIndex{}
+			svc, err := NewWithConfig(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := svc.newConsumer(nil).maxDistance; got != tt.expect {
+				t.Fatalf("\ngot:%v \nexpect:%v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package linkcrawler
+
+import (
+	"log"
+	"time"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/event"
+)
+
+// publish hands e to sink, a nil sink drops the event. An event that can not
+// be published is logged, it does not fail the crawl.
+func publish(sink EventSink, e event.Event) {
+	if sink == nil {
+		return
+	}
+	if err := sink.Publish(e); err != nil {
+		eventPublishFailures.Inc()
+		log.Println("crawl events:", err)
+	}
+}
+
+// fetchEvent returns the event of the request of the page of l that took d,
+// classified like the fetch failure metrics.
+func fetchEvent(l *linkgraph.Link, p *page, err error, d time.Duration) event.Event {
+	link := event.Link{ID: l.ID, URL: l.URL, At: time.Now()}
+	if class, _, failed := failureOf(p, err); failed {
+		e := &event.FetchFailed{Link: link, Class: class, Status: p.status}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		return e
+	}
+	return &event.PageFetched{
+		Link:        link,
+		Status:      p.status,
+		FinalURL:    p.finalURL,
+		MediaType:   p.mediaType,
+		Bytes:       p.bytes,
+		NotModified: p.notModified,
+		Duration:    d,
+	}
+}
@@ -0,0 +1,88 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/event"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+func Test_events(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body><a href="/a">a</a></body></html>`))
+	}))
+	defer srv.Close()
+
+	sink := event.NewChannel(10)
+	graph := &batchGraph{recordGraph: newRecordGraph()}
+	svc, err := NewWithConfig(Config{
+		GraphAPI: graph,
+		IndexAPI: newRecordIndex(),
+		Events:   sink,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := &linkgraph.Link{URL: srv.URL + "/"}
+	if err := graph.UpsertLink(link); err != nil {
+		t.Fatal(err)
+	}
+	p, err := svc.pages.get(context.Background(), link.URL, recrawl.State{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := webcrawler.NewResource()
+	r.ID = link.ID
+	r.URL = link.URL
+	if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+		t.Fatal(err)
+	}
+	publish(sink, fetchEvent(link, p, nil, 0))
+
+	gone := &linkgraph.Link{ID: uuid.New(), URL: srv.URL + "/gone"}
+	p, err = svc.pages.get(context.Background(), gone.URL, recrawl.State{}, false)
+	publish(sink, fetchEvent(gone, p, err, 0))
+
+	var got []string
+	for len(sink.Events()) > 0 {
+		e := <-sink.Events()
+		switch e := e.(type) {
+		case *event.LinkDiscovered:
+			got = append(got, fmt.Sprintf("%s %s from %v", e.Type(), e.URL, e.SourceID == link.ID))
+		case *event.DocumentIndexed:
+			got = append(got, fmt.Sprintf("%s %s", e.Type(), e.URL))
+		case *event.PageFetched:
+			got = append(got, fmt.Sprintf("%s %d %s", e.Type(), e.Status, e.MediaType))
+		case *event.FetchFailed:
+			got = append(got, fmt.Sprintf("%s %s %d", e.Type(), e.Class, e.Status))
+		}
+	}
+	if len(got) != 4 {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, 4)
+	}
+	// the links and the document of a page are written concurrently
+	sort.Strings(got[:2])
+	expect := []string{
+		"document_indexed " + srv.URL + "/",
+		"link_discovered " + srv.URL + "/a from true",
+		"page_fetched 200 text/html",
+		"fetch_failed http 410",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+}
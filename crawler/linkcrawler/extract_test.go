@@ -0,0 +1,76 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+func Test_extract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/README.md":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("# Setup guide\n\nSee the [install notes](docs/install.md).\n"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		}
+	}))
+	defer srv.Close()
+
+	graph := newRecordGraph()
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crawl := func(t *testing.T, path string) *linkgraph.Link {
+		link := &linkgraph.Link{URL: srv.URL + path}
+		if err := graph.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		p, err := svc.pages.get(context.Background(), link.URL, recrawl.State{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := webcrawler.NewResource()
+		r.ID = link.ID
+		r.URL = link.URL
+		if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+
+	t.Run("test_markdown", func(t *testing.T) {
+		link := crawl(t, "/README.md")
+		doc, ok := idx.docs[link.ID]
+		if !ok {
+			t.Fatal("markdown document not indexed")
+		}
+		if doc.Title != "Setup guide" || doc.Content != "Setup guide See the install notes." {
+			t.Fatalf("\ngot:%q %q", doc.Title, doc.Content)
+		}
+		if doc.MediaType != "text/markdown" {
+			t.Fatalf("\ngot:%v \nexpect:%v", doc.MediaType, "text/markdown")
+		}
+		expect := srv.URL + "/docs/install.md"
+		if got := graph.outlinks(link.ID); len(got) != 1 || got[0] != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	})
+
+	t.Run("test_unsupported", func(t *testing.T) {
+		link := crawl(t, "/logo.png")
+		if _, ok := idx.docs[link.ID]; ok {
+			t.Fatal("document without extractor was indexed")
+		}
+	})
+}
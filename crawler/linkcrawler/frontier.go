@@ -0,0 +1,235 @@
+package linkcrawler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/frontier"
+)
+
+// the number of links handed to the frontier per Refresh call
+var refresh_batch_size = 1000
+
+// frontierPass tracks the frontier entries leased for a single crawler pass.
+// A nil *frontierPass is valid and does nothing, it is used when the service
+// runs without a frontier and scans the graph instead.
+type frontierPass struct {
+	store   frontier.Store
+	recrawl time.Duration
+	scoring frontier.Scoring
+
+	// the lease of the batch, zero if it is empty
+	leasedUntil time.Time
+
+	mu    sync.Mutex
+	depth map[uuid.UUID]int
+}
+
+// dequeue leases the next batch of links in the [fromID, toID) range. The
+// priorities of the range are refreshed first if the refresh interval has
+// passed since the last refresh.
+func (la *CrawlService) dequeue(fromID, toID uuid.UUID) (*frontierPass, linkgraph.LinkIterator, error) {
+	store := la.cfg.Frontier
+	sc := frontier.Scoring{Weights: la.cfg.FrontierWeights, RecrawlInterval: la.cfg.RecrawlInterval}
+	la.refreshFrontier(fromID, toID, sc)
+
+	entries, err := store.Dequeue(fromID, toID, la.cfg.FrontierBatchSize, la.cfg.FrontierLease)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pass := &frontierPass{
+		store:   store,
+		recrawl: la.cfg.RecrawlInterval,
+		scoring: sc,
+		depth:   make(map[uuid.UUID]int, len(entries)),
+	}
+	for _, e := range entries {
+		pass.depth[e.LinkID] = e.Depth
+		pass.leasedUntil = e.LeasedUntil
+	}
+
+	return pass, &entryIterator{entries: entries}, nil
+}
+
+// submittedPass returns the pass the links submitted outside of a crawler
+// pass are pushed to the frontier through, nil without a frontier.
+func (la *CrawlService) submittedPass() *frontierPass {
+	if la.cfg.Frontier == nil {
+		return nil
+	}
+	return &frontierPass{
+		store:   la.cfg.Frontier,
+		recrawl: la.cfg.RecrawlInterval,
+		scoring: frontier.Scoring{Weights: la.cfg.FrontierWeights, RecrawlInterval: la.cfg.RecrawlInterval},
+	}
+}
+
+// refreshFrontier refreshes the frontier entries of the links of the graph in
+// the [fromID, toID) range once every FrontierRefreshInterval, or right away
+// if the range changed.
+func (la *CrawlService) refreshFrontier(fromID, toID uuid.UUID, sc frontier.Scoring) {
+	r := [2]uuid.UUID{fromID, toID}
+	if r == la.refreshedRange && time.Since(la.refreshedAt) < la.cfg.FrontierRefreshInterval {
+		return
+	}
+	if err := la.refreshLinks(fromID, toID, sc); err != nil {
+		// stale priorities are better than no crawl at all
+		log.Println("frontier:", err)
+		return
+	}
+	la.refreshedRange, la.refreshedAt = r, time.Now()
+}
+
+// refreshLinks hands the links in the [fromID, toID) range to the frontier in
+// batches, along with their PageRank scores.
+func (la *CrawlService) refreshLinks(fromID, toID uuid.UUID, sc frontier.Scoring) error {
+	it, err := la.cfg.GraphAPI.Links(fromID, toID, time.Now())
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	batch := make([]frontier.Link, 0, refresh_batch_size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := la.rank(batch); err != nil {
+			return err
+		}
+		err := la.cfg.Frontier.Refresh(batch, sc)
+		batch = batch[:0]
+		return err
+	}
+	for it.Next() {
+		l := it.Link()
+		batch = append(batch, frontier.Link{ID: l.ID, URL: l.URL, RetrievedAt: l.RetrievedAt})
+		if len(batch) == refresh_batch_size {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// rank sets the PageRank scores of links.
+func (la *CrawlService) rank(links []frontier.Link) error {
+	if la.cfg.Pageranks == nil {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(links))
+	for i, l := range links {
+		ids[i] = l.ID
+	}
+	ranks, err := la.cfg.Pageranks.Pageranks(ids)
+	if err != nil {
+		return err
+	}
+	for i := range links {
+		links[i].Rank = ranks[links[i].ID]
+	}
+	return nil
+}
+
+// release hands out the unacknowledged links of the frontier batch of an
+// interrupted pass again, before its lease expires.
+func (la *CrawlService) release(cp *checkpoint.Checkpoint) {
+	releaser, ok := la.cfg.Frontier.(frontier.Releaser)
+	if !ok || cp.LeasedUntil.IsZero() {
+		return
+	}
+	if err := releaser.Release(cp.FromID, cp.ToID, cp.LeasedUntil); err != nil {
+		log.Println("frontier:", err)
+	}
+}
+
+// depthOf returns the depth of a link leased in this pass.
+func (fp *frontierPass) depthOf(linkID uuid.UUID) int {
+	if fp == nil {
+		return 0
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return fp.depth[linkID]
+}
+
+// ack reschedules a link after the fixed recrawl interval.
+func (fp *frontierPass) ack(linkID uuid.UUID) {
+	if fp == nil {
+		return
+	}
+	fp.ackAt(linkID, time.Now().Add(fp.recrawl))
+}
+
+// ackAt reschedules a crawled link at due.
+func (fp *frontierPass) ackAt(linkID uuid.UUID, due time.Time) {
+	if fp == nil {
+		return
+	}
+	if err := fp.store.Ack(linkID, due, fp.scoring); err != nil {
+		log.Println("frontier:", err)
+	}
+}
+
+// discovered adds the links found on src to the frontier one hop deeper.
+func (fp *frontierPass) discovered(src uuid.UUID, links []*linkgraph.Link) {
+	if fp == nil || len(links) == 0 {
+		return
+	}
+
+	depth := fp.depthOf(src) + 1
+	entries := make([]frontier.Entry, 0, len(links))
+	for _, l := range links {
+		entries = append(entries, frontier.Entry{LinkID: l.ID, URL: l.URL, Depth: depth})
+	}
+	if err := fp.store.Push(entries, fp.scoring); err != nil {
+		log.Println("frontier:", err)
+	}
+}
+
+// push adds links to the frontier as they are.
+func (fp *frontierPass) push(entries []frontier.Entry) {
+	if fp == nil || len(entries) == 0 {
+		return
+	}
+	if err := fp.store.Push(entries, fp.scoring); err != nil {
+		log.Println("frontier:", err)
+	}
+}
+
+var _ linkgraph.LinkIterator = (*entryIterator)(nil)
+
+// entryIterator iterates the links of a dequeued frontier batch.
+type entryIterator struct {
+	entries []frontier.Entry
+	link    *linkgraph.Link
+}
+
+// Next implements linkgraph.LinkIterator.
+func (it *entryIterator) Next() bool {
+	if len(it.entries) == 0 {
+		return false
+	}
+	e := it.entries[0]
+	it.entries = it.entries[1:]
+	it.link = &linkgraph.Link{ID: e.LinkID, URL: e.URL}
+	return true
+}
+
+// Link implements linkgraph.LinkIterator.
+func (it *entryIterator) Link() *linkgraph.Link { return it.link }
+
+// Error implements linkgraph.LinkIterator.
+func (it *entryIterator) Error() error { return nil }
+
+// Close implements linkgraph.LinkIterator.
+func (it *entryIterator) Close() error { return nil }
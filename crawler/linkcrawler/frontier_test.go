@@ -0,0 +1,93 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/frontier"
+)
+
+// refreshFrontier records the links handed to Refresh and the entries handed
+// to Push.
+type refreshFrontier struct {
+	mu      sync.Mutex
+	batches [][]frontier.Link
+	pushed  []frontier.Entry
+}
+
+func (f *refreshFrontier) Refresh(links []frontier.Link, s frontier.Scoring) error {
+	f.batches = append(f.batches, append([]frontier.Link(nil), links...))
+	return nil
+}
+
+func (f *refreshFrontier) Dequeue(fromID, toID uuid.UUID, n int, lease time.Duration) ([]frontier.Entry, error) {
+	return nil, nil
+}
+
+func (f *refreshFrontier) Push(entries []frontier.Entry, s frontier.Scoring) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pushed = append(f.pushed, entries...)
+	return nil
+}
+
+func (f *refreshFrontier) Ack(linkID uuid.UUID, nextDue time.Time, s frontier.Scoring) error {
+	return nil
+}
+
+// fixedRanks is a PagerankSource of fixed scores.
+type fixedRanks map[uuid.UUID]float64
+
+func (r fixedRanks) Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	ranks := make(map[uuid.UUID]float64)
+	for _, id := range linkIDs {
+		if rank, ok := r[id]; ok {
+			ranks[id] = rank
+		}
+	}
+	return ranks, nil
+}
+
+func Test_frontier_refresh(t *testing.T) {
+	graph := &fakeGraph{}
+	for i := 0; i < 2*refresh_batch_size+1; i++ {
+		graph.links = append(graph.links, &linkgraph.Link{
+			ID:  uuid.New(),
+			URL: fmt.Sprintf("https://example.com/%d", i),
+		})
+	}
+	ranked := graph.links[refresh_batch_size+1].ID
+
+	store := &refreshFrontier{}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:  graph,
+		IndexAPI:  fakeIndex{},
+		Frontier:  store,
+		Pageranks: fixedRanks{ranked: 0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.refreshFrontier(minUUID, maxUUID, frontier.Scoring{Weights: frontier.DefaultWeights})
+
+	if len(store.batches) != 3 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(store.batches), 3)
+	}
+	seen := make(map[uuid.UUID]float64)
+	for _, batch := range store.batches {
+		for _, l := range batch {
+			seen[l.ID] = l.Rank
+		}
+	}
+	if len(seen) != len(graph.links) {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", len(seen), len(graph.links), "every link of the graph is refreshed")
+	}
+	if seen[ranked] != 0.5 {
+		t.Fatalf("\ngot:%v \nexpect:%v", seen[ranked], 0.5)
+	}
+}
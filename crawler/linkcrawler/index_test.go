@@ -0,0 +1,39 @@
+package linkcrawler
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/index/indexapi"
+)
+
+// recordIndex records the indexed documents by link ID, the number of
+// IndexDocument calls and the removed documents.
+type recordIndex struct {
+	mu      sync.Mutex
+	n       int
+	docs    map[uuid.UUID]*indexapi.Document
+	removed map[uuid.UUID]bool
+}
+
+func newRecordIndex() *recordIndex {
+	return &recordIndex{
+		docs:    make(map[uuid.UUID]*indexapi.Document),
+		removed: make(map[uuid.UUID]bool),
+	}
+}
+
+func (ri *recordIndex) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.n++
+	ri.docs[doc.LinkID] = doc
+	return uuid.Nil, nil
+}
+
+func (ri *recordIndex) DeleteDocument(linkID uuid.UUID) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.removed[linkID] = true
+	return nil
+}
@@ -4,12 +4,30 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/odit-bit/indexstore/index"
 	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/event"
+	"github.com/odit-bit/se/crawler/scope"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+	"github.com/odit-bit/se/index/indexapi"
 )
 
+// DocIndexer writes the documents of crawled pages, e.g. an indexapi.Client.
 type DocIndexer interface {
-	Index(doc *index.Document) error
+	// IndexDocument indexes doc unless it is a near-duplicate of another
+	// indexed document within maxDistance bits, it then returns the ID of
+	// that document.
+	IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error)
+
+	// DeleteDocument removes the document of linkID, e.g. of a dead page.
+	DeleteDocument(linkID uuid.UUID) error
+}
+
+// PagerankSource reads the PageRank scores of links, e.g. an indexapi.Client.
+type PagerankSource interface {
+	// Pageranks returns the PageRank scores of the links relative to the
+	// best ranked link. Links without a score are left out.
+	Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error)
 }
 
 type GraphUpdater interface {
@@ -31,3 +49,78 @@ type LinkFetcher interface {
 	//return link iterator to iterate link in graph
 	linkgraph.LinkIterator
 }
+
+// ScopePolicy decides which URLs are part of the crawl.
+type ScopePolicy interface {
+	// Allowed reports whether rawURL, found depth hops from its seed
+	// link, is in scope.
+	Allowed(rawURL string, depth int) bool
+}
+
+// DepthLimiter is implemented by scope policies that limit the number of hops
+// from the seed link. The depth of a link is only kept by the frontier, a
+// crawler without one rejects a policy with a maximum depth.
+type DepthLimiter interface {
+	// MaxDepth returns the maximum depth, zero if it is unlimited.
+	MaxDepth() int
+}
+
+// ReloadedScope is implemented by scope policies whose rules are reloaded while
+// the crawler runs, e.g. a *scope.Watcher. A crawler without a frontier makes
+// it reject rules with a maximum depth, like it rejects them at the start.
+type ReloadedScope interface {
+	// Require sets a check every reloaded set of rules must pass, rules
+	// that fail it keep the previous ones in place.
+	Require(check func(*scope.Scope) error)
+}
+
+// AnchorGraph is implemented by graphs that keep the anchor text and rel
+// attribute of the link an edge was found in. When the GraphAPI implements
+// it, the anchors of a crawled page are stored with its outgoing edges and
+// the anchor text of the links pointing to a page is indexed along with it.
+type AnchorGraph interface {
+	// UpsertAnchoredEdge is like UpsertEdge, it also stores the anchor text
+	// and rel attribute of the link.
+	UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error
+
+	// AnchorText returns the aggregated anchor text of the links pointing
+	// to dst.
+	AnchorText(dst uuid.UUID) (string, error)
+}
+
+// BatchGraph is implemented by graphs that write the links of a crawled page
+// in a single transaction. When the GraphAPI implements it, the page, the
+// links found on it and its outgoing edges are written with one call instead
+// of a few calls per found URL. Only such a graph tells the links that are new
+// to it, discovered links are not counted or published for other graphs.
+type BatchGraph interface {
+	// UpsertOutlinks upserts src and links, sets the ID of each like
+	// UpsertLink and replaces the outgoing edges of src with edges to the
+	// links and their anchors. New is set for the links that were not in
+	// the graph before.
+	UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error
+}
+
+// AliasGraph is implemented by graphs that tell alias edges from links. When
+// the GraphAPI implements it, a redirect is recorded as an alias edge from
+// the requested URL to the final URL, otherwise as a plain edge.
+type AliasGraph interface {
+	// UpsertAlias records that edge.Src redirects to edge.Dst.
+	UpsertAlias(edge *linkgraph.Edge) error
+}
+
+// StatusRecorder is implemented by graphs that keep the fetch status of
+// links. When the GraphAPI implements it, the outcome of every fetch is
+// recorded and consecutive failures are counted.
+type StatusRecorder interface {
+	// RecordFetch updates the status of a link with the outcome of a fetch
+	// that ended with the HTTP status code and fetchErr.
+	RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error)
+}
+
+// EventSink is implemented by the receivers of crawl events, such as the sinks
+// of the event package. Publish is called synchronously by the crawler, an
+// error is logged and does not stop the crawl.
+type EventSink interface {
+	Publish(e event.Event) error
+}
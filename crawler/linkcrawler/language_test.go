@@ -0,0 +1,38 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/indexstore/index"
+	"github.com/odit-bit/se/index/indexapi"
+)
+
+func Test_document_language(t *testing.T) {
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer := svc.newConsumer(nil)
+
+	docs := []*indexapi.Document{
+		{Document: index.Document{LinkID: uuid.New(), Title: "Resep", Content: "Kumpulan resep makanan khas daerah yang mudah dibuat di rumah bersama keluarga."}},
+		{Document: index.Document{LinkID: uuid.New(), Title: "Recipes", Content: "A collection of regional recipes that are easy to make at home with the whole family."}},
+		{Document: index.Document{LinkID: uuid.New(), Title: "404"}},
+	}
+	for _, doc := range docs {
+		if err := consumer.indexDocument(doc, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := fmt.Sprintf("%v %v %v", idx.docs[docs[0].LinkID].Language, idx.docs[docs[1].LinkID].Language, idx.docs[docs[2].LinkID].Language == "")
+	if expect := "id en true"; got != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+	if idx.n != len(docs) {
+		t.Fatalf("\ngot:%v \nexpect:%v", idx.n, len(docs))
+	}
+}
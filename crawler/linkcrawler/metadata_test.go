@@ -0,0 +1,55 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+func Test_metadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<meta name="description" content="All about crawling">
+			<meta property="article:published_time" content="2023-11-02">
+			<link rel="icon" href="../static/favicon.ico">
+		</head><body><h1>Crawling</h1></body></html>`))
+	}))
+	defer srv.Close()
+
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := &linkgraph.Link{ID: uuid.New(), URL: srv.URL + "/blog/post"}
+	p, err := svc.pages.get(context.Background(), link.URL, recrawl.State{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := webcrawler.NewResource()
+	r.ID = link.ID
+	r.URL = link.URL
+	if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := idx.docs[link.ID]
+	if doc == nil {
+		t.Fatal("metadata not stored")
+	}
+	meta := &doc.Meta
+	if meta.Description != "All about crawling" || len(meta.Headings) != 1 || meta.Published.IsZero() {
+		t.Fatalf("\ngot:%+v", meta)
+	}
+	if expect := srv.URL + "/static/favicon.ico"; meta.Favicon != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", meta.Favicon, expect, "favicon not resolved against the page")
+	}
+}
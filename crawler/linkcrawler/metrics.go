@@ -0,0 +1,177 @@
+package linkcrawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/odit-bit/se/crawler/politeness"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchedPages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "fetched_total",
+		Help:      "Number of page requests that were answered.",
+	})
+
+	fetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "fetch_failures_total",
+		Help:      "Number of failed page requests by error class and HTTP status.",
+	}, []string{"class", "status"})
+
+	indexedPages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "indexed_total",
+		Help:      "Number of pages indexed.",
+	})
+
+	discoveredLinks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "discovered_links_total",
+		Help:      "Number of links found on crawled pages that were not in the link graph before.",
+	})
+
+	removedDocuments = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "removed_documents_total",
+		Help:      "Number of documents removed from the index because their page is dead.",
+	})
+
+	downloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "downloaded_bytes_total",
+		Help:      "Number of response body bytes read by the crawler.",
+	})
+
+	eventPublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Name:      "event_publish_failures_total",
+		Help:      "Number of crawl events that could not be published.",
+	})
+
+	fetchSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "crawler",
+		Subsystem: "host",
+		Name:      "fetch_duration_seconds",
+		Help:      "Time taken to request a page of a host.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"host"})
+)
+
+// passStats counts the outcome of a crawl pass. Every count is also added to
+// the metrics of the crawler, a nil passStats only updates the metrics and a
+// dry one updates neither.
+type passStats struct {
+	fetched    atomic.Int64
+	failed     atomic.Int64
+	indexed    atomic.Int64
+	discovered atomic.Int64
+	bytes      atomic.Int64
+	removed    atomic.Int64
+
+	// set for a dry run, nothing it does is counted
+	dry bool
+}
+
+// fetch records the request of a page of host that took d.
+func (ps *passStats) fetch(host string, p *page, err error, d time.Duration) {
+	if ps != nil && ps.dry {
+		return
+	}
+	fetchSeconds.WithLabelValues(politeness.HostLabel(host)).Observe(d.Seconds())
+	downloadedBytes.Add(float64(p.bytes))
+
+	class, status, failed := failureOf(p, err)
+	if failed {
+		fetchFailures.WithLabelValues(class, status).Inc()
+	} else {
+		fetchedPages.Inc()
+	}
+
+	if ps == nil {
+		return
+	}
+	ps.bytes.Add(p.bytes)
+	if failed {
+		ps.failed.Add(1)
+	} else {
+		ps.fetched.Add(1)
+	}
+}
+
+// index records an indexed page.
+func (ps *passStats) index() {
+	if ps != nil && ps.dry {
+		return
+	}
+	indexedPages.Inc()
+	if ps != nil {
+		ps.indexed.Add(1)
+	}
+}
+
+// discover records a link that was not crawled before.
+func (ps *passStats) discover() {
+	if ps != nil && ps.dry {
+		return
+	}
+	discoveredLinks.Inc()
+	if ps != nil {
+		ps.discovered.Add(1)
+	}
+}
+
+// remove records a document removed because its page is dead.
+func (ps *passStats) remove() {
+	if ps != nil && ps.dry {
+		return
+	}
+	removedDocuments.Inc()
+	if ps != nil {
+		ps.removed.Add(1)
+	}
+}
+
+// failureOf classifies a failed request by the error of the request or the
+// HTTP status of the response.
+func failureOf(p *page, err error) (class string, status string, failed bool) {
+	switch {
+	case err != nil:
+		return errorClass(err), "none", true
+	case p.status >= 400:
+		return "http", strconv.Itoa(p.status), true
+	}
+	return "", "", false
+}
+
+func errorClass(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	var opErr *net.OpError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &recordErr):
+		return "tls"
+	case errors.As(err, &opErr):
+		return "connection"
+	}
+	return "other"
+}
@@ -0,0 +1,52 @@
+package linkcrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_pass_stats(t *testing.T) {
+	stats := &passStats{}
+	stats.fetch("a.example.com", &page{status: 200, bytes: 512}, nil, time.Millisecond)
+	stats.fetch("a.example.com", &page{status: 404, bytes: 128}, nil, time.Millisecond)
+	stats.fetch("b.example.com", &page{}, &net.DNSError{Err: "no such host", Name: "b.example.com"}, time.Millisecond)
+	stats.index()
+	stats.discover()
+	stats.discover()
+
+	got := fmt.Sprint(stats.fetched.Load(), stats.failed.Load(), stats.indexed.Load(), stats.discovered.Load(), stats.bytes.Load())
+	if expect := "1 2 1 2 640"; got != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+
+	// a nil passStats only updates the metrics
+	var none *passStats
+	none.fetch("a.example.com", &page{status: 200}, nil, time.Millisecond)
+	none.index()
+	none.discover()
+
+	t.Run("test_failure_class", func(t *testing.T) {
+		tests := []struct {
+			p      *page
+			err    error
+			expect string
+		}{
+			{&page{status: 503}, nil, "http 503 true"},
+			{&page{status: 304}, nil, "  false"},
+			{&page{}, fmt.Errorf("get: %w", context.DeadlineExceeded), "timeout none true"},
+			{&page{}, &net.DNSError{Err: "no such host"}, "dns none true"},
+			{&page{}, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, "connection none true"},
+			{&page{}, errors.New("stopped after 10 redirects"), "other none true"},
+		}
+		for _, tt := range tests {
+			class, status, failed := failureOf(tt.p, tt.err)
+			if got := fmt.Sprintf("%s %s %v", class, status, failed); got != tt.expect {
+				t.Fatalf("\ngot:%v \nexpect:%v", got, tt.expect)
+			}
+		}
+	})
+}
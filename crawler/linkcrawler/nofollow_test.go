@@ -0,0 +1,106 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+func Test_robots_directives(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		var meta string
+		switch r.URL.Path {
+		case "/noindex":
+			w.Header().Set("X-Robots-Tag", "otherbot: nofollow, se-crawler: noindex")
+		case "/nofollow":
+			meta = `<meta name="robots" content="nofollow">`
+		}
+		fmt.Fprintf(w, `<html><head>%s</head><body>
+			<a href="/a">a</a>
+			<a href="/b" rel="sponsored">b</a>
+			<a href="/c" rel="ugc">c</a>
+			<a href="/c">c again</a>
+		</body></html>`, meta)
+	}))
+	defer srv.Close()
+
+	crawl := func(t *testing.T, graph GraphUpdater, idx DocIndexer, path string) *linkgraph.Link {
+		svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		link := &linkgraph.Link{URL: srv.URL + path}
+		if err := graph.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		p, err := svc.pages.get(context.Background(), link.URL, recrawl.State{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := webcrawler.NewResource()
+		r.ID = link.ID
+		r.URL = link.URL
+		if err := svc.newConsumer(nil).upsertResource(r, p); err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+
+	t.Run("test_nofollow_links", func(t *testing.T) {
+		graph := newRecordGraph()
+		idx := newRecordIndex()
+		link := crawl(t, graph, idx, "/")
+
+		// a URL linked with and without rel=ugc is followed
+		expect := []string{srv.URL + "/a", srv.URL + "/c"}
+		if got := graph.outlinks(link.ID); fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if _, ok := idx.docs[link.ID]; !ok {
+			t.Fatal("page not indexed")
+		}
+	})
+
+	t.Run("test_nofollow_page", func(t *testing.T) {
+		graph := newRecordGraph()
+		link := crawl(t, graph, newRecordIndex(), "/nofollow")
+		if got := graph.outlinks(link.ID); len(got) != 0 {
+			t.Fatalf("\ngot:%v \nmessage:%v", got, "links of a nofollow page added to the graph")
+		}
+	})
+
+	t.Run("test_flagged_edges", func(t *testing.T) {
+		// graphs that store the rel attribute keep the links as flagged
+		// edges
+		graph := &anchorGraph{recordGraph: newRecordGraph(), anchors: make(map[string]anchor)}
+		crawl(t, graph, newRecordIndex(), "/nofollow")
+		expect := map[string]anchor{
+			srv.URL + "/a": {text: "a", rel: "nofollow"},
+			srv.URL + "/b": {text: "b", rel: "sponsored"},
+			srv.URL + "/c": {text: "c", rel: "nofollow"},
+		}
+		if fmt.Sprint(graph.anchors) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", graph.anchors, expect)
+		}
+	})
+
+	t.Run("test_noindex", func(t *testing.T) {
+		graph := newRecordGraph()
+		idx := newRecordIndex()
+		link := crawl(t, graph, idx, "/noindex")
+		if _, ok := idx.docs[link.ID]; ok || !idx.removed[link.ID] {
+			t.Fatalf("\ngot:%v %v \nmessage:%v", idx.docs, idx.removed, "noindex page kept in the index")
+		}
+		// the directive for another crawler does not apply
+		if got := graph.outlinks(link.ID); len(got) != 2 {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, 2)
+		}
+	})
+}
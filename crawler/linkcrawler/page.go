@@ -0,0 +1,227 @@
+package linkcrawler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/extract"
+	"github.com/odit-bit/se/crawler/pageinfo"
+	"github.com/odit-bit/se/crawler/politeness"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/robots"
+	"github.com/odit-bit/se/crawler/warc"
+)
+
+// maximum number of bytes read from a page by the crawler's own request
+const maxPageSize = 4 << 20
+
+// maximum number of bytes read from a document that is not HTML, such as a
+// PDF file
+const maxDocumentSize = 32 << 20
+
+// page is the response to the crawler's request of a link. Every page is
+// requested once, the document, the HTTP validators and the signals of the raw
+// markup, such as <link rel=canonical>, are all read from the same response
+// before the link is handed to the pipeline.
+type page struct {
+	// the recrawl state of the link, with the validators of this response
+	state recrawl.State
+	known bool
+
+	// the server answered the conditional request with 304 Not Modified
+	notModified bool
+
+	// the HTTP status of the response and the number of body bytes read,
+	// zero if the request failed
+	status int
+	bytes  int64
+
+	// the URL the request was redirected to, empty without a redirect
+	finalURL string
+
+	// the media type of the response body
+	mediaType string
+
+	info *pageinfo.Info
+
+	// the robots directives of the X-Robots-Tag headers and, for HTML
+	// pages, the robots meta tags
+	directives robots.Directives
+
+	// the document extracted from the body, nil for media types without
+	// extractor
+	doc        *extract.Document
+	extractErr error
+
+	// the connection slot of the host, released once the page is consumed
+	slot politeness.Slot
+}
+
+// indexable reports whether the page is indexed, pages of a media type that
+// can not be extracted and pages that declare noindex are only added to the
+// link graph.
+func (p *page) indexable() bool {
+	if p == nil {
+		return true
+	}
+	if p.directives.NoIndex {
+		return false
+	}
+	return p.mediaType == "" || isHTML(p.mediaType) || p.doc != nil
+}
+
+// nofollow reports whether the page declares that its links do not pass on
+// rank.
+func (p *page) nofollow() bool {
+	return p != nil && p.directives.NoFollow
+}
+
+// content returns the text extracted from the page, empty if there is none.
+func (p *page) content() string {
+	if p == nil || p.doc == nil {
+		return ""
+	}
+	return p.doc.Content
+}
+
+// pageSet keeps the pages requested by the fetcher until the consumer
+// receives the matching resource.
+type pageSet struct {
+	client     *http.Client
+	userAgent  string
+	extractors *extract.Registry
+
+	// nil if responses are not archived
+	archive *warc.Writer
+
+	mu    sync.Mutex
+	pages map[uuid.UUID]*page
+}
+
+func newPageSet(client *http.Client, userAgent string, extractors *extract.Registry) *pageSet {
+	return &pageSet{
+		client:     client,
+		userAgent:  userAgent,
+		extractors: extractors,
+		pages:      make(map[uuid.UUID]*page),
+	}
+}
+
+func (ps *pageSet) put(linkID uuid.UUID, p *page) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.pages[linkID] = p
+}
+
+// take returns and forgets the page of linkID, nil if there is none.
+func (ps *pageSet) take(linkID uuid.UUID) *page {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p := ps.pages[linkID]
+	delete(ps.pages, linkID)
+	return p
+}
+
+// get requests rawURL, conditional on the validators of the previous crawl.
+// The returned page is never nil, if the request fails it only carries the
+// previous state.
+func (ps *pageSet) get(ctx context.Context, rawURL string, state recrawl.State, known bool) (*page, error) {
+	p := &page{state: state, known: known, info: &pageinfo.Info{}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return p, err
+	}
+	req.Header.Set("User-Agent", ps.userAgent)
+	if state.ETag != "" {
+		req.Header.Set("If-None-Match", state.ETag)
+	}
+	if state.LastModified != "" {
+		req.Header.Set("If-Modified-Since", state.LastModified)
+	}
+
+	res, err := ps.client.Do(req)
+	if err != nil {
+		return p, err
+	}
+	defer res.Body.Close()
+
+	if ps.archive != nil {
+		if err := ps.archive.WriteExchange(res.Request, res, maxDocumentSize); err != nil {
+			// the page is crawled all the same
+			log.Println("archive:", err)
+		}
+	}
+	return p, ps.read(p, rawURL, res)
+}
+
+// read reads the response to the request of rawURL into p.
+func (ps *pageSet) read(p *page, rawURL string, res *http.Response) error {
+	p.status = res.StatusCode
+	if final := res.Request.URL.String(); final != rawURL {
+		p.finalURL = final
+	}
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		p.notModified = true
+		return nil
+	case http.StatusOK:
+		p.state.ETag = res.Header.Get("ETag")
+		p.state.LastModified = res.Header.Get("Last-Modified")
+	default:
+		return nil
+	}
+
+	p.mediaType = ps.extractors.MediaType(res.Header.Get("Content-Type"), res.Request.URL.String())
+	headers := res.Header.Values("X-Robots-Tag")
+	p.directives = robots.ParseDirectives(ps.userAgent, headers...)
+	if !isHTML(p.mediaType) {
+		ps.extract(p, res.Body)
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxPageSize))
+	p.bytes = int64(len(body))
+	if err != nil {
+		return err
+	}
+	info, err := pageinfo.Parse(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	p.info = info
+	p.directives = robots.ParseDirectives(ps.userAgent, append(headers, info.Robots...)...)
+	p.doc, p.extractErr = extract.HTML(bytes.NewReader(body))
+	return nil
+}
+
+// extract extracts the document of a body that is not HTML. A document that
+// can not be extracted is not a failed fetch, the error is kept on the page.
+func (ps *pageSet) extract(p *page, r io.Reader) {
+	if _, ok := ps.extractors.Lookup(p.mediaType); !ok {
+		return
+	}
+	body := &countingReader{r: io.LimitReader(r, maxDocumentSize)}
+	p.doc, p.extractErr = ps.extractors.Extract(p.mediaType, body)
+	p.bytes = body.n
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.n += int64(n)
+	return n, err
+}
+
+func isHTML(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
@@ -0,0 +1,32 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/pagerank/partition"
+)
+
+// partitionExtents returns the [from, to) UUID range assigned to this
+// instance. The range split is recomputed whenever the number of partitions
+// changes, e.g. when crawler replicas are added or removed.
+func (la *CrawlService) partitionExtents() (uuid.UUID, uuid.UUID, error) {
+	curPartition, numPartitions, err := la.cfg.PartitionDetector.PartitionInfo()
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	if numPartitions != la.numPartitions {
+		rng, err := partition.NewFullRange(numPartitions)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, fmt.Errorf("crawler partition: %w", err)
+		}
+		if la.numPartitions != 0 {
+			log.Printf("partition count changed from %d to %d", la.numPartitions, numPartitions)
+		}
+		la.partitions, la.numPartitions = rng, numPartitions
+	}
+
+	return la.partitions.PartitionExtents(curPartition)
+}
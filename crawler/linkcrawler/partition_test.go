@@ -0,0 +1,118 @@
+package linkcrawler
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/index/indexapi"
+	"github.com/odit-bit/se/pagerank/partition"
+)
+
+// fakeGraph is an in-memory GraphUpdater holding a fixed set of links.
+type fakeGraph struct {
+	links []*linkgraph.Link
+}
+
+func (g *fakeGraph) UpsertLink(link *linkgraph.Link) error       { return nil }
+func (g *fakeGraph) UpsertEdge(edge *linkgraph.Edge) error       { return nil }
+func (g *fakeGraph) RemoveStaleEdges(uuid.UUID, time.Time) error { return nil }
+func (g *fakeGraph) Links(fromID, toID uuid.UUID, _ time.Time) (linkgraph.LinkIterator, error) {
+	var entries []frontier.Entry
+	for _, l := range g.links {
+		if bytes.Compare(l.ID[:], fromID[:]) >= 0 && bytes.Compare(l.ID[:], toID[:]) < 0 {
+			entries = append(entries, frontier.Entry{LinkID: l.ID, URL: l.URL})
+		}
+	}
+	return &entryIterator{entries: entries}, nil
+}
+
+type fakeIndex struct{}
+
+func (fakeIndex) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+
+func (fakeIndex) DeleteDocument(linkID uuid.UUID) error { return nil }
+
+func Test_partitioned_crawlers(t *testing.T) {
+	graph := &fakeGraph{}
+	for i := 0; i < 1000; i++ {
+		graph.links = append(graph.links, &linkgraph.Link{
+			ID:  uuid.New(),
+			URL: fmt.Sprintf("https://example.com/%d", i),
+		})
+	}
+
+	for _, numCrawlers := range []int{1, 2, 3, 7} {
+		seen := make(map[uuid.UUID]int)
+
+		for p := 0; p < numCrawlers; p++ {
+			svc, err := NewWithConfig(Config{
+				GraphAPI:          graph,
+				IndexAPI:          fakeIndex{},
+				PartitionDetector: partition.Fixed{Partition: p, NumPartitions: numCrawlers},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fromID, toID, err := svc.partitionExtents()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, iter, err := svc.passLinks(svc.passCheckpoint(fromID, toID, false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for iter.Next() {
+				seen[iter.Link().ID]++
+			}
+		}
+
+		if len(seen) != len(graph.links) {
+			t.Fatalf("\ncrawlers:%v \ngot:%v \nexpect:%v \nmessage:%v", numCrawlers, len(seen), len(graph.links),
+				"links not covered by any crawler")
+		}
+		for id, n := range seen {
+			if n != 1 {
+				t.Fatalf("\ncrawlers:%v \ngot:%v \nexpect:%v \nmessage:%v", numCrawlers, n, 1,
+					fmt.Sprint("link crawled more than once ", id))
+			}
+		}
+	}
+}
+
+func Test_partition_count_change(t *testing.T) {
+	det := &partition.Fixed{Partition: 0, NumPartitions: 1}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:          &fakeGraph{},
+		IndexAPI:          fakeIndex{},
+		PartitionDetector: det,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, to, err := svc.partitionExtents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to != maxUUID {
+		t.Fatalf("\ngot:%v \nexpect:%v", to, maxUUID)
+	}
+
+	// a second replica joined
+	det.NumPartitions = 2
+	_, to, err = svc.partitionExtents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to == maxUUID {
+		t.Fatalf("\ngot:%v \nmessage:%v", to, "range was not split after the partition count changed")
+	}
+}
@@ -0,0 +1,79 @@
+package linkcrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/index/indexapi"
+)
+
+// failIndex fails every document after the first n.
+type failIndex struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (fi *failIndex) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if fi.n == 0 {
+		return uuid.Nil, errors.New("index is down")
+	}
+	fi.n--
+	return uuid.Nil, nil
+}
+
+func (fi *failIndex) DeleteDocument(linkID uuid.UUID) error { return nil }
+
+func Test_consumer_failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>page</title></head><body>text</body></html>"))
+	}))
+	defer srv.Close()
+
+	graph := &scanGraph{retrieved: make(map[uuid.UUID]time.Time)}
+	for i := 0; i < 8; i++ {
+		graph.links = append(graph.links, &linkgraph.Link{ID: uuid.New(), URL: fmt.Sprintf("%s/page/%d", srv.URL, i)})
+	}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:     graph,
+		IndexAPI:     &failIndex{n: 2},
+		HostMinDelay: time.Millisecond,
+		HostMaxConns: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errC := make(chan error, 1)
+	go func() { errC <- svc.startCrawl(context.Background(), false) }()
+	select {
+	case err := <-errC:
+		if err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "the error of the consumer")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pass hangs after the consumer failed")
+	}
+
+	// the host of the pages has a single slot, it is free again
+	u, _ := url.Parse(srv.URL)
+	svc.sched.Push(u.Host, &linkgraph.Link{})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	slot, _, err := svc.sched.Pop(ctx)
+	if err != nil {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", err, nil, "slot of the host leaked")
+	}
+	svc.sched.Done(slot)
+}
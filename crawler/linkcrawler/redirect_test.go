@@ -0,0 +1,87 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+// aliasGraph is a recordGraph that keeps the alias edges apart from links.
+type aliasGraph struct {
+	*recordGraph
+	aliases map[uuid.UUID]uuid.UUID
+}
+
+func (g *aliasGraph) UpsertAlias(edge *linkgraph.Edge) error {
+	if err := g.UpsertEdge(edge); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.aliases[edge.Src] = edge.Dst
+	return nil
+}
+
+func Test_redirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>new</title></head><body>moved here</body></html>`))
+	}))
+	defer srv.Close()
+
+	graph := &aliasGraph{recordGraph: newRecordGraph(), aliases: make(map[uuid.UUID]uuid.UUID)}
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &linkgraph.Link{URL: srv.URL + "/old"}
+	if err := graph.UpsertLink(src); err != nil {
+		t.Fatal(err)
+	}
+	p, err := svc.pages.get(context.Background(), src.URL, recrawl.State{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := webcrawler.NewResource()
+	r.ID = src.ID
+	r.URL = src.URL
+	consumer := svc.newConsumer(nil)
+	if err := consumer.upsertResource(r, p); err != nil {
+		t.Fatal(err)
+	}
+
+	finalID := graph.ids[srv.URL+"/new"]
+	t.Run("test_alias_edge", func(t *testing.T) {
+		if graph.aliases[src.ID] != finalID || finalID == uuid.Nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", graph.aliases[src.ID], finalID)
+		}
+		if consumer.redirects.Load() != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v", consumer.redirects.Load(), 1)
+		}
+	})
+
+	t.Run("test_indexed_under_final_url", func(t *testing.T) {
+		if doc := idx.docs[finalID]; doc == nil || doc.URL != srv.URL+"/new" {
+			t.Fatalf("\ngot:%+v \nexpect:%v", doc, srv.URL+"/new")
+		}
+		if _, ok := idx.docs[src.ID]; ok {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", idx.docs[src.ID], "", "redirecting URL was indexed")
+		}
+		if !idx.removed[src.ID] {
+			t.Fatal("document of the redirecting URL was not removed")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package linkcrawler
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/recrawl"
+)
+
+// revisitor decides when a crawled link is due again. Without a store every
+// link is recrawled after the initial interval of the policy.
+type revisitor struct {
+	store  recrawl.Store
+	policy recrawl.Policy
+}
+
+// lookup returns the recrawl state of a link.
+func (rv *revisitor) lookup(linkID uuid.UUID) (recrawl.State, bool) {
+	if rv.store == nil {
+		return recrawl.State{}, false
+	}
+	s, found, err := rv.store.LookupState(linkID)
+	if err != nil {
+		log.Println("recrawl:", err)
+		return recrawl.State{}, false
+	}
+	return s, found
+}
+
+// notModified reschedules a link whose server answered the conditional
+// request with 304 Not Modified.
+func (rv *revisitor) notModified(linkID uuid.UUID, s recrawl.State) time.Time {
+	return rv.save(linkID, rv.policy.Schedule(s, false, time.Now()))
+}
+
+// crawled reschedules a link after its content was retrieved. p is nil if
+// the link was not requested by the fetcher of this service.
+func (rv *revisitor) crawled(linkID uuid.UUID, p *page, contentHash uint64) time.Time {
+	var s recrawl.State
+	known := false
+	if p != nil {
+		s, known = p.state, p.known
+	}
+
+	changed := !known || s.ContentHash != contentHash
+	s.ContentHash = contentHash
+	return rv.save(linkID, rv.policy.Schedule(s, changed, time.Now()))
+}
+
+func (rv *revisitor) save(linkID uuid.UUID, s recrawl.State) time.Time {
+	if rv.store != nil {
+		if err := rv.store.SaveState(linkID, s); err != nil {
+			log.Println("recrawl:", err)
+		}
+	}
+	return s.NextCrawlAt
+}
+
+// hint applies the lastmod of a sitemap entry to the schedule of a link and
+// reports whether the link became due.
+func (rv *revisitor) hint(linkID uuid.UUID, lastMod time.Time) bool {
+	if rv.store == nil || lastMod.IsZero() {
+		return false
+	}
+	s, found := rv.lookup(linkID)
+	if !found {
+		return false
+	}
+	s, ok := recrawl.Hint(s, lastMod, time.Now())
+	if ok {
+		rv.save(linkID, s)
+	}
+	return ok
+}
@@ -0,0 +1,118 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/webcrawler"
+)
+
+type memStateStore struct {
+	mu     sync.Mutex
+	states map[uuid.UUID]recrawl.State
+}
+
+func (m *memStateStore) LookupState(linkID uuid.UUID) (recrawl.State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[linkID]
+	return s, ok, nil
+}
+
+func (m *memStateStore) SaveState(linkID uuid.UUID, s recrawl.State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[linkID] = s
+	return nil
+}
+
+func Test_conditional_recrawl(t *testing.T) {
+	var requests, conditional atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>static page</body></html>"))
+	}))
+	defer srv.Close()
+
+	store := &memStateStore{states: make(map[uuid.UUID]recrawl.State)}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:        &fakeGraph{},
+		IndexAPI:        fakeIndex{},
+		Recrawl:         store,
+		RecrawlInterval: 24 * time.Hour,
+		HostMinDelay:    time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry := frontier.Entry{LinkID: uuid.New(), URL: srv.URL + "/page"}
+
+	// pass crawls the link and returns whether it was handed to the pipeline
+	pass := func() bool {
+		fetcher := svc.newFetcher(ctx, &entryIterator{entries: []frontier.Entry{entry}}, nil)
+		defer fetcher.Close()
+
+		results := make(chan *webcrawler.Resource, 1)
+		handed := false
+		for fetcher.Next() {
+			handed = true
+			results <- fetcher.Resource()
+		}
+		close(results)
+		if err := svc.newConsumer(nil).Consume(ctx, results); err != nil {
+			t.Fatal(err)
+		}
+		return handed
+	}
+
+	t.Run("test_first_crawl", func(t *testing.T) {
+		if !pass() {
+			t.Fatal("link was not crawled")
+		}
+		s, found, _ := store.LookupState(entry.LinkID)
+		if !found || s.ETag != `"v1"` {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.ETag, `"v1"`)
+		}
+		if s.Interval != 24*time.Hour {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.Interval, 24*time.Hour)
+		}
+	})
+
+	t.Run("test_not_modified", func(t *testing.T) {
+		if pass() {
+			t.Fatal("unchanged link was handed to the pipeline")
+		}
+		if conditional.Load() != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", conditional.Load(), 1, "conditional request was not sent")
+		}
+		s, _, _ := store.LookupState(entry.LinkID)
+		if s.Interval <= 24*time.Hour {
+			t.Fatalf("\ngot:%v \nexpect:>%v \nmessage:%v", s.Interval, 24*time.Hour, "unchanged page did not back off")
+		}
+		if s.ETag != `"v1"` {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.ETag, `"v1"`)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/extract"
+	"github.com/odit-bit/se/crawler/pageinfo"
+	"github.com/odit-bit/se/crawler/scope"
+	"github.com/odit-bit/webcrawler"
+)
+
+func Test_scope_found_urls(t *testing.T) {
+	rules, err := scope.Compile(scope.Rules{
+		AllowDomains: []string{"example.com"},
+		Exclude:      []string{`/private/`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := newRecordGraph()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: newRecordIndex(), Scope: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &linkgraph.Link{URL: "http://example.com/"}
+	if err := graph.UpsertLink(src); err != nil {
+		t.Fatal(err)
+	}
+	r := webcrawler.NewResource()
+	r.ID = src.ID
+	r.URL = src.URL
+	doc := &extract.Document{Links: []string{
+		"http://example.com/a",
+		"http://blog.example.com/b",
+		"http://example.com/private/c",
+		"http://other.com/d",
+	}}
+
+	consumer := svc.newConsumer(nil)
+	if err := consumer.upsertResource(r, &page{info: &pageinfo.Info{}, doc: doc}); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{"http://blog.example.com/b", "http://example.com/a"}
+	if got := graph.linksExcept(src.ID); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+	if consumer.outOfScope.Load() != 2 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", consumer.outOfScope.Load(), 2, "out of scope links not counted")
+	}
+}
+
+func Test_scope_max_depth(t *testing.T) {
+	rules, err := scope.Compile(scope.Rules{MaxDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test_without_frontier", func(t *testing.T) {
+		// without a frontier the depth of a link is unknown
+		_, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: newRecordIndex(), Scope: rules})
+		if err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "an error")
+		}
+	})
+
+	t.Run("test_with_frontier", func(t *testing.T) {
+		cfg := Config{GraphAPI: newRecordGraph(), IndexAPI: newRecordIndex(), Scope: rules, Frontier: &refreshFrontier{}}
+		if _, err := NewWithConfig(cfg); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("test_reload_without_frontier", func(t *testing.T) {
+		// rules reloaded later may not add a maximum depth either
+		reloaded := &reloadedScope{}
+		if _, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: newRecordIndex(), Scope: reloaded}); err != nil {
+			t.Fatal(err)
+		}
+		if reloaded.check == nil || reloaded.check(rules) == nil || reloaded.check(scope.Unrestricted) != nil {
+			t.Fatal("reloaded rules with a maximum depth are not rejected")
+		}
+
+		reloaded = &reloadedScope{}
+		cfg := Config{GraphAPI: newRecordGraph(), IndexAPI: newRecordIndex(), Scope: reloaded, Frontier: &refreshFrontier{}}
+		if _, err := NewWithConfig(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if reloaded.check != nil {
+			t.Fatal("reloaded rules checked with a frontier")
+		}
+	})
+}
+
+// reloadedScope is an unrestricted ReloadedScope that keeps its check.
+type reloadedScope struct {
+	check func(*scope.Scope) error
+}
+
+func (s *reloadedScope) Allowed(string, int) bool { return true }
+
+func (s *reloadedScope) Require(check func(*scope.Scope) error) { s.check = check }
@@ -5,12 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/odit-bit/indexstore/index"
 	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/checkpoint"
+	"github.com/odit-bit/se/crawler/event"
+	"github.com/odit-bit/se/crawler/langdetect"
+	"github.com/odit-bit/se/crawler/politeness"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/report"
+	"github.com/odit-bit/se/crawler/robots"
+	"github.com/odit-bit/se/crawler/simhash"
+	"github.com/odit-bit/se/crawler/trap"
+	"github.com/odit-bit/se/graph/canonical"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+	"github.com/odit-bit/se/index/indexapi"
+	"github.com/odit-bit/se/pagerank/partition"
 	"github.com/odit-bit/webcrawler"
 	"github.com/odit-bit/webcrawler/x/xpipe"
 )
@@ -19,30 +37,180 @@ var minUUID = uuid.Nil
 var maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
 var default_interval = 1 * time.Minute
 var default_recrawl_interval = 7 * 24 * time.Hour
+var default_min_recrawl_interval = 1 * time.Hour
+var default_max_recrawl_interval = 30 * 24 * time.Hour
+var default_user_agent = "se-crawler"
+var default_robots_ttl = 24 * time.Hour
+var default_host_min_delay = 1 * time.Second
+var default_host_max_conns = 2
+var default_lookahead = 1000
+var default_sitemap_interval = 24 * time.Hour
+var default_frontier_batch_size = 500
+var default_frontier_lease = 1 * time.Hour
+var default_frontier_refresh_interval = 15 * time.Minute
+var default_duplicate_distance = 3
+var default_max_failures = 3
+var default_checkpoint_interval = 10 * time.Second
+var default_submission_interval = 2 * time.Second
+
+// pipeline streams the resources of a fetcher to a streamer.
+type pipeline interface {
+	Crawl(ctx context.Context, f xpipe.Fetcher[*webcrawler.Resource], s xpipe.Streamer[*webcrawler.Resource]) error
+}
+
+// pagePipeline hands the resources of the fetcher straight to the streamer.
+// The fetcher already requested and extracted every page, the pipeline does
+// not request it again. If the streamer fails the pipeline returns right
+// away, the pages the fetcher still holds are released by its Close.
+type pagePipeline struct{}
+
+func (pagePipeline) Crawl(ctx context.Context, f xpipe.Fetcher[*webcrawler.Resource], s xpipe.Streamer[*webcrawler.Resource]) error {
+	result := make(chan *webcrawler.Resource)
+	errC := make(chan error, 1)
+	go func() { errC <- s.Consume(ctx, result) }()
+	for f.Next() {
+		select {
+		case result <- f.Resource():
+		case err := <-errC:
+			return errors.Join(err, f.Error())
+		case <-ctx.Done():
+		}
+	}
+	close(result)
+	return errors.Join(<-errC, f.Error())
+}
 
 type CrawlService struct {
-	crawler  *webcrawler.Crawler
+	cfg      Config
+	crawler  pipeline
 	graphAPI GraphUpdater
 	indexAPI DocIndexer
+	robots   *robots.Cache
+	sched    *politeness.Scheduler[*linkgraph.Link]
+	canon    *canonical.Canonicalizer
+	pages    *pageSet
+	revisit  *revisitor
+	sitemaps *sitemapIngester
+	status   *statusTracker
+	traps    *trap.Detector
 
-	Interval        time.Duration //default 1 * time.Minute
-	RecrawlInterval time.Duration
+	// nil without a submission queue
+	submissions *submissionLane
+
+	// the UUID range split for the last seen partition count
+	numPartitions int
+	partitions    partition.Range
+
+	// the frontier range refreshed last and when
+	refreshedRange [2]uuid.UUID
+	refreshedAt    time.Time
 }
 
-func New(graphAPI GraphUpdater, indexAPI DocIndexer) *CrawlService {
-	s := CrawlService{
-		crawler:  webcrawler.NewCrawler(),
-		graphAPI: graphAPI,
-		indexAPI: indexAPI,
+// create instance with default configuration
+func New(graphAPI GraphUpdater, indexAPI DocIndexer) (*CrawlService, error) {
+	return NewWithConfig(Config{
+		GraphAPI: graphAPI,
+		IndexAPI: indexAPI,
+	})
+}
 
-		Interval:        default_interval,
-		RecrawlInterval: default_recrawl_interval,
+// NewWithConfig creates a new crawler service instance with the specified config.
+func NewWithConfig(cfg Config) (*CrawlService, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("crawler service: config validation failed: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	s := CrawlService{
+		cfg:      cfg,
+		crawler:  pagePipeline{},
+		graphAPI: cfg.GraphAPI,
+		indexAPI: cfg.IndexAPI,
+		robots:   robots.NewCache(client, cfg.UserAgent, cfg.RobotsTTL),
+		traps:    trap.New(cfg.Traps),
+		sched: politeness.New[*linkgraph.Link](politeness.Config{
+			MinDelay: cfg.HostMinDelay,
+			MaxConns: cfg.HostMaxConns,
+		}),
+		canon: canonical.New(cfg.TrackingParams),
+		pages: newPageSet(client, cfg.UserAgent, cfg.Extractors),
+		revisit: &revisitor{
+			store: cfg.Recrawl,
+			policy: recrawl.Policy{
+				Initial: cfg.RecrawlInterval,
+				Min:     cfg.MinRecrawlInterval,
+				Max:     cfg.MaxRecrawlInterval,
+			},
+		},
+	}
+	s.pages.archive = cfg.Archive
+	if cfg.Submissions != nil {
+		s.submissions = &submissionLane{
+			store:    cfg.Submissions,
+			crawler:  pagePipeline{},
+			interval: cfg.SubmissionInterval,
+			sched:    s.sched.Lane(),
+		}
 	}
-	return &s
+	if cfg.TrapStore != nil {
+		flags, err := cfg.TrapStore.FlaggedHosts()
+		if err != nil {
+			return nil, fmt.Errorf("crawler service: %w", err)
+		}
+		s.traps.Restore(flags)
+	}
+	s.status = &statusTracker{
+		policy: linkstatus.Policy{MaxFailures: cfg.MaxFailures},
+	}
+	s.status.graph, _ = cfg.GraphAPI.(StatusRecorder)
+	s.status.index = cfg.IndexAPI
+	s.sitemaps = &sitemapIngester{
+		client:    client,
+		userAgent: cfg.UserAgent,
+		interval:  cfg.SitemapInterval,
+		canon:     s.canon,
+		graph:     cfg.GraphAPI,
+		scope:     cfg.Scope,
+		revisit:   s.revisit,
+		traps:     s.traps,
+		trapStore: cfg.TrapStore,
+		queue:     make(chan string, sitemapQueueSize),
+		read:      make(map[string]time.Time),
+	}
+	return &s, nil
 }
 
 func (la *CrawlService) Run(ctx context.Context) error {
-	ticker := time.NewTicker(la.Interval)
+	if err := la.knownLinks(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// submitted links are crawled alongside the passes
+	if la.submissions != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			la.runSubmissions(ctx)
+		}()
+	}
+
+	// submitted sitemaps are ingested alongside the passes as well
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		la.sitemaps.runSubmitted(ctx, la.submittedPass())
+	}()
+
+	// a pass that was interrupted, e.g. by a restart, is resumed right away
+	// instead of after an interval
+	if err := la.startCrawl(ctx, true); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(la.cfg.Interval)
 	defer ticker.Stop()
 
 	for {
@@ -50,70 +218,420 @@ func (la *CrawlService) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			err := la.startCrawl(ctx)
+			err := la.startCrawl(ctx, false)
 			if err != nil {
 				return err
 			}
-			ticker.Reset(la.Interval)
+			ticker.Reset(la.cfg.Interval)
 		}
 	}
 }
 
+// knownLinks records the URLs of the links in the graph as known to the trap
+// detector. It only remembers the URLs it accepted since the start, without
+// them a flagged host would throttle its existing links and the edges to them
+// would be dropped the next time the page linking them is crawled.
+func (la *CrawlService) knownLinks() error {
+	it, err := la.graphAPI.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return fmt.Errorf("crawler service: %w", err)
+	}
+	defer it.Close()
+
+	for it.Next() {
+		la.traps.Known(it.Link().URL)
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("crawler service: %w", err)
+	}
+	return nil
+}
+
 //underlying crawler need fetcher and streamer for source and sink, that this type profided
 //from api
 
-func (la *CrawlService) startCrawl(ctx context.Context) error {
+// startCrawl runs a crawl pass, resuming the unfinished pass of this
+// partition if there is one. With resumeOnly set it only resumes.
+func (la *CrawlService) startCrawl(ctx context.Context, resumeOnly bool) error {
 
-	producer, err := la.Fetcher(minUUID, maxUUID, time.Now().Add(-la.RecrawlInterval))
+	fromID, toID, err := la.partitionExtents()
 	if err != nil {
+		if errors.Is(err, partition.ErrNoPartitionDataAvailableYet) {
+			log.Println("deferring crawl pass: partition data not yet available")
+			return nil
+		}
 		return err
 	}
 
-	consumer, err := la.Consumer()
+	cp := la.passCheckpoint(fromID, toID, resumeOnly)
+	if cp == nil {
+		return nil
+	}
+
+	pass, iter, err := la.passLinks(cp)
 	if err != nil {
 		return err
 	}
 
+	stats := &passStats{}
+	progress := newPassProgress(cp.LastID)
+	producer := la.newFetcher(ctx, iter, pass)
+	producer.stats = stats
+	producer.progress = progress
+	consumer := la.newConsumer(pass)
+	consumer.stats = stats
+	consumer.progress = progress
+
+	// the counts of a resumed pass continue from its checkpoint
+	counts := func() report.Pass {
+		r := report.Pass{
+			StartedAt:    cp.Counts.StartedAt,
+			Links:        producer.counter.Load(),
+			Fetched:      stats.fetched.Load(),
+			Failed:       stats.failed.Load(),
+			NotModified:  producer.unchanged.Load(),
+			Disallowed:   producer.disallowed.Load(),
+			OutOfScope:   producer.outOfScope.Load() + consumer.outOfScope.Load(),
+			Trapped:      consumer.trapped.Load(),
+			Indexed:      stats.indexed.Load(),
+			Duplicates:   consumer.duplicates.Load(),
+			NonCanonical: consumer.aliased.Load(),
+			Redirects:    consumer.redirects.Load(),
+			Discovered:   stats.discovered.Load(),
+			Bytes:        stats.bytes.Load(),
+			Removed:      stats.removed.Load(),
+		}
+		r.Add(&cp.Counts)
+		return r
+	}
+
+	stopSaving := la.saveCheckpoints(cp, progress, counts)
 	err = la.crawler.Crawl(ctx, producer, consumer)
 	producer.Close()
+	stopSaving()
 
-	log.Println("fecthed link:", producer.counter)
-	log.Println("dispatched link:", consumer.counter)
+	if err != nil || ctx.Err() != nil {
+		la.saveCheckpoint(*cp, progress, counts)
+		return err
+	}
+	la.clearCheckpoint(cp)
 
-	return err
+	r := counts()
+	r.FinishedAt = time.Now()
+	la.report(&r)
+
+	return nil
+}
+
+// report logs the summary of a pass and saves it if a report store is
+// configured.
+func (la *CrawlService) report(r *report.Pass) {
+	log.Printf("crawl pass: %d links in %v (%.2f pages/s), fetched %d, failed %d, not modified %d, disallowed %d, out of scope %d",
+		r.Links, r.Duration().Round(time.Millisecond), r.PagesPerSecond(), r.Fetched, r.Failed, r.NotModified, r.Disallowed, r.OutOfScope)
+	log.Printf("crawl pass: indexed %d, near-duplicate %d, non-canonical %d, redirects %d, removed %d dead, discovered %d links, %d trapped, %d bytes",
+		r.Indexed, r.Duplicates, r.NonCanonical, r.Redirects, r.Removed, r.Discovered, r.Trapped, r.Bytes)
+
+	if la.cfg.Reports == nil {
+		return
+	}
+	if err := la.cfg.Reports.SavePass(r); err != nil {
+		log.Println("crawl pass:", err)
+	}
+}
+
+// passLinks returns the links to crawl in the range of the pass, either the
+// next frontier batch or every link that is due for a recrawl. A resumed pass
+// hands out the unacknowledged links of its frontier batch again or continues
+// the graph scan after the last processed link.
+func (la *CrawlService) passLinks(cp *checkpoint.Checkpoint) (*frontierPass, linkgraph.LinkIterator, error) {
+	if la.cfg.Frontier != nil {
+		la.release(cp)
+		pass, iter, err := la.dequeue(cp.FromID, cp.ToID)
+		if err != nil {
+			return nil, nil, err
+		}
+		cp.LeasedUntil = pass.leasedUntil
+		return pass, iter, nil
+	}
+
+	fromID := cp.FromID
+	if cp.LastID != uuid.Nil {
+		fromID = checkpoint.Next(cp.LastID)
+	}
+	iter, err := la.graphAPI.Links(fromID, cp.ToID, cp.RetrievedBefore)
+	return nil, iter, err
 }
 
 // return fetcher to supply data for pipe
-func (li *CrawlService) Fetcher(fromID, toID uuid.UUID, retrieveBefore time.Time) (*linkFetcher, error) {
+func (li *CrawlService) Fetcher(ctx context.Context, fromID, toID uuid.UUID, retrieveBefore time.Time) (*linkFetcher, error) {
 	iter, err := li.graphAPI.Links(fromID, toID, retrieveBefore)
 	if err != nil {
 		return nil, err
 	}
 
-	fetcher := &linkFetcher{
+	return li.newFetcher(ctx, iter, nil), nil
+}
+
+func (li *CrawlService) newFetcher(ctx context.Context, iter linkgraph.LinkIterator, pass *frontierPass) *linkFetcher {
+	// the fetcher is stopped by Close, even if ctx is not done
+	ctx, cancel := context.WithCancel(ctx)
+	return &linkFetcher{
+		ctx:          ctx,
+		cancel:       cancel,
+		robots:       li.robots,
+		userAgent:    li.cfg.UserAgent,
+		sched:        li.sched,
+		lookahead:    li.cfg.Lookahead,
+		pages:        li.pages,
+		revisit:      li.revisit,
+		sitemaps:     li.sitemaps,
+		status:       li.status,
+		scope:        li.cfg.Scope,
+		ready:        make(chan *linkgraph.Link),
+		graph:        li.graphAPI,
+		frontier:     pass,
+		events:       li.cfg.Events,
 		LinkIterator: iter,
 	}
-
-	return fetcher, nil
 }
 
 // return streamer to send data from pipe
 func (li *CrawlService) Consumer() (*linkConsumer, error) {
-	consumer := &linkConsumer{
+	return li.newConsumer(nil), nil
+}
+
+func (li *CrawlService) newConsumer(pass *frontierPass) *linkConsumer {
+	anchors, _ := li.graphAPI.(AnchorGraph)
+	batch, _ := li.graphAPI.(BatchGraph)
+	aliases, _ := li.graphAPI.(AliasGraph)
+	return &linkConsumer{
+		sched:        li.sched,
+		frontier:     pass,
+		pages:        li.pages,
+		revisit:      li.revisit,
+		canon:        li.canon,
+		scope:        li.cfg.Scope,
+		traps:        li.traps,
+		trapStore:    li.cfg.TrapStore,
+		maxDistance:  li.cfg.DuplicateDistance,
+		anchors:      anchors,
+		batch:        batch,
+		aliases:      aliases,
+		events:       li.cfg.Events,
 		GraphUpdater: li.graphAPI,
 		DocIndexer:   li.indexAPI,
 	}
-
-	return consumer, nil
 }
 
 var _ xpipe.Fetcher[*webcrawler.Resource] = (*linkFetcher)(nil)
 
 type linkFetcher struct {
-	counter int
+	counter    atomic.Int64
+	disallowed atomic.Int64
+	outOfScope atomic.Int64
+	unchanged  atomic.Int64
+	stats      *passStats
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	robots    *robots.Cache
+	userAgent string
+	sched     *politeness.Scheduler[*linkgraph.Link]
+	lookahead int
+	pages     *pageSet
+	revisit   *revisitor
+	sitemaps  *sitemapIngester
+	status    *statusTracker
+	scope     ScopePolicy
+	graph     GraphUpdater
+	frontier  *frontierPass
+	progress  *passProgress
+	events    EventSink
+	link      *linkgraph.Link
+
+	start sync.Once
+	wg    sync.WaitGroup
+	ready chan *linkgraph.Link
+
 	linkgraph.LinkIterator
 }
 
+// Next implements xpipe.Fetcher.
+// links are buffered per host and handed out in the order their hosts become
+// eligible for another request, so a large site does not hold up the others.
+func (lf *linkFetcher) Next() bool {
+	lf.start.Do(func() {
+		lf.wg.Add(1)
+		go lf.dispatch()
+	})
+
+	select {
+	case l, ok := <-lf.ready:
+		if !ok {
+			return false
+		}
+		lf.link = l
+		return true
+	case <-lf.ctx.Done():
+		return false
+	}
+}
+
+// Close implements xpipe.Fetcher. It stops the requests still in flight and
+// releases the host slots of the pages that were not consumed, e.g. because
+// the consumer failed.
+func (lf *linkFetcher) Close() error {
+	lf.cancel()
+	lf.wg.Wait()
+	if lf.link != nil {
+		if p := lf.pages.take(lf.link.ID); p != nil {
+			lf.sched.Done(p.slot)
+		}
+	}
+	return lf.LinkIterator.Close()
+}
+
+// dispatch pops links as their hosts become eligible and requests each of
+// them concurrently before they are handed to the pipeline.
+func (lf *linkFetcher) dispatch() {
+	defer lf.wg.Done()
+
+	var checks sync.WaitGroup
+	defer close(lf.ready)
+	defer checks.Wait()
+
+	for {
+		lf.fill()
+
+		slot, l, err := lf.sched.Pop(lf.ctx)
+		if err != nil {
+			return
+		}
+		checks.Add(1)
+		go func() {
+			defer checks.Done()
+			lf.check(slot, l)
+		}()
+	}
+}
+
+// check requests the page of l, conditional on the validators of its last
+// crawl. Unchanged pages and pages that failed to load are rescheduled
+// without going through the pipeline. The slot of the host is released once
+// the page is done with.
+func (lf *linkFetcher) check(slot politeness.Slot, l *linkgraph.Link) {
+	state, known := lf.revisit.lookup(l.ID)
+	start := time.Now()
+	p, err := lf.pages.get(lf.ctx, l.URL, state, known)
+	if lf.ctx.Err() != nil {
+		lf.sched.Done(slot)
+		return
+	}
+	d := time.Since(start)
+	lf.stats.fetch(slot.Host, p, err, d)
+	publish(lf.events, fetchEvent(l, p, err, d))
+	if err != nil {
+		log.Println("link fetcher:", err)
+	}
+	if p.extractErr != nil {
+		log.Println("link fetcher:", l.URL, p.extractErr)
+	}
+
+	if lf.status.record(l.ID, p.status, err) {
+		lf.stats.remove()
+	}
+	if linkstatus.Failed(p.status, err) {
+		lf.sched.Done(slot)
+		lf.frontier.ack(l.ID)
+		lf.retrieved(l)
+		return
+	}
+
+	if p.notModified {
+		lf.unchanged.Add(1)
+		lf.sched.Done(slot)
+		lf.frontier.ackAt(l.ID, lf.revisit.notModified(l.ID, state))
+		lf.retrieved(l)
+		return
+	}
+
+	p.slot = slot
+	lf.pages.put(l.ID, p)
+	select {
+	case lf.ready <- l:
+	case <-lf.ctx.Done():
+		lf.pages.take(l.ID)
+		lf.sched.Done(slot)
+	}
+}
+
+// Link implements linkgraph.LinkIterator.
+func (lf *linkFetcher) Link() *linkgraph.Link {
+	return lf.link
+}
+
+// fill tops up the scheduler from the underlying iterator, skipping links
+// that are out of scope or disallowed by the robots.txt of their host.
+func (lf *linkFetcher) fill() {
+	for lf.sched.Pending() < lf.lookahead && lf.LinkIterator.Next() {
+		l := lf.LinkIterator.Link()
+		lf.progress.read(l.ID)
+
+		// the scope may have narrowed since the link was added
+		if !lf.scope.Allowed(l.URL, lf.frontier.depthOf(l.ID)) {
+			lf.outOfScope.Add(1)
+			lf.frontier.ack(l.ID)
+			lf.retrieved(l)
+			continue
+		}
+
+		u, err := url.Parse(l.URL)
+		if err != nil {
+			lf.skip(l)
+			continue
+		}
+
+		rules, err := lf.robots.Lookup(lf.ctx, u)
+		if err != nil {
+			if lf.ctx.Err() != nil {
+				return
+			}
+			lf.skip(l)
+			continue
+		}
+		if !rules.Allowed(lf.userAgent, u.RequestURI()) {
+			lf.skip(l)
+			continue
+		}
+
+		lf.sched.Push(u.Host, l)
+		lf.sched.SetCrawlDelay(u.Host, rules.CrawlDelay(lf.userAgent))
+		lf.sitemaps.discover(lf.ctx, u, rules, lf.frontier)
+	}
+}
+
+// skip marks a disallowed link as retrieved so it is not handed out again
+// until the recrawl interval has passed and robots.txt is checked again.
+func (lf *linkFetcher) skip(l *linkgraph.Link) {
+	lf.disallowed.Add(1)
+	lf.frontier.ack(l.ID)
+	lf.retrieved(l)
+}
+
+// retrieved updates the retrieval time of a link that was not handed to the
+// pipeline and records it as processed.
+func (lf *linkFetcher) retrieved(l *linkgraph.Link) {
+	err := lf.graph.UpsertLink(&linkgraph.Link{
+		ID:          l.ID,
+		URL:         l.URL,
+		RetrievedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("link fetcher:", err)
+	}
+	lf.progress.processed(l.ID)
+}
+
 // Resource implements xpipe.Fetcher.
 func (lf *linkFetcher) Resource() *webcrawler.Resource {
 	l := lf.Link()
@@ -121,14 +639,48 @@ func (lf *linkFetcher) Resource() *webcrawler.Resource {
 	resource.ID = l.ID
 	resource.URL = l.URL
 
-	lf.counter++
+	lf.counter.Add(1)
 	return resource
 }
 
 var _ xpipe.Streamer[*webcrawler.Resource] = (*linkConsumer)(nil)
 
 type linkConsumer struct {
-	counter int
+	counter  int
+	stats    *passStats
+	sched    *politeness.Scheduler[*linkgraph.Link]
+	frontier *frontierPass
+	progress *passProgress
+	pages    *pageSet
+	revisit  *revisitor
+	canon    *canonical.Canonicalizer
+	aliased  atomic.Int64
+
+	scope      ScopePolicy
+	outOfScope atomic.Int64
+
+	// the store is nil if flagged hosts are not saved
+	traps     *trap.Detector
+	trapStore trap.Store
+	trapped   atomic.Int64
+
+	// nil if the graph can not write the links of a page in one batch
+	batch BatchGraph
+
+	// nil if the graph does not tell alias edges from links
+	aliases   AliasGraph
+	redirects atomic.Int64
+
+	// the SimHash distance within which pages are near-duplicates
+	maxDistance int
+	duplicates  atomic.Int64
+
+	// nil if the graph does not keep anchors
+	anchors AnchorGraph
+
+	// nil if no events are published
+	events EventSink
+
 	GraphUpdater
 	DocIndexer
 }
@@ -143,97 +695,446 @@ func (ld *linkConsumer) Consume(ctx context.Context, result <-chan *webcrawler.R
 			if !ok {
 				return nil
 			}
-			err := ld.upsertResource(r)
+			id := r.ID
+			p := ld.pages.take(id)
+			if p != nil {
+				ld.sched.Done(p.slot)
+			}
+			hash := recrawl.ContentHash(p.content())
+			err := ld.upsertResource(r, p)
+			r.Put()
 			if err != nil {
 				return err
 			}
+			ld.frontier.ackAt(id, ld.revisit.crawled(id, p, hash))
+			ld.progress.processed(id)
 
 		}
 	}
 }
 
-func (ld *linkConsumer) upsertResource(r *webcrawler.Resource) error {
-	defer r.Put() //bug potential
-
+// upsertResource writes the link and the document of a crawled resource,
+// the resource is still owned by the caller.
+func (ld *linkConsumer) upsertResource(r *webcrawler.Resource, p *page) error {
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	var err error
 
 	//upsert link
-	n := len(r.FoundURLs)
-	foundURls := make([]string, n)
-	nn := copy(foundURls, r.FoundURLs)
-	if nn != n {
-		return fmt.Errorf("link consumer : failed copy slice")
-	}
-
 	link := &linkgraph.Link{
 		ID:          r.ID,
 		URL:         r.URL,
 		RetrievedAt: time.Now(),
 	}
 
+	// a page reached through a redirect is recorded as an alias of the URL
+	// it redirects to, its content belongs to the final URL
+	pageURL := r.URL
+	if final := ld.redirectOf(p, r.URL); final != "" {
+		finalLink, err := ld.upsertRedirect(link, final)
+		if err != nil || finalLink == nil {
+			return err
+		}
+		link, pageURL = finalLink, final
+	}
+
+	// a page that declares another canonical URL is not indexed, its rank
+	// is passed on to the canonical page instead
+	target := ld.canonicalOf(p, pageURL)
+	if target != pageURL && ld.scope.Allowed(target, ld.frontier.depthOf(r.ID)) {
+		ld.aliased.Add(1)
+		return ld.upsertCanonical(link, target)
+	}
+
+	// the page is indexed with the text extracted by the fetcher, the links
+	// it contains are added to the link graph
+	var title, content string
+	var foundURls []string
+	if p != nil && p.doc != nil {
+		title, content = p.doc.Title, p.doc.Content
+		foundURls = ld.resolveAll(pageURL, p.doc.Links)
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		newErr := ld.upsertLinkEdge(link, foundURls)
+		newErr := ld.upsertLinkEdge(link, foundURls, ld.anchorsOf(p, pageURL), p.nofollow())
+		mu.Lock()
 		err = errors.Join(err, newErr)
+		mu.Unlock()
 	}()
 
+	if !p.indexable() {
+		wg.Wait()
+		// a page that declares noindex leaves the index if it was indexed
+		// before
+		if err == nil && p.directives.NoIndex {
+			err = ld.DeleteDocument(link.ID)
+		}
+		return err
+	}
+
 	//index doc
-	doc := &index.Document{
-		LinkID:    r.ID,
-		URL:       r.URL,
-		Title:     string(r.Title),
-		Content:   string(r.Content),
+	doc := &indexapi.Document{Document: index.Document{
+		LinkID:    link.ID,
+		URL:       pageURL,
+		Title:     title,
+		Content:   content,
 		IndexedAt: time.Now(),
-	}
+	}}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		newErr := ld.Index(doc)
+		newErr := ld.indexDocument(doc, p)
+		mu.Lock()
 		err = errors.Join(err, newErr)
+		mu.Unlock()
 	}()
 
 	wg.Wait()
 	return err
 }
 
-func (ld *linkConsumer) upsertLinkEdge(link *linkgraph.Link, foundURLs []string) error {
+// indexDocument indexes doc along with the language, media type, metadata and
+// fingerprint of its page and the anchor text of the links pointing to it. A
+// near-duplicate of an already indexed document is recorded as its alias by
+// the indexer.
+func (ld *linkConsumer) indexDocument(doc *indexapi.Document, p *page) error {
+	doc.Language, _ = langdetect.Detect(doc.Title + "\n" + doc.Content)
+	doc.Fingerprint, _ = simhash.Fingerprint(doc.Content)
+	if p != nil {
+		doc.MediaType = p.mediaType
+		doc.Meta = p.info.Meta
+		if doc.Meta.Favicon != "" {
+			doc.Meta.Favicon, _ = ld.canon.Resolve(doc.URL, doc.Meta.Favicon)
+		}
+	}
+	if ld.anchors != nil {
+		text, err := ld.anchors.AnchorText(doc.LinkID)
+		if err != nil {
+			return err
+		}
+		doc.AnchorText = text
+	}
 
-	if err := ld.UpsertLink(link); err != nil {
+	duplicateOf, err := ld.IndexDocument(doc, ld.maxDistance)
+	if err != nil {
 		return err
 	}
+	if duplicateOf != uuid.Nil {
+		ld.duplicates.Add(1)
+		return nil
+	}
+	ld.stats.index()
+	publish(ld.events, &event.DocumentIndexed{
+		Link:  event.Link{ID: doc.LinkID, URL: doc.URL, At: time.Now()},
+		Title: doc.Title,
+	})
+	return nil
+}
 
-	for _, dst := range foundURLs {
-		dstLink := &linkgraph.Link{
-			URL: dst,
+// resolveAll resolves the links of an extracted document against the URL
+// of the document, links that can not be resolved are dropped.
+func (ld *linkConsumer) resolveAll(base string, links []string) []string {
+	resolved := make([]string, 0, len(links))
+	for _, link := range links {
+		if u, err := ld.canon.Resolve(base, link); err == nil {
+			resolved = append(resolved, u)
 		}
+	}
+	return resolved
+}
 
-		//insert link destination as node
-		err := ld.UpsertLink(dstLink)
+// anchor is the text and rel attribute of a link.
+type anchor struct {
+	text string
+	rel  string
+}
+
+// nofollow reports whether the link asks not to pass on rank.
+func (a anchor) nofollow() bool {
+	return robots.NoFollowRel(a.rel)
+}
+
+// anchorsOf returns the anchors of the links of a crawled page by canonical
+// URL. A URL linked more than once keeps the first non-empty text, it is
+// followed if any of its links is.
+func (ld *linkConsumer) anchorsOf(p *page, rawURL string) map[string]anchor {
+	if p == nil {
+		return nil
+	}
+	anchors := make(map[string]anchor, len(p.info.Links))
+	for _, l := range p.info.Links {
+		dst, err := ld.canon.Resolve(rawURL, l.Href)
+		if err != nil {
+			continue
+		}
+		a, ok := anchors[dst]
+		if !ok {
+			anchors[dst] = anchor{text: l.Text, rel: l.Rel}
+			continue
+		}
+		if a.text == "" {
+			a.text = l.Text
+		}
+		if a.nofollow() && !robots.NoFollowRel(l.Rel) {
+			a.rel = l.Rel
+		}
+		anchors[dst] = a
+	}
+	return anchors
+}
+
+// flagNofollow adds nofollow to the anchors of every link of a page that
+// declares nofollow. Graphs that store the rel attribute keep the links that
+// are not followed as flagged edges, for other graphs they are left out.
+func (ld *linkConsumer) flagNofollow(dsts []*linkgraph.Link, anchors map[string]anchor, page bool) ([]*linkgraph.Link, map[string]anchor) {
+	if page {
+		flagged := make(map[string]anchor, len(dsts))
+		for _, dst := range dsts {
+			a := anchors[dst.URL]
+			if !a.nofollow() {
+				a.rel = strings.TrimSpace(a.rel + " nofollow")
+			}
+			flagged[dst.URL] = a
+		}
+		anchors = flagged
+	}
+	if ld.anchors != nil || ld.batch != nil {
+		return dsts, anchors
+	}
+	return followedOf(dsts, anchors), anchors
+}
+
+// followedOf returns the links whose anchor does not ask for nofollow.
+func followedOf(dsts []*linkgraph.Link, anchors map[string]anchor) []*linkgraph.Link {
+	followed := make([]*linkgraph.Link, 0, len(dsts))
+	for _, dst := range dsts {
+		if !anchors[dst.URL].nofollow() {
+			followed = append(followed, dst)
+		}
+	}
+	return followed
+}
+
+// upsertEdge upserts an edge along with the anchor of its link if the graph
+// keeps anchors.
+func (ld *linkConsumer) upsertEdge(edge *linkgraph.Edge, a anchor) error {
+	if ld.anchors == nil {
+		return ld.UpsertEdge(edge)
+	}
+	return ld.anchors.UpsertAnchoredEdge(edge, a.text, a.rel)
+}
+
+// canonicalOf returns the canonical URL of a crawled page, preferring the
+// <link rel=canonical> declared by the page.
+func (ld *linkConsumer) canonicalOf(p *page, rawURL string) string {
+	if p != nil && p.info.Canonical != "" {
+		if target, err := ld.canon.Resolve(rawURL, p.info.Canonical); err == nil {
+			return target
+		}
+	}
+	if target, err := ld.canon.URL(rawURL); err == nil {
+		return target
+	}
+	return rawURL
+}
+
+// redirectOf returns the canonical URL a crawled page was redirected to,
+// empty if the request was not redirected to another URL.
+func (ld *linkConsumer) redirectOf(p *page, rawURL string) string {
+	if p == nil || p.finalURL == "" {
+		return ""
+	}
+	final, err := ld.canon.URL(p.finalURL)
+	if err != nil {
+		return ""
+	}
+	if requested, err := ld.canon.URL(rawURL); err == nil && requested == final {
+		return ""
+	}
+	return final
+}
+
+// upsertRedirect replaces the outgoing edges of link with an alias edge to
+// the URL it redirects to and removes the document indexed for link. It
+// returns the link of the final URL, nil if the final URL is out of scope.
+func (ld *linkConsumer) upsertRedirect(link *linkgraph.Link, final string) (*linkgraph.Link, error) {
+	if err := ld.UpsertLink(link); err != nil {
+		return nil, err
+	}
+	if !ld.scope.Allowed(final, ld.frontier.depthOf(link.ID)) {
+		ld.outOfScope.Add(1)
+		return nil, nil
+	}
+
+	dst := &linkgraph.Link{URL: final, RetrievedAt: time.Now()}
+	if err := ld.UpsertLink(dst); err != nil {
+		return nil, err
+	}
+
+	before := time.Now()
+	edge := &linkgraph.Edge{Src: link.ID, Dst: dst.ID}
+	if ld.aliases != nil {
+		if err := ld.aliases.UpsertAlias(edge); err != nil {
+			return nil, err
+		}
+	} else if err := ld.UpsertEdge(edge); err != nil {
+		return nil, err
+	}
+	if err := ld.RemoveStaleEdges(link.ID, before); err != nil {
+		return nil, err
+	}
+
+	if err := ld.DeleteDocument(link.ID); err != nil {
+		return nil, err
+	}
+	ld.redirects.Add(1)
+	return dst, nil
+}
+
+// upsertCanonical replaces the outgoing edges of link with a single edge to
+// its canonical URL.
+func (ld *linkConsumer) upsertCanonical(link *linkgraph.Link, target string) error {
+	if err := ld.UpsertLink(link); err != nil {
+		return err
+	}
+
+	dst := &linkgraph.Link{URL: target}
+	if err := ld.UpsertLink(dst); err != nil {
+		return err
+	}
+	ld.frontier.discovered(link.ID, []*linkgraph.Link{dst})
+
+	before := time.Now()
+	if err := ld.UpsertEdge(&linkgraph.Edge{Src: link.ID, Dst: dst.ID}); err != nil {
+		return err
+	}
+	return ld.RemoveStaleEdges(link.ID, before)
+}
+
+// upsertLinkEdge upserts link and the links found on its page and replaces
+// the outgoing edges of link with edges to them. Links that are not followed
+// are not added to the frontier.
+func (ld *linkConsumer) upsertLinkEdge(link *linkgraph.Link, foundURLs []string, anchors map[string]anchor, nofollow bool) error {
+	dsts, anchors := ld.flagNofollow(ld.outlinksOf(link, foundURLs), anchors, nofollow)
+	if ld.batch != nil {
+		isNew, err := ld.upsertOutlinks(link, dsts, anchors)
 		if err != nil {
 			return err
 		}
-		ld.counter++
+		ld.discovered(link, dsts, isNew, anchors)
+		return nil
+	}
 
+	if err := ld.upsertLinks(link, dsts); err != nil {
+		return err
+	}
+	ld.discovered(link, dsts, nil, anchors)
+
+	// edges that were not upserted again are no longer linked by the page
+	removeEdgeBefore := time.Now()
+	for _, dstLink := range dsts {
 		//insert link destination as edge
 		edge := linkgraph.Edge{
-
 			Src: link.ID,
 			Dst: dstLink.ID,
 		}
-
-		if err := ld.UpsertEdge(&edge); err != nil {
+		if err := ld.upsertEdge(&edge, anchors[dstLink.URL]); err != nil {
 			return err
 		}
-		ld.counter++
+	}
+	return ld.RemoveStaleEdges(link.ID, removeEdgeBefore)
+}
 
-		removeEdgeBefore := time.Now()
-		if err := ld.RemoveStaleEdges(link.ID, removeEdgeBefore); err != nil {
-			return err
+// upsertOutlinks writes link, the links of its page and its outgoing edges
+// with their anchors in one batch and sets the ID of each link. It reports
+// which of the links were new to the graph.
+func (ld *linkConsumer) upsertOutlinks(link *linkgraph.Link, dsts []*linkgraph.Link, anchors map[string]anchor) ([]bool, error) {
+	links := make([]*outlink.Link, len(dsts))
+	for i, dstLink := range dsts {
+		a := anchors[dstLink.URL]
+		links[i] = &outlink.Link{Link: *dstLink, Text: a.text, Rel: a.rel}
+	}
+	if err := ld.batch.UpsertOutlinks(link, links); err != nil {
+		return nil, err
+	}
+	isNew := make([]bool, len(links))
+	for i, l := range links {
+		*dsts[i], isNew[i] = l.Link, l.New
+	}
+	return isNew, nil
+}
+
+// discovered publishes the links of the page of link that were new to the
+// graph and adds the followed ones to the frontier. isNew is nil if the graph
+// does not tell new links from known ones, no link is published then.
+func (ld *linkConsumer) discovered(link *linkgraph.Link, dsts []*linkgraph.Link, isNew []bool, anchors map[string]anchor) {
+	for i, dstLink := range dsts {
+		if isNew != nil && isNew[i] {
+			ld.stats.discover()
+			publish(ld.events, &event.LinkDiscovered{
+				Link:     event.Link{ID: dstLink.ID, URL: dstLink.URL, At: time.Now()},
+				SourceID: link.ID,
+			})
+		}
+	}
+	ld.frontier.discovered(link.ID, followedOf(dsts, anchors))
+	ld.counter += 2 * len(dsts)
+}
+
+// outlinksOf returns the links to the distinct canonical URLs found on the
+// page of link that are in scope and do not look like a crawler trap.
+func (ld *linkConsumer) outlinksOf(link *linkgraph.Link, foundURLs []string) []*linkgraph.Link {
+	depth := ld.frontier.depthOf(link.ID) + 1
+	dsts := make([]*linkgraph.Link, 0, len(foundURLs))
+	seen := make(map[string]bool, len(foundURLs))
+	for _, dst := range foundURLs {
+		dst, err := ld.canon.URL(dst)
+		if err != nil || seen[dst] {
+			continue
 		}
+		seen[dst] = true
 
+		if !ld.scope.Allowed(dst, depth) {
+			ld.outOfScope.Add(1)
+			continue
+		}
+		if isTrap(ld.traps, ld.trapStore, dst) {
+			ld.trapped.Add(1)
+			continue
+		}
+		dsts = append(dsts, &linkgraph.Link{URL: dst})
 	}
+	return dsts
+}
 
+// isTrap reports whether the discovered URL dst is rejected by the trap
+// detector. A host flagged by dst is logged and saved to store, if any.
+func isTrap(traps *trap.Detector, store trap.Store, dst string) bool {
+	reason, flag := traps.Check(dst)
+	if flag != nil {
+		log.Printf("crawler trap: flagged host %s: %s", flag.Host, flag.Reason)
+		if store != nil {
+			if err := store.FlagHost(*flag); err != nil {
+				log.Println("crawler trap:", err)
+			}
+		}
+	}
+	return reason != trap.Accepted
+}
+
+// upsertLinks upserts link and the links of its page one by one, for graphs
+// that can not write them in one batch.
+func (ld *linkConsumer) upsertLinks(link *linkgraph.Link, dsts []*linkgraph.Link) error {
+	if err := ld.UpsertLink(link); err != nil {
+		return err
+	}
+	for _, dstLink := range dsts {
+		//insert link destination as node
+		if err := ld.UpsertLink(dstLink); err != nil {
+			return err
+		}
+	}
 	return nil
 }
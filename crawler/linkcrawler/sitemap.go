@@ -0,0 +1,200 @@
+package linkcrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/robots"
+	"github.com/odit-bit/se/crawler/sitemap"
+	"github.com/odit-bit/se/crawler/trap"
+	"github.com/odit-bit/se/graph/canonical"
+)
+
+// the number of submitted sitemaps waiting to be ingested
+const sitemapQueueSize = 16
+
+// ErrSitemapQueueFull is returned by SubmitSitemap when too many submitted
+// sitemaps wait to be ingested.
+var ErrSitemapQueueFull = errors.New("sitemap queue is full")
+
+// sitemapSource is where the URL of a sitemap comes from.
+type sitemapSource int
+
+const (
+	// a Sitemap line of robots.txt
+	advertisedSitemap sitemapSource = iota
+
+	// the default /sitemap.xml of a host, most hosts do not serve one
+	defaultSitemap
+
+	// submitted by a user, its URLs are crawled ahead of discovered links
+	submittedSitemap
+)
+
+// sitemapIngester reads the sitemaps of every crawled host at most once per
+// interval and adds the URLs they list to the link graph.
+type sitemapIngester struct {
+	client    *http.Client
+	userAgent string
+	interval  time.Duration
+	canon     *canonical.Canonicalizer
+	graph     GraphUpdater
+	scope     ScopePolicy
+	revisit   *revisitor
+	traps     *trap.Detector
+	trapStore trap.Store
+
+	// the submitted sitemaps waiting to be ingested
+	queue chan string
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	read map[string]time.Time
+}
+
+// SubmitSitemap queues a sitemap submitted by a user. Its URLs are added to
+// the link graph in the background while the service runs, subject to the
+// scope and the trap detector like discovered links. With a frontier they are
+// crawled ahead of discovered links. It fails with ErrSitemapQueueFull if too
+// many sitemaps wait to be ingested.
+func (la *CrawlService) SubmitSitemap(sitemapURL string) error {
+	u, err := la.canon.URL(sitemapURL)
+	if err != nil {
+		return fmt.Errorf("submit sitemap: %w", err)
+	}
+	return la.sitemaps.submit(u)
+}
+
+// discover reads the sitemaps advertised in the robots.txt of the host of u
+// and its /sitemap.xml in the background, unless they were read within the
+// interval.
+func (si *sitemapIngester) discover(ctx context.Context, u *url.URL, rules *robots.Robots, pass *frontierPass) {
+	origin, err := si.canon.URL(u.Scheme + "://" + u.Host)
+	if err != nil {
+		return
+	}
+
+	si.mu.Lock()
+	if last, ok := si.read[origin]; ok && time.Since(last) < si.interval {
+		si.mu.Unlock()
+		return
+	}
+	si.read[origin] = time.Now()
+	si.mu.Unlock()
+
+	fallback := origin + "sitemap.xml"
+	sitemaps := rules.Sitemaps
+	if !contains(sitemaps, fallback) {
+		sitemaps = append(sitemaps[:len(sitemaps):len(sitemaps)], fallback)
+	}
+
+	si.wg.Add(1)
+	go func() {
+		defer si.wg.Done()
+		for _, sm := range sitemaps {
+			from := advertisedSitemap
+			if sm == fallback {
+				from = defaultSitemap
+			}
+			si.ingest(ctx, origin, sm, pass, from)
+		}
+	}()
+}
+
+// wait blocks until all sitemaps being read were ingested.
+func (si *sitemapIngester) wait() {
+	si.wg.Wait()
+}
+
+// submit queues the canonical URL of a sitemap submitted by a user.
+func (si *sitemapIngester) submit(sitemapURL string) error {
+	select {
+	case si.queue <- sitemapURL:
+		return nil
+	default:
+		return ErrSitemapQueueFull
+	}
+}
+
+// runSubmitted ingests the submitted sitemaps one at a time until ctx is
+// done. Their URLs are pushed to the frontier through pass.
+func (si *sitemapIngester) runSubmitted(ctx context.Context, pass *frontierPass) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sm := <-si.queue:
+			u, err := url.Parse(sm)
+			if err != nil {
+				continue
+			}
+			si.ingest(ctx, u.Scheme+"://"+u.Host+"/", sm, pass, submittedSitemap)
+		}
+	}
+}
+
+// ingest upserts the URLs of the host listed in a sitemap. URLs with a lastmod
+// newer than their last crawl become due immediately, as do the URLs of a
+// submitted sitemap.
+func (si *sitemapIngester) ingest(ctx context.Context, origin, sitemapURL string, pass *frontierPass, from sitemapSource) {
+	entries, err := sitemap.Fetch(ctx, si.client, si.userAgent, sitemapURL)
+	if err != nil {
+		if from != defaultSitemap {
+			log.Println("sitemap:", err)
+		}
+		return
+	}
+
+	host := hostOf(origin)
+	pushed := make([]frontier.Entry, 0, len(entries))
+	for _, e := range entries {
+		loc, err := si.canon.URL(e.Loc)
+		if err != nil || hostOf(loc) != host {
+			// a sitemap may only list URLs of its own host
+			continue
+		}
+		if !si.scope.Allowed(loc, 0) || isTrap(si.traps, si.trapStore, loc) {
+			continue
+		}
+
+		link := &linkgraph.Link{URL: loc}
+		if err := si.graph.UpsertLink(link); err != nil {
+			log.Println("sitemap:", err)
+			return
+		}
+
+		entry := frontier.Entry{LinkID: link.ID, URL: link.URL, Submitted: from == submittedSitemap}
+		if si.revisit.hint(link.ID, e.LastMod) || entry.Submitted {
+			entry.DueAt = time.Now()
+		}
+		pushed = append(pushed, entry)
+	}
+
+	pass.push(pushed)
+	log.Printf("sitemap %s: %d urls", sitemapURL, len(pushed))
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
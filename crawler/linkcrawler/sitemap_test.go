@@ -0,0 +1,119 @@
+package linkcrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/odit-bit/se/crawler/scope"
+)
+
+func Test_sitemap_ingestion(t *testing.T) {
+	var srv *httptest.Server
+	var reads atomic.Int64
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow:\nSitemap: %s/map.xml\n", srv.URL)
+		case "/map.xml":
+			reads.Add(1)
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>%[1]s/a?utm_source=sitemap</loc><lastmod>2023-01-01</lastmod></url>
+				<url><loc>%[1]s/b</loc></url>
+				<url><loc>https://other.example.com/c</loc></url>
+			</urlset>`, srv.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	graph := newRecordGraph()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: fakeIndex{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	u, _ := url.Parse(srv.URL + "/")
+	rules, err := svc.robots.Lookup(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.sitemaps.discover(ctx, u, rules, nil)
+	svc.sitemaps.discover(ctx, u, rules, nil)
+	svc.sitemaps.wait()
+
+	expect := []string{srv.URL + "/a", srv.URL + "/b"}
+	if got := graph.linksExcept(minUUID); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+	if reads.Load() != 1 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", reads.Load(), 1, "sitemap read more than once per interval")
+	}
+}
+
+func Test_sitemap_submission(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+			<url><loc>%[1]s/a</loc></url>
+			<url><loc>%[1]s/private/b</loc></url>
+			<url><loc>https://other.example.com/c</loc></url>
+		</urlset>`, srv.URL)
+	}))
+	defer srv.Close()
+
+	rules, err := scope.Compile(scope.Rules{Exclude: []string{`/private/`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := newRecordGraph()
+	store := &refreshFrontier{}
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: fakeIndex{}, Frontier: store, Scope: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SubmitSitemap(srv.URL + "/map.xml"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.sitemaps.runSubmitted(ctx, svc.submittedPass())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(graph.linksExcept(minUUID)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	expect := []string{srv.URL + "/a"}
+	if got := graph.linksExcept(minUUID); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+	if len(store.pushed) != 1 || !store.pushed[0].Submitted || store.pushed[0].DueAt.IsZero() {
+		t.Fatalf("\ngot:%+v \nexpect:%v", store.pushed, "the link pushed as submitted and due")
+	}
+
+	t.Run("test_queue_full", func(t *testing.T) {
+		var err error
+		for i := 0; i <= sitemapQueueSize && err == nil; i++ {
+			err = svc.SubmitSitemap(srv.URL + "/map.xml")
+		}
+		if !errors.Is(err, ErrSitemapQueueFull) {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, ErrSitemapQueueFull)
+		}
+	})
+}
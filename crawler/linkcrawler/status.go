@@ -0,0 +1,43 @@
+package linkcrawler
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/graph/linkstatus"
+)
+
+// statusTracker records the outcome of every fetch and removes the documents
+// of pages that are dead according to the policy.
+type statusTracker struct {
+	// nil if the graph does not keep the status of links, only 410 Gone
+	// is then detected
+	graph StatusRecorder
+
+	index DocIndexer
+
+	policy linkstatus.Policy
+}
+
+// record records a fetch of linkID that ended with the HTTP status code and
+// fetchErr. It reports whether the document of the link was removed.
+func (st *statusTracker) record(linkID uuid.UUID, code int, fetchErr error) bool {
+	s := linkstatus.Status{Code: code}
+	if st.graph != nil {
+		var err error
+		s, err = st.graph.RecordFetch(linkID, code, fetchErr)
+		if err != nil {
+			log.Println("link status:", err)
+			return false
+		}
+	}
+
+	if !linkstatus.Failed(code, fetchErr) || !st.policy.Dead(s) {
+		return false
+	}
+	if err := st.index.DeleteDocument(linkID); err != nil {
+		log.Println("link status:", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package linkcrawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/graph/linkstatus"
+)
+
+// statusGraph counts the consecutive failed fetches of links.
+type statusGraph struct {
+	*recordGraph
+	failures map[uuid.UUID]int
+}
+
+func (g *statusGraph) RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if linkstatus.Failed(code, fetchErr) {
+		g.failures[linkID]++
+	} else {
+		g.failures[linkID] = 0
+	}
+	return linkstatus.Status{Code: code, Failures: g.failures[linkID]}, nil
+}
+
+func Test_dead_pages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	graph := &statusGraph{recordGraph: newRecordGraph(), failures: make(map[uuid.UUID]int)}
+	idx := newRecordIndex()
+	svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: idx, MaxFailures: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(id uuid.UUID, path string) bool {
+		p, err := svc.pages.get(context.Background(), srv.URL+path, recrawl.State{}, false)
+		return svc.status.record(id, p.status, err)
+	}
+	removed := func(id uuid.UUID) bool {
+		return idx.removed[id]
+	}
+
+	t.Run("test_gone", func(t *testing.T) {
+		id := uuid.New()
+		if !fetch(id, "/gone") || !removed(id) {
+			t.Fatal("document of a 410 page was not removed")
+		}
+	})
+
+	t.Run("test_max_failures", func(t *testing.T) {
+		id := uuid.New()
+		if fetch(id, "/missing") {
+			t.Fatal("document removed after the first failure")
+		}
+		if fetch(id, "/ok") || fetch(id, "/missing") {
+			t.Fatal("failures not reset by a successful fetch")
+		}
+		if !fetch(id, "/missing") || !removed(id) {
+			t.Fatal("document not removed after max failures")
+		}
+	})
+
+	t.Run("test_unreachable_host", func(t *testing.T) {
+		id := uuid.New()
+		p, err := svc.pages.get(context.Background(), "http://127.0.0.1:1/", recrawl.State{}, false)
+		if err == nil {
+			t.Fatal("expected a connection error")
+		}
+		svc.status.record(id, p.status, err)
+		if graph.failures[id] != 1 {
+			t.Fatalf("\ngot:%v \nexpect:%v", graph.failures[id], 1)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package linkcrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/event"
+	"github.com/odit-bit/se/crawler/frontier"
+	"github.com/odit-bit/se/crawler/politeness"
+	"github.com/odit-bit/se/crawler/submission"
+	"github.com/odit-bit/se/graph/canonical"
+)
+
+// the number of submissions claimed at once and how long they are leased
+const (
+	submissionBatchSize = 20
+	submissionLease     = 10 * time.Minute
+)
+
+// submissionLane crawls the links submitted by users ahead of the crawl
+// passes. It has its own pipeline and a lane of the host scheduler so that
+// submissions do not wait for a running pass, while a host crawled by both
+// still gets its delay and connection limit.
+type submissionLane struct {
+	store    submission.Store
+	crawler  pipeline
+	sched    *politeness.Scheduler[*linkgraph.Link]
+	interval time.Duration
+}
+
+// runSubmissions crawls the queued submissions until ctx is done.
+func (la *CrawlService) runSubmissions(ctx context.Context) {
+	ticker := time.NewTicker(la.submissions.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := la.crawlSubmissions(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Println("submissions:", err)
+		}
+		// a full batch is followed by the next one right away
+		if err == nil && n == submissionBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// crawlSubmissions crawls a batch of queued submissions and records the
+// outcome of each. It returns the number of claimed submissions, and the error
+// of ctx once it is done.
+func (la *CrawlService) crawlSubmissions(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	lane := la.submissions
+	subs, err := lane.store.Claim(submissionBatchSize, submissionLease)
+	if err != nil || len(subs) == 0 {
+		return 0, err
+	}
+
+	entries := make([]frontier.Entry, len(subs))
+	for i, s := range subs {
+		entries[i] = frontier.Entry{LinkID: s.LinkID, URL: s.URL, Submitted: true}
+	}
+
+	// the outcome of every page is followed through the events of the crawl
+	tracker := newSubmissionTracker(la.canon, subs)
+	var events EventSink = tracker
+	if la.cfg.Events != nil {
+		events = event.Multi{la.cfg.Events, tracker}
+	}
+
+	producer := la.newFetcher(ctx, &entryIterator{entries: entries}, nil)
+	producer.sched, producer.events = lane.sched, events
+	consumer := la.newConsumer(nil)
+	consumer.sched, consumer.events = lane.sched, events
+
+	err = lane.crawler.Crawl(ctx, producer, consumer)
+	producer.Close()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		// the submissions are claimed again by the next batch
+		ids := make([]uuid.UUID, len(subs))
+		for i, s := range subs {
+			ids[i] = s.ID
+		}
+		if rerr := lane.store.Requeue(ids); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		return len(subs), err
+	}
+
+	for _, s := range subs {
+		state, reason := tracker.outcome(s.LinkID)
+		if err := lane.store.Finish(s.ID, state, reason); err != nil {
+			return len(subs), err
+		}
+	}
+	log.Printf("submissions: crawled %d submitted links", len(subs))
+	return len(subs), nil
+}
+
+// submissionTracker follows the crawl of submitted links through the events
+// of the crawler.
+type submissionTracker struct {
+	canon *canonical.Canonicalizer
+
+	mu sync.Mutex
+
+	// the submitted links by URL, including the URLs they redirect to
+	links   map[string]uuid.UUID
+	results map[uuid.UUID]*submissionResult
+}
+
+type submissionResult struct {
+	fetched     bool
+	notModified bool
+	indexed     bool
+	failure     string
+}
+
+func newSubmissionTracker(canon *canonical.Canonicalizer, subs []submission.Submission) *submissionTracker {
+	t := &submissionTracker{
+		canon:   canon,
+		links:   make(map[string]uuid.UUID, len(subs)),
+		results: make(map[uuid.UUID]*submissionResult, len(subs)),
+	}
+	for _, s := range subs {
+		t.links[s.URL] = s.LinkID
+		t.results[s.LinkID] = &submissionResult{}
+	}
+	return t
+}
+
+// Publish implements EventSink.
+func (t *submissionTracker) Publish(e event.Event) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e := e.(type) {
+	case *event.PageFetched:
+		r := t.results[e.ID]
+		if r == nil {
+			return nil
+		}
+		r.fetched, r.notModified = true, e.NotModified
+		// the document of a redirected page is indexed for its final URL
+		if final, err := t.canon.URL(e.FinalURL); err == nil && e.FinalURL != "" {
+			t.links[final] = e.ID
+		}
+	case *event.FetchFailed:
+		r := t.results[e.ID]
+		if r == nil {
+			return nil
+		}
+		r.failure = e.Error
+		if e.Status != 0 {
+			r.failure = fmt.Sprintf("HTTP %d", e.Status)
+		}
+	case *event.DocumentIndexed:
+		id, ok := t.links[e.URL]
+		if !ok {
+			id = e.ID
+		}
+		if r := t.results[id]; r != nil {
+			r.indexed = true
+		}
+	}
+	return nil
+}
+
+// outcome returns the final state of the submission of linkID and the reason
+// recorded with it.
+func (t *submissionTracker) outcome(linkID uuid.UUID) (submission.State, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.results[linkID]
+	switch {
+	case r.indexed:
+		return submission.Indexed, ""
+	case r.failure != "":
+		return submission.Failed, r.failure
+	case r.notModified:
+		return submission.Unchanged, "the page did not change since its last crawl"
+	case r.fetched:
+		return submission.Failed, "the page was fetched but not indexed, e.g. because it declares noindex or duplicates another page"
+	}
+	return submission.Failed, "the link is disallowed by robots.txt or out of the crawl scope"
+}
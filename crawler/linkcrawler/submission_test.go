@@ -0,0 +1,242 @@
+package linkcrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/recrawl"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+// submissionQueue is an in-memory submission.Store.
+type submissionQueue struct {
+	mu   sync.Mutex
+	subs []*submission.Submission
+}
+
+func (q *submissionQueue) Submit(linkID uuid.UUID, url string) (*submission.Submission, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := &submission.Submission{ID: uuid.New(), LinkID: linkID, URL: url, State: submission.Queued, SubmittedAt: time.Now()}
+	q.subs = append(q.subs, s)
+	return s, nil
+}
+
+func (q *submissionQueue) Submission(id uuid.UUID) (*submission.Submission, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.subs {
+		if s.ID == id {
+			c := *s
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *submissionQueue) Claim(n int, lease time.Duration) ([]submission.Submission, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var claimed []submission.Submission
+	for _, s := range q.subs {
+		if s.State == submission.Queued && len(claimed) < n {
+			s.State = submission.Fetching
+			claimed = append(claimed, *s)
+		}
+	}
+	return claimed, nil
+}
+
+func (q *submissionQueue) Finish(id uuid.UUID, state submission.State, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.subs {
+		if s.ID == id {
+			s.State, s.Reason = state, reason
+		}
+	}
+	return nil
+}
+
+func (q *submissionQueue) Requeue(ids []uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.subs {
+		for _, id := range ids {
+			if s.ID == id && s.State == submission.Fetching {
+				s.State = submission.Queued
+			}
+		}
+	}
+	return nil
+}
+
+func Test_submissions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new", "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>page</title></head></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	graph := newRecordGraph()
+	idx := newRecordIndex()
+	queue := &submissionQueue{}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:     graph,
+		IndexAPI:     idx,
+		Submissions:  queue,
+		HostMinDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]submission.State{
+		"/page":    submission.Indexed,
+		"/old":     submission.Indexed,
+		"/missing": submission.Failed,
+		"/private": submission.Failed,
+	}
+	ids := make(map[string]uuid.UUID)
+	for path := range expect {
+		link := &linkgraph.Link{URL: srv.URL + path}
+		if err := graph.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		s, err := queue.Submit(link.ID, link.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[path] = s.ID
+	}
+
+	n, err := svc.crawlSubmissions(context.Background())
+	if err != nil || n != len(expect) {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", n, err, len(expect))
+	}
+	for path, state := range expect {
+		s, _ := queue.Submission(ids[path])
+		if s.State != state {
+			t.Fatalf("\ngot:%v %v \nexpect:%v \nmessage:%v", s.State, s.Reason, state, path)
+		}
+	}
+	if s, _ := queue.Submission(ids["/missing"]); s.Reason != "HTTP 404" {
+		t.Fatalf("\ngot:%v \nexpect:%v", s.Reason, "HTTP 404")
+	}
+	if _, ok := idx.docs[graph.ids[srv.URL+"/page"]]; !ok {
+		t.Fatal("submitted page not indexed")
+	}
+
+	// the queue is drained
+	if n, err := svc.crawlSubmissions(context.Background()); err != nil || n != 0 {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", n, err, 0)
+	}
+}
+
+func Test_submissions_shutdown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>page</title></head></html>"))
+	}))
+	defer srv.Close()
+
+	queue := &submissionQueue{}
+	svc, err := NewWithConfig(Config{GraphAPI: newRecordGraph(), IndexAPI: newRecordIndex(), Submissions: queue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3*submissionBatchSize; i++ {
+		if _, err := queue.Submit(uuid.New(), fmt.Sprintf("%s/%d", srv.URL, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.runSubmissions(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("submission lane did not stop")
+	}
+
+	for _, s := range queue.subs {
+		if s.State != submission.Queued {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", s.State, submission.Queued, "submission claimed after shutdown")
+		}
+	}
+}
+
+func Test_submission_outcomes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>page</title></head><body>text</body></html>"))
+	}))
+	defer srv.Close()
+
+	t.Run("test_not_modified", func(t *testing.T) {
+		graph, queue := newRecordGraph(), &submissionQueue{}
+		link := &linkgraph.Link{URL: srv.URL + "/static"}
+		if err := graph.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		states := &memStateStore{states: map[uuid.UUID]recrawl.State{link.ID: {ETag: `"v1"`}}}
+		svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: newRecordIndex(), Recrawl: states, Submissions: queue})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, _ := queue.Submit(link.ID, link.URL)
+
+		if _, err := svc.crawlSubmissions(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if s, _ := queue.Submission(sub.ID); s.State != submission.Unchanged {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.State, submission.Unchanged)
+		}
+	})
+
+	t.Run("test_crawl_error", func(t *testing.T) {
+		graph, queue := newRecordGraph(), &submissionQueue{}
+		svc, err := NewWithConfig(Config{GraphAPI: graph, IndexAPI: &failIndex{}, Submissions: queue})
+		if err != nil {
+			t.Fatal(err)
+		}
+		link := &linkgraph.Link{URL: srv.URL + "/page"}
+		if err := graph.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		sub, _ := queue.Submit(link.ID, link.URL)
+
+		if _, err := svc.crawlSubmissions(context.Background()); err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "the error of the index")
+		}
+		if s, _ := queue.Submission(sub.ID); s.State != submission.Queued {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", s.State, submission.Queued, "claim of a failed crawl not released")
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package linkcrawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/extract"
+	"github.com/odit-bit/se/crawler/pageinfo"
+	"github.com/odit-bit/se/crawler/trap"
+	"github.com/odit-bit/webcrawler"
+)
+
+// trapStore records the flagged hosts.
+type trapStore struct {
+	flags []trap.Flag
+}
+
+func (s *trapStore) FlagHost(f trap.Flag) error {
+	s.flags = append(s.flags, f)
+	return nil
+}
+
+func (s *trapStore) FlaggedHosts() ([]trap.Flag, error) {
+	return s.flags, nil
+}
+
+func Test_traps(t *testing.T) {
+	graph := newRecordGraph()
+	store := &trapStore{}
+	svc, err := NewWithConfig(Config{
+		GraphAPI:  graph,
+		IndexAPI:  newRecordIndex(),
+		Traps:     trap.Config{MaxPatternCardinality: 3, ThrottledURLs: 1},
+		TrapStore: store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := &linkgraph.Link{URL: "https://a.com/calendar"}
+	if err := graph.UpsertLink(link); err != nil {
+		t.Fatal(err)
+	}
+	r := webcrawler.NewResource()
+	r.ID = link.ID
+	r.URL = link.URL
+	doc := &extract.Document{}
+	for day := 1; day <= 5; day++ {
+		doc.Links = append(doc.Links, fmt.Sprintf("https://a.com/calendar/2024/05/%02d", day))
+	}
+	doc.Links = append(doc.Links, "https://a.com/about", "https://a.com/contact", "https://a.com/a/b/a/b/a")
+
+	consumer := svc.newConsumer(nil)
+	if err := consumer.upsertResource(r, &page{info: &pageinfo.Info{}, doc: doc}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the trap pattern is cut off and the flagged host only adds one more
+	// link
+	expect := []string{
+		"https://a.com/about",
+		"https://a.com/calendar/2024/05/01",
+		"https://a.com/calendar/2024/05/02",
+		"https://a.com/calendar/2024/05/03",
+	}
+	if got := graph.outlinks(link.ID); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+	if got := consumer.trapped.Load(); got != 4 {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, 4)
+	}
+	if len(store.flags) != 1 || store.flags[0].Host != "a.com" || store.flags[0].Reason != trap.URLPattern {
+		t.Fatalf("\ngot:%v \nexpect:%v", store.flags, trap.URLPattern)
+	}
+
+	// a restarted crawler keeps the host flagged
+	restarted, err := NewWithConfig(Config{
+		GraphAPI:  graph,
+		IndexAPI:  &fakeIndex{},
+		Traps:     trap.Config{MaxPatternCardinality: 3, ThrottledURLs: 1},
+		TrapStore: store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags := restarted.traps.Flagged(); len(flags) != 1 || flags[0].Host != "a.com" {
+		t.Fatalf("\ngot:%v \nmessage:%v", flags, "flagged host not restored")
+	}
+
+	// the links already in the graph are not throttled, the page keeps its
+	// edges to them and the host adds one more new link
+	if err := restarted.knownLinks(); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.newConsumer(nil).upsertResource(r, &page{info: &pageinfo.Info{}, doc: doc}); err != nil {
+		t.Fatal(err)
+	}
+	expect = append(expect, "https://a.com/contact")
+	if got := graph.outlinks(link.ID); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+}
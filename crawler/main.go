@@ -2,40 +2,198 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/odit-bit/indexstore"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/linkstore"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/crawlpostgre"
+	"github.com/odit-bit/se/crawler/event"
 	"github.com/odit-bit/se/crawler/linkcrawler"
+	"github.com/odit-bit/se/crawler/scope"
+	"github.com/odit-bit/se/crawler/submission"
+	"github.com/odit-bit/se/crawler/submissionapi"
+	"github.com/odit-bit/se/crawler/warc"
+	"github.com/odit-bit/se/graph/graphapi"
+	"github.com/odit-bit/se/index/indexapi"
+	"github.com/odit-bit/se/pagerank/partition"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// the size after which a new WARC file is started
+const archiveMaxSize = 1 << 30
+
 func main() {
+	// `crawler replay FILE...` and `crawler debug URL` run a single command
+	// instead of crawling
+	var command string
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
 	linkstoreAddress := os.Getenv("LINKSTORE_SERVER_ADDRESS")
-	indexstoreAddress := os.Getenv("INDEXSTORE_SERVER_ADDRESS")
-	if linkstoreAddress == "" || indexstoreAddress == "" {
+	if linkstoreAddress == "" {
 		log.Fatal("grpc server address is nil")
 	}
+	indexapiAddress := os.Getenv("INDEXAPI_SERVER_ADDRESS")
+	if indexapiAddress == "" {
+		log.Fatal("index api address is nil")
+	}
+	graphapiAddress := os.Getenv("GRAPHAPI_SERVER_ADDRESS")
+	if graphapiAddress == "" {
+		log.Fatal("graph api address is nil")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	graphAPI, err := linkstore.ConnectGraph(linkstoreAddress)
+	linkGraph, err := linkstore.ConnectGraph(linkstoreAddress)
 	if err != nil {
 		log.Fatal("failed connect to graph server")
 	}
 
-	indexAPI, err := indexstore.ConnectIndex(indexstoreAddress)
-	if err != nil {
-		log.Fatal("failed connect to index server")
+	// links and edges are written through the gRPC service, the anchors of
+	// the edges, redirects and fetch statuses through the HTTP API of the
+	// graph service.
+	graphAPI := graphClient{Graph: linkGraph, Client: graphapi.NewClient(graphapiAddress)}
+
+	// the documents are written through the HTTP API of the index service,
+	// the gRPC service only knows their text. The PageRank scores the
+	// frontier is prioritized by are read through it as well.
+	indexAPI := indexapi.NewClient(indexapiAddress)
+	conf := linkcrawler.Config{
+		GraphAPI:  graphAPI,
+		IndexAPI:  indexAPI,
+		Pageranks: indexAPI,
+	}
+
+	// replicas split the link space between them using the SRV records of
+	// the crawler service (e.g. a kubernetes headless service).
+	if srvName := os.Getenv("PARTITION_SRV_NAME"); srvName != "" {
+		conf.PartitionDetector = partition.DetectFromSRVRecords(srvName)
+	}
+
+	// crawl events are published to every configured sink
+	var events event.Multi
+
+	// the queue of links submitted through the UI
+	var submissions submission.Store
+
+	// the crawler keeps its own state (frontier, pass checkpoints) in its own tables,
+	// without a database it falls back to scanning the graph every pass.
+	if dsn := os.Getenv("DSN"); dsn != "" {
+		db, err := sqlx.Connect("pgx", dsn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		store, err := crawlpostgre.New(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		conf.Frontier = store
+		conf.Recrawl = store
+		conf.Reports = store
+		conf.Checkpoints = store
+		conf.TrapStore = store
+		conf.Submissions = store
+		submissions = store
+
+		// the outbox is only filled when something relays it, see
+		// crawlpostgre UnpublishedEvents
+		if os.Getenv("EVENTS_OUTBOX") == "true" {
+			events = append(events, store)
+		}
+	}
+
+	// the scope rules file is reloaded while the crawler runs, links that
+	// fell out of scope are skipped from the next pass on.
+	if path := os.Getenv("SCOPE_FILE"); path != "" {
+		rules, err := scope.Watch(ctx, path, 10*time.Second)
+		if err != nil {
+			log.Fatal(err)
+		}
+		conf.Scope = rules
+	}
+
+	// every response of the crawler's own requests is archived to rotating
+	// WARC files, they can be replayed with `crawler replay FILE...`. Only a
+	// crawl is archived, not the requests of a command.
+	if dir := os.Getenv("ARCHIVE_DIR"); dir != "" && command == "" {
+		archive, err := warc.NewWriter(dir, "se-crawler", archiveMaxSize, "se-crawler")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer archive.Close()
+		conf.Archive = archive
+	}
+
+	// crawl events are appended to a JSON-lines file, e.g. for a log shipper
+	if path := os.Getenv("EVENTS_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		events = append(events, event.NewJSONLines(f))
+	}
+	if len(events) > 0 {
+		conf.Events = events
 	}
 
 	// crawler service
-	cr := linkcrawler.New(graphAPI, indexAPI)
+	cr, err := linkcrawler.NewWithConfig(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "replay":
+		if err := replay(cr, os.Args[2:]); err != nil {
+			log.Println(err)
+		}
+		return
+	case "debug":
+		debug(ctx, cr, os.Args[2:])
+		return
+	}
+	// metrics endpoint
+	metricsAddress := os.Getenv("METRICS_ADDRESS")
+	if metricsAddress == "" {
+		metricsAddress = ":8282"
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(metricsAddress, mux); err != nil {
+			log.Println("metrics server:", err)
+		}
+	}()
+
+	// the UI queues submitted links and sitemaps through the HTTP API of
+	// the crawler
+	if submissions != nil {
+		apiAddress := os.Getenv("SUBMISSIONAPI_ADDRESS")
+		if apiAddress == "" {
+			apiAddress = ":8585"
+		}
+		go func() {
+			if err := http.ListenAndServe(apiAddress, submissionapi.NewHandler(submissionQueue{Store: submissions, CrawlService: cr})); err != nil {
+				log.Println("submission api server:", err)
+			}
+		}()
+	}
+
 	sigC := make(chan os.Signal, 1)
-	signal.Notify(sigC, syscall.SIGINT)
+	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-sigC
@@ -49,3 +207,112 @@ func main() {
 	log.Println("[crawler service exit]")
 
 }
+
+// graphClient is the graph service, the gRPC service along with the HTTP API
+// of what it does not know.
+type graphClient struct {
+	linkgraph.Graph
+	*graphapi.Client
+}
+
+// submissionQueue is the queue of the links submitted through the UI along
+// with the crawler that ingests the submitted sitemaps.
+type submissionQueue struct {
+	submission.Store
+	*linkcrawler.CrawlService
+}
+
+// replay rebuilds the graph and the index from the given WARC files, it stops
+// at the first file that fails.
+func replay(cr *linkcrawler.CrawlService, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("usage: crawler replay FILE...")
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		n, err := cr.Replay(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("replay %s: %v", name, err)
+		}
+		log.Printf("replayed %d pages from %s", n, name)
+	}
+	return nil
+}
+
+// debug runs a single URL through the crawler and prints what it makes of
+// the page, it is only written with --commit.
+func debug(ctx context.Context, cr *linkcrawler.CrawlService, args []string) {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	commit := fs.Bool("commit", false, "write the page to the graph and the index")
+	usage := "usage: crawler debug URL [--commit]"
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal(usage)
+	}
+	// the flag may also follow the URL
+	rawURL := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		log.Fatal(usage)
+	}
+
+	res, err := cr.Debug(ctx, rawURL, *commit)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("url:        %s\n", res.URL)
+	fmt.Printf("in scope:   %v\n", res.InScope)
+	fmt.Printf("robots:     %v\n", allowed(res.RobotsAllowed))
+	if res.Status == 0 && res.FetchErr == nil {
+		return
+	}
+	fmt.Printf("status:     %d\n", res.Status)
+	if res.FetchErr != nil {
+		fmt.Printf("error:      %v\n", res.FetchErr)
+	}
+	if res.FinalURL != "" {
+		fmt.Printf("final url:  %s\n", res.FinalURL)
+	}
+	fmt.Printf("media type: %s\n", res.MediaType)
+	fmt.Printf("noindex:    %v\n", res.NoIndex)
+	fmt.Printf("nofollow:   %v\n", res.NoFollow)
+	fmt.Printf("title:      %s\n", res.Title)
+	fmt.Printf("content:    %d bytes\n", res.ContentLength)
+
+	outlinks := res.Outlinks()
+	fmt.Printf("outlinks:   %d\n", len(outlinks))
+	for _, l := range outlinks {
+		fmt.Printf("  %s\n", l)
+	}
+
+	written := "would be written"
+	if res.Committed {
+		written = "written"
+	}
+	fmt.Printf("graph (%s):\n", written)
+	for _, l := range res.Links {
+		fmt.Printf("  link %s\n", l)
+	}
+	for _, e := range res.Edges {
+		fmt.Printf("  edge %s -> %s\n", e[0], e[1])
+	}
+	fmt.Printf("index (%s):\n", written)
+	if len(res.Documents) == 0 {
+		fmt.Println("  not indexed")
+	}
+	for _, doc := range res.Documents {
+		fmt.Printf("  document %s %q (%d bytes)\n", doc.URL, doc.Title, len(doc.Content))
+	}
+}
+
+func allowed(ok bool) string {
+	if ok {
+		return "allowed"
+	}
+	return "disallowed"
+}
@@ -0,0 +1,288 @@
+// Package pageinfo extracts crawl signals from the markup of an HTML page
+// that are not part of the text content, such as <link rel=canonical>, the
+// anchor text of links, the metadata declared in <meta> tags and the
+// schema.org entities of JSON-LD and microdata blocks.
+package pageinfo
+
+import (
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/odit-bit/se/index/docmeta"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// the maximum length of the anchor text kept for a link, in bytes
+const maxAnchorText = 256
+
+// the maximum length of a heading and of a metadata value, in bytes
+const (
+	maxHeading   = 256
+	maxMetaValue = 1024
+)
+
+// the maximum number of headings kept for a page
+const maxHeadings = 32
+
+// the date formats of the publication and modification dates
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Info holds the signals found in a page.
+type Info struct {
+	// The href of the first <link rel=canonical> in the document head, as
+	// written in the page. Empty if the page declares none.
+	Canonical string
+
+	// The <a href> links of the document body in order of appearance.
+	Links []Link
+
+	// The metadata declared by the page, the favicon as written in the
+	// page.
+	Meta docmeta.Metadata
+
+	// The content of the <meta name=robots> tags, e.g. "noindex, nofollow".
+	Robots []string
+}
+
+// Link is a link found in the document body.
+type Link struct {
+	// The href as written in the page.
+	Href string
+
+	// The text of the link with collapsed whitespace, the alt text of
+	// images inside the link included.
+	Text string
+
+	// The rel attribute of the link, e.g. "nofollow ugc".
+	Rel string
+}
+
+// Parse reads an HTML document and returns the signals found in it.
+func Parse(r io.Reader) (*Info, error) {
+	info := &Info{}
+	inBody := false
+
+	// the link being read and its text
+	var link *Link
+	var text strings.Builder
+	closeLink := func() {
+		if link == nil {
+			return
+		}
+		link.Text = truncate(strings.Join(strings.Fields(text.String()), " "), maxAnchorText)
+		info.Links = append(info.Links, *link)
+		link = nil
+		text.Reset()
+	}
+
+	// the text of the heading being read, nil outside of a heading
+	var heading *strings.Builder
+	closeHeading := func() {
+		if heading == nil {
+			return
+		}
+		if h := truncate(strings.Join(strings.Fields(heading.String()), " "), maxHeading); h != "" && len(info.Meta.Headings) < maxHeadings {
+			info.Meta.Headings = append(info.Meta.Headings, h)
+		}
+		heading = nil
+	}
+
+	// the JSON-LD block being read, nil outside of one
+	var jsonLD []byte
+	md := &microdata{}
+
+	z := html.NewTokenizer(r)
+	for {
+		switch tt := z.Next(); tt {
+		case html.ErrorToken:
+			closeLink()
+			closeHeading()
+			md.close()
+			info.addEntities(md.items...)
+			if z.Err() == io.EOF {
+				return info, nil
+			}
+			return info, z.Err()
+
+		case html.TextToken:
+			// the text can only be read once
+			b := z.Text()
+			if link != nil && text.Len() <= maxAnchorText {
+				text.Write(b)
+				text.WriteByte(' ')
+			}
+			if heading != nil && heading.Len() <= maxHeading {
+				heading.Write(b)
+				heading.WriteByte(' ')
+			}
+			if jsonLD != nil {
+				jsonLD = append(jsonLD, b...)
+				// the block is skipped once it is too large
+				if len(jsonLD) > maxJSONLD {
+					jsonLD = nil
+				}
+			}
+			md.text(b)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			md.end(string(name))
+			switch atom.Lookup(name) {
+			case atom.Script:
+				if jsonLD != nil {
+					info.addEntities(parseJSONLD(jsonLD)...)
+				}
+				jsonLD = nil
+			case atom.A:
+				closeLink()
+			case atom.H1, atom.H2, atom.H3:
+				closeHeading()
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			md.start(t, tt == html.SelfClosingTagToken)
+			switch t.DataAtom {
+			case atom.Script:
+				if strings.EqualFold(strings.TrimSpace(attr(t, "type")), "application/ld+json") {
+					jsonLD = []byte{}
+				}
+			case atom.Link:
+				// the canonical link is only valid in the head
+				if !inBody && info.Canonical == "" && hasToken(attr(t, "rel"), "canonical") {
+					info.Canonical = strings.TrimSpace(attr(t, "href"))
+				}
+				if info.Meta.Favicon == "" && hasToken(attr(t, "rel"), "icon") {
+					info.Meta.Favicon = strings.TrimSpace(attr(t, "href"))
+				}
+			case atom.Meta:
+				info.addMeta(t)
+			case atom.H1, atom.H2, atom.H3:
+				// headings can not be nested either
+				closeHeading()
+				heading = &strings.Builder{}
+			case atom.Body:
+				inBody = true
+			case atom.A:
+				// links can not be nested, a new one ends the previous
+				closeLink()
+				if href := strings.TrimSpace(attr(t, "href")); href != "" {
+					link = &Link{Href: href, Rel: strings.Join(strings.Fields(attr(t, "rel")), " ")}
+				}
+			case atom.Img:
+				if link != nil {
+					text.WriteString(attr(t, "alt"))
+					text.WriteByte(' ')
+				}
+			}
+		}
+	}
+}
+
+// addMeta records the value of a <meta> tag. The first value of every field
+// is kept.
+func (info *Info) addMeta(t html.Token) {
+	key := attr(t, "property")
+	if key == "" {
+		key = attr(t, "name")
+	}
+	if key == "" {
+		key = attr(t, "itemprop")
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	value := truncate(strings.Join(strings.Fields(attr(t, "content")), " "), maxMetaValue)
+	if key == "" || value == "" {
+		return
+	}
+
+	m := &info.Meta
+	switch key {
+	case "robots":
+		info.Robots = append(info.Robots, value)
+	case "description":
+		if m.Description == "" {
+			m.Description = value
+		}
+	case "author", "article:author":
+		if m.Author == "" {
+			m.Author = value
+		}
+	case "article:published_time", "datepublished", "date", "dc.date", "dcterms.created":
+		if m.Published.IsZero() {
+			m.Published = parseDate(value)
+		}
+	case "article:modified_time", "og:updated_time", "datemodified", "dcterms.modified":
+		if m.Modified.IsZero() {
+			m.Modified = parseDate(value)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(key, "og:"):
+		m.OpenGraph = addField(m.OpenGraph, key[len("og:"):], value)
+	case strings.HasPrefix(key, "twitter:"):
+		m.Twitter = addField(m.Twitter, key[len("twitter:"):], value)
+	}
+}
+
+// addField adds a field to fields unless it is already set.
+func addField(fields map[string]string, key, value string) map[string]string {
+	if fields == nil {
+		fields = make(map[string]string)
+	}
+	if _, ok := fields[key]; !ok && key != "" {
+		fields[key] = value
+	}
+	return fields
+}
+
+// parseDate parses a date in one of the dateLayouts, it returns the zero
+// time if the date can not be parsed.
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func attr(t html.Token, key string) string {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether the space separated list contains token.
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package pageinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func Test_parse_canonical(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		expect string
+	}{
+		{
+			name:   "test_head_link",
+			doc:    `<html><head><title>x</title><link rel="canonical" href=" /a/b "></head><body></body></html>`,
+			expect: "/a/b",
+		},
+		{
+			name:   "test_multiple_rel_values",
+			doc:    `<head><link rel="Canonical alternate" href="https://example.com/"/></head>`,
+			expect: "https://example.com/",
+		},
+		{
+			name:   "test_first_wins",
+			doc:    `<head><link rel=canonical href=/first><link rel=canonical href=/second></head>`,
+			expect: "/first",
+		},
+		{
+			name:   "test_ignored_in_body",
+			doc:    `<head></head><body><link rel=canonical href=/body></body>`,
+			expect: "",
+		},
+		{
+			name:   "test_other_rel",
+			doc:    `<head><link rel=stylesheet href=/style.css></head>`,
+			expect: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Canonical != tt.expect {
+				t.Fatalf("\ngot:%v \nexpect:%v", info.Canonical, tt.expect)
+			}
+		})
+	}
+}
+
+func Test_parse_links(t *testing.T) {
+	doc := `<html><head><link rel=canonical href=/c></head><body>
+		<a href="/a" rel="nofollow  ugc">  Search
+			<b>engine</b> </a>
+		<a href="/logo"><img src=logo.png alt="Home page"></a>
+		<a name="top">not a link</a>
+		<a href="/b">open<a href="/c">next</a>
+		<link rel=canonical href=/body>
+	</body></html>`
+
+	info, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Link{
+		{Href: "/a", Text: "Search engine", Rel: "nofollow ugc"},
+		{Href: "/logo", Text: "Home page"},
+		{Href: "/b", Text: "open"},
+		{Href: "/c", Text: "next"},
+	}
+	if fmt.Sprint(info.Links) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", info.Links, expect)
+	}
+	if info.Canonical != "/c" {
+		t.Fatalf("\ngot:%v \nexpect:%v", info.Canonical, "/c")
+	}
+
+	t.Run("test_long_anchor_text", func(t *testing.T) {
+		info, err := Parse(strings.NewReader(`<a href=/x>` + strings.Repeat("é", maxAnchorText) + `</a>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := info.Links[0].Text
+		if len(text) > maxAnchorText || !utf8.ValidString(text) {
+			t.Fatalf("\ngot:%v \nmessage:%v", len(text), "anchor text not truncated")
+		}
+	})
+}
+
+func Test_parse_meta(t *testing.T) {
+	doc := `<html><head>
+		<title>x</title>
+		<meta charset="utf-8">
+		<meta name="Description" content="  A page
+			about search ">
+		<meta name="description" content="second">
+		<meta property="og:title" content="OG title">
+		<meta property="og:image" content="/cover.png">
+		<meta name="twitter:card" content="summary">
+		<meta property="article:published_time" content="2023-11-02T10:00:00+02:00">
+		<meta name="dcterms.modified" content="2023-11-05">
+		<meta name="author" content="Jo Doe">
+		<link rel="shortcut icon" href="/favicon.ico">
+		<meta name="robots" content="noindex">
+	</head><body>
+		<h1>Search <a href="/engines">engines</a></h1>
+		<h2>  How they work </h2>
+		<h4>not kept</h4>
+		<h3></h3>
+	</body></html>`
+
+	info, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := info.Meta
+	if m.Description != "A page about search" || m.Author != "Jo Doe" || m.Favicon != "/favicon.ico" {
+		t.Fatalf("\ngot:%+v", m)
+	}
+	if fmt.Sprint(m.Headings) != "[Search engines How they work]" {
+		t.Fatalf("\ngot:%v \nexpect:%v", m.Headings, "[Search engines How they work]")
+	}
+	if m.OpenGraph["title"] != "OG title" || m.OpenGraph["image"] != "/cover.png" || m.Twitter["card"] != "summary" {
+		t.Fatalf("\ngot:%v %v", m.OpenGraph, m.Twitter)
+	}
+	if m.Published.Format(time.RFC3339) != "2023-11-02T08:00:00Z" || m.Modified.Format("2006-01-02") != "2023-11-05" {
+		t.Fatalf("\ngot:%v %v", m.Published, m.Modified)
+	}
+	if fmt.Sprint(info.Robots) != "[noindex]" {
+		t.Fatalf("\ngot:%v \nexpect:%v", info.Robots, "[noindex]")
+	}
+	if len(info.Links) != 1 || info.Links[0].Text != "engines" {
+		t.Fatalf("\ngot:%v", info.Links)
+	}
+
+	t.Run("test_invalid_date", func(t *testing.T) {
+		info, err := Parse(strings.NewReader(`<meta name="date" content="last tuesday">`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Meta.Published.IsZero() {
+			t.Fatalf("\ngot:%v", info.Meta.Published)
+		}
+	})
+}
+
+func Test_parse_entities(t *testing.T) {
+	doc := `<html><head>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@type": "Article",
+			"headline": "  How search  engines work ",
+			"author": {"@type": "Person", "name": "Jo Doe"},
+			"datePublished": {"@value": "2023-11-02", "@type": "Date"}
+		}
+		</script>
+		<script type="application/ld+json">
+		{"@context": "https://schema.org", "@graph": [
+			{"@type": "http://schema.org/BreadcrumbList", "@id": "#crumbs"},
+			{"@type": ["FAQPage"], "mainEntity": [{"@type": "Question", "name": "Why?"}]}
+		]}
+		</script>
+		<script type="application/ld+json">{ not json </script>
+		<script>{"@type": "Article"}</script>
+	</head><body>
+		<div itemscope itemtype="https://schema.org/Product">
+			<h2 itemprop="name">  Kettle <b>2000</b></h2>
+			<img itemprop="image" src="/kettle.png">
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<meta itemprop="priceCurrency" content="EUR">
+				<span itemprop="price" content="19.90">19,90 €</span>
+				<div><p>in stock</p></div>
+			</div>
+			<span itemprop="color">red</span> <span itemprop="color">blue</span>
+		</div>
+		<p itemprop="name">not in an item</p>
+		<div itemscope><span itemprop="name">untyped</span></div>
+	</body></html>`
+
+	info, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		`{"@type":"Article","author":{"@type":"Person","name":"Jo Doe"},"datePublished":"2023-11-02","headline":"How search engines work"}`,
+		`{"@type":"BreadcrumbList"}`,
+		`{"@type":"FAQPage","mainEntity":[{"@type":"Question","name":"Why?"}]}`,
+		`{"@type":"Product","color":["red","blue"],"image":"/kettle.png","name":"Kettle 2000","offers":{"@type":"Offer","price":"19.90","priceCurrency":"EUR"}}`,
+	}
+	var got []string
+	for _, e := range info.Meta.Entities {
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(b))
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+
+	product := info.Meta.Entities[3]
+	if !product.Is("product") || product.Property("offers").Text("price") != "19.90" {
+		t.Fatalf("\ngot:%v", product)
+	}
+
+	t.Run("test_max_entities", func(t *testing.T) {
+		doc := strings.Repeat(`<div itemscope itemtype="https://schema.org/Thing"></div>`, maxEntities+1)
+		info, err := Parse(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(info.Meta.Entities) != maxEntities {
+			t.Fatalf("\ngot:%v \nexpect:%v", len(info.Meta.Entities), maxEntities)
+		}
+	})
+}
@@ -0,0 +1,348 @@
+package pageinfo
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/odit-bit/se/index/docmeta"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// the maximum number of entities kept for a page
+const maxEntities = 16
+
+// the maximum size of a JSON-LD block, larger blocks are skipped
+const maxJSONLD = 256 << 10
+
+// the maximum nesting of the entities and values of a JSON-LD block
+const maxEntityDepth = 8
+
+// the prefixes of the schema.org vocabulary, removed from types and property
+// names
+var schemaPrefixes = []string{"https://schema.org/", "http://schema.org/", "schema:"}
+
+// addEntities adds entities to the metadata of the page up to maxEntities.
+func (info *Info) addEntities(entities ...docmeta.Entity) {
+	for _, e := range entities {
+		if len(info.Meta.Entities) >= maxEntities {
+			return
+		}
+		if len(e.Types()) > 0 {
+			info.Meta.Entities = append(info.Meta.Entities, e)
+		}
+	}
+}
+
+// parseJSONLD returns the entities of a <script type="application/ld+json">
+// block, nil if the block is not valid JSON. A block holds an entity, a list
+// of entities or a @graph of entities.
+func parseJSONLD(b []byte) []docmeta.Entity {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil
+	}
+	return graphOf(v, 0)
+}
+
+func graphOf(v any, depth int) []docmeta.Entity {
+	if depth > 1 {
+		return nil
+	}
+	switch v := v.(type) {
+	case []any:
+		var entities []docmeta.Entity
+		for _, item := range v {
+			entities = append(entities, graphOf(item, depth+1)...)
+		}
+		return entities
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			return graphOf(graph, depth)
+		}
+		return []docmeta.Entity{normalizeEntity(v, 0)}
+	}
+	return nil
+}
+
+// normalizeEntity returns the entity of a JSON-LD node without @context and
+// with the schema.org prefixes removed from its type and property names.
+func normalizeEntity(node map[string]any, depth int) docmeta.Entity {
+	e := make(docmeta.Entity, len(node))
+	for key, v := range node {
+		switch key {
+		case "@context", "@id":
+			continue
+		case "@type":
+			if t := normalizeType(v); t != nil {
+				e["@type"] = t
+			}
+			continue
+		}
+		if key = stripSchema(key); key == "" || strings.HasPrefix(key, "@") {
+			continue
+		}
+		if v = normalizeValue(v, depth+1); v != nil {
+			e[key] = v
+		}
+	}
+	return e
+}
+
+func normalizeValue(v any, depth int) any {
+	if depth > maxEntityDepth {
+		return nil
+	}
+	switch v := v.(type) {
+	case string:
+		if v = truncate(strings.Join(strings.Fields(v), " "), maxMetaValue); v != "" {
+			return v
+		}
+		return nil
+	case []any:
+		values := make([]any, 0, len(v))
+		for _, item := range v {
+			if item = normalizeValue(item, depth); item != nil {
+				values = append(values, item)
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		return values
+	case map[string]any:
+		// a value object, e.g. {"@value": "2024-01-01", "@type": "Date"}
+		if value, ok := v["@value"]; ok {
+			return normalizeValue(value, depth)
+		}
+		if e := normalizeEntity(v, depth); len(e) > 0 {
+			return map[string]any(e)
+		}
+		return nil
+	}
+	return v
+}
+
+func normalizeType(v any) any {
+	switch v := v.(type) {
+	case string:
+		if t := stripSchema(v); t != "" {
+			return t
+		}
+	case []any:
+		types := make([]any, 0, len(v))
+		for _, t := range v {
+			if t, ok := normalizeType(t).(string); ok {
+				types = append(types, t)
+			}
+		}
+		switch len(types) {
+		case 0:
+		case 1:
+			return types[0]
+		default:
+			return types
+		}
+	}
+	return nil
+}
+
+func stripSchema(s string) string {
+	s = strings.TrimSpace(s)
+	for _, prefix := range schemaPrefixes {
+		if len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return s[len(prefix):]
+		}
+	}
+	return s
+}
+
+// microdata reads the itemscope items of a page. The elements inside of an
+// item are tracked so that the end of the item and of its properties are
+// found.
+type microdata struct {
+	stack []*itemElement
+
+	// the top-level items in order of their end
+	items []docmeta.Entity
+}
+
+// itemElement is an element inside of an item.
+type itemElement struct {
+	tag string
+
+	// the item started by the element, nil if it has no itemscope
+	item docmeta.Entity
+
+	// the itemprop names of the element, its value is added to the item
+	// around it
+	props []string
+
+	// the text of the element, nil unless its value is its text
+	text *strings.Builder
+}
+
+func (m *microdata) start(t html.Token, selfClosing bool) {
+	props := strings.Fields(attr(t, "itemprop"))
+	el := &itemElement{tag: t.Data, props: props}
+
+	switch {
+	case hasAttr(t, "itemscope"):
+		el.item = docmeta.Entity{}
+		var types []any
+		for _, typ := range strings.Fields(attr(t, "itemtype")) {
+			if typ = stripSchema(typ); typ != "" {
+				types = append(types, typ)
+			}
+		}
+		if t := normalizeType(types); t != nil {
+			el.item["@type"] = t
+		}
+	case len(props) > 0:
+		if value, ok := attrValue(t); ok {
+			if value != "" {
+				m.addProp(props, value)
+			}
+			el.props = nil
+		} else {
+			el.text = &strings.Builder{}
+		}
+	case len(m.stack) == 0:
+		// nothing to track outside of items
+		return
+	}
+
+	m.stack = append(m.stack, el)
+	if selfClosing || isVoid(t.DataAtom) {
+		m.closeTop()
+	}
+}
+
+func (m *microdata) text(b []byte) {
+	for _, el := range m.stack {
+		if el.text != nil && el.text.Len() <= maxMetaValue {
+			el.text.Write(b)
+			el.text.WriteByte(' ')
+		}
+	}
+}
+
+// end closes the element of tag and the elements inside of it that were not
+// closed. An end tag without a start tag is ignored.
+func (m *microdata) end(tag string) {
+	for i := len(m.stack) - 1; i >= 0; i-- {
+		if m.stack[i].tag == tag {
+			for len(m.stack) > i {
+				m.closeTop()
+			}
+			return
+		}
+	}
+}
+
+// close closes every element, at the end of the page.
+func (m *microdata) close() {
+	for len(m.stack) > 0 {
+		m.closeTop()
+	}
+}
+
+func (m *microdata) closeTop() {
+	el := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+
+	var value any
+	switch {
+	case el.item != nil:
+		value = map[string]any(el.item)
+	case el.text != nil:
+		if s := truncate(strings.Join(strings.Fields(el.text.String()), " "), maxMetaValue); s != "" {
+			value = s
+		}
+	}
+	if value == nil {
+		return
+	}
+	// an item that is not the property of another item is a top-level item
+	if !m.addProp(el.props, value) && el.item != nil {
+		m.items = append(m.items, el.item)
+	}
+}
+
+// addProp adds a value to the innermost open item, a property that is set
+// more than once becomes a list. It reports whether there was an item to add
+// the value to.
+func (m *microdata) addProp(props []string, value any) bool {
+	if len(props) == 0 {
+		return false
+	}
+	var item docmeta.Entity
+	for i := len(m.stack) - 1; i >= 0 && item == nil; i-- {
+		item = m.stack[i].item
+	}
+	if item == nil {
+		return false
+	}
+	for _, prop := range props {
+		if prop = stripSchema(prop); prop == "" {
+			continue
+		}
+		switch prev := item[prop].(type) {
+		case nil:
+			item[prop] = value
+		case []any:
+			item[prop] = append(prev, value)
+		default:
+			item[prop] = []any{prev, value}
+		}
+	}
+	return true
+}
+
+// attrValue returns the value of an itemprop element that is taken from an
+// attribute rather than its text.
+func attrValue(t html.Token) (string, bool) {
+	var key string
+	switch t.DataAtom {
+	case atom.Meta:
+		key = "content"
+	case atom.A, atom.Area, atom.Link:
+		key = "href"
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Embed, atom.Iframe, atom.Track:
+		key = "src"
+	case atom.Object:
+		key = "data"
+	case atom.Data, atom.Meter:
+		key = "value"
+	case atom.Time:
+		if !hasAttr(t, "datetime") {
+			return "", false
+		}
+		key = "datetime"
+	default:
+		if !hasAttr(t, "content") {
+			return "", false
+		}
+		key = "content"
+	}
+	return truncate(strings.TrimSpace(attr(t, key)), maxMetaValue), true
+}
+
+func hasAttr(t html.Token, key string) bool {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isVoid reports whether the element of a has no end tag.
+func isVoid(a atom.Atom) bool {
+	switch a {
+	case atom.Area, atom.Base, atom.Br, atom.Col, atom.Embed, atom.Hr, atom.Img,
+		atom.Input, atom.Link, atom.Meta, atom.Param, atom.Source, atom.Track, atom.Wbr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package politeness
+
+import (
+	"net"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxHostLabels bounds the number of values of the host label of the crawler
+// metrics. The domains seen after that many are labelled otherHost.
+const maxHostLabels = 100
+
+const otherHost = "other"
+
+var hostLabels = struct {
+	sync.Mutex
+	domains map[string]struct{}
+}{domains: make(map[string]struct{})}
+
+// HostLabel returns the value of the host label of the crawler metrics for
+// host: its registrable domain, e.g. example.co.uk for www.example.co.uk:8080.
+// Only the first maxHostLabels domains get a label of their own, the others
+// share the label "other" so the number of series stays bounded.
+func HostLabel(host string) string {
+	domain := registrableDomain(host)
+
+	hostLabels.Lock()
+	defer hostLabels.Unlock()
+	if _, ok := hostLabels.domains[domain]; ok {
+		return domain
+	}
+	if len(hostLabels.domains) >= maxHostLabels {
+		return otherHost
+	}
+	hostLabels.domains[domain] = struct{}{}
+	return domain
+}
+
+func registrableDomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,37 @@
+package politeness
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The host label of every metric is bounded by HostLabel.
+var (
+	queuedLinks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Subsystem: "host",
+		Name:      "queued_total",
+		Help:      "Number of links queued for a host.",
+	}, []string{"host"})
+
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Subsystem: "host",
+		Name:      "requests_total",
+		Help:      "Number of links released to the crawl pipeline for a host.",
+	}, []string{"host"})
+
+	waitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Subsystem: "host",
+		Name:      "wait_seconds_total",
+		Help:      "Time spent waiting for a host to become eligible for another request.",
+	}, []string{"host"})
+
+	inflight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "crawler",
+		Subsystem: "host",
+		Name:      "inflight",
+		Help:      "Number of requests currently in flight for a host.",
+	}, []string{"host"})
+)
@@ -0,0 +1,257 @@
+// Package politeness implements a per-host scheduler that spreads crawl
+// requests across hosts so that no single host is hammered in parallel.
+package politeness
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrEmpty is returned by Pop when the scheduler has no pending items.
+var ErrEmpty = errors.New("politeness: no pending items")
+
+// Config encapsulates the settings for a Scheduler.
+type Config struct {
+	// The minimum time between two requests to the same host. A larger
+	// Crawl-delay reported through SetCrawlDelay takes precedence.
+	MinDelay time.Duration
+
+	// The maximum number of requests in flight per host. If not
+	// specified, a default value of 1 will be used instead.
+	MaxConns int
+
+	// A connection slot that is not released with Done within this
+	// duration is reclaimed. If not specified, a default value of 2
+	// minutes will be used instead.
+	SlotTimeout time.Duration
+}
+
+func (cfg *Config) validate() {
+	if cfg.MinDelay < 0 {
+		cfg.MinDelay = 0
+	}
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = 1
+	}
+	if cfg.SlotTimeout <= 0 {
+		cfg.SlotTimeout = 2 * time.Minute
+	}
+}
+
+type hostState[T any] struct {
+	// the queued items by lane
+	pending map[int][]T
+
+	// the time each slot in flight was taken, by the ID of the slot
+	inflight   map[uint64]time.Time
+	last       time.Time
+	crawlDelay time.Duration
+}
+
+// Slot is a connection slot for a host, taken by Pop and released with Done.
+type Slot struct {
+	Host string
+
+	id uint64
+}
+
+// Scheduler buffers items per host and hands them out in the order the hosts
+// become eligible for another request. It is safe for concurrent use.
+type Scheduler[T any] struct {
+	*state[T]
+
+	// the lane of the scheduler, see Lane
+	lane int
+}
+
+// state is shared by the lanes of a scheduler.
+type state[T any] struct {
+	cfg Config
+
+	mu      sync.Mutex
+	hosts   map[string]*hostState[T]
+	pending map[int]int
+	wake    chan struct{}
+
+	// the ID of the last slot taken and the last lane created
+	slots uint64
+	lanes int
+}
+
+// New creates a scheduler with the specified config.
+func New[T any](cfg Config) *Scheduler[T] {
+	cfg.validate()
+	return &Scheduler[T]{state: &state[T]{
+		cfg:     cfg,
+		hosts:   make(map[string]*hostState[T]),
+		pending: make(map[int]int),
+		wake:    make(chan struct{}),
+	}}
+}
+
+// Lane returns a scheduler with a queue of its own. Its Pop only hands out
+// the items pushed to it, but the delay and the connection limit of every
+// host are shared with s and its other lanes.
+func (s *Scheduler[T]) Lane() *Scheduler[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lanes++
+	return &Scheduler[T]{state: s.state, lane: s.lanes}
+}
+
+// Push queues item for host.
+func (s *Scheduler[T]) Push(host string, item T) {
+	s.mu.Lock()
+	h := s.host(host)
+	h.pending[s.lane] = append(h.pending[s.lane], item)
+	s.pending[s.lane]++
+	s.notify()
+	s.mu.Unlock()
+
+	queuedLinks.WithLabelValues(HostLabel(host)).Inc()
+}
+
+// Pending returns the number of items queued in the lane of s across all
+// hosts.
+func (s *Scheduler[T]) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending[s.lane]
+}
+
+// SetCrawlDelay records the Crawl-delay requested by host.
+func (s *Scheduler[T]) SetCrawlDelay(host string, delay time.Duration) {
+	s.mu.Lock()
+	s.host(host).crawlDelay = delay
+	s.mu.Unlock()
+}
+
+// Pop blocks until a host with pending items may receive another request and
+// returns its next item, taking a connection slot for the host that must be
+// released with Done. It returns ErrEmpty if there is nothing to schedule.
+func (s *Scheduler[T]) Pop(ctx context.Context) (Slot, T, error) {
+	var zero T
+	start := time.Now()
+	for {
+		s.mu.Lock()
+		if s.pending[s.lane] == 0 {
+			s.mu.Unlock()
+			return Slot{}, zero, ErrEmpty
+		}
+
+		now := time.Now()
+		host, readyAt, ok := s.next(now)
+		if ok && !readyAt.After(now) {
+			h := s.hosts[host]
+			item := h.pending[s.lane][0]
+			h.pending[s.lane] = h.pending[s.lane][1:]
+			if len(h.pending[s.lane]) == 0 {
+				delete(h.pending, s.lane)
+			}
+			s.slots++
+			slot := Slot{Host: host, id: s.slots}
+			h.inflight[slot.id] = now
+			h.last = now
+			s.pending[s.lane]--
+			s.mu.Unlock()
+
+			label := HostLabel(host)
+			requests.WithLabelValues(label).Inc()
+			waitSeconds.WithLabelValues(label).Add(now.Sub(start).Seconds())
+			inflight.WithLabelValues(label).Inc()
+			return slot, item, nil
+		}
+
+		wake := s.wake
+		s.mu.Unlock()
+
+		// Wait until the earliest host becomes ready or a slot is released.
+		// If every host is at its connection limit the slot timeout bounds
+		// the wait.
+		wait := s.cfg.SlotTimeout
+		if ok {
+			wait = readyAt.Sub(now)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Slot{}, zero, ctx.Err()
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Done releases a connection slot taken by Pop. A slot that was reclaimed
+// after its timeout is not released again.
+func (s *Scheduler[T]) Done(slot Slot) {
+	s.mu.Lock()
+	if h, ok := s.hosts[slot.Host]; ok && !h.inflight[slot.id].IsZero() {
+		delete(h.inflight, slot.id)
+		inflight.WithLabelValues(HostLabel(slot.Host)).Dec()
+		s.notify()
+	}
+	s.mu.Unlock()
+}
+
+// next returns the host with items in the lane of s that becomes eligible
+// first along with the time it does. ok is false if every host with pending
+// items in the lane is at its connection limit. s.mu must be held.
+func (s *Scheduler[T]) next(now time.Time) (host string, readyAt time.Time, ok bool) {
+	for name, h := range s.hosts {
+		delay := s.cfg.MinDelay
+		if h.crawlDelay > delay {
+			delay = h.crawlDelay
+		}
+
+		// reclaim slots that were never released
+		for id, taken := range h.inflight {
+			if now.Sub(taken) > s.cfg.SlotTimeout {
+				delete(h.inflight, id)
+				inflight.WithLabelValues(HostLabel(name)).Dec()
+			}
+		}
+
+		if len(h.pending) == 0 {
+			// forget idle hosts whose last request no longer matters
+			if len(h.inflight) == 0 && now.Sub(h.last) > delay {
+				delete(s.hosts, name)
+			}
+			continue
+		}
+		if len(h.pending[s.lane]) == 0 {
+			continue
+		}
+		if len(h.inflight) >= s.cfg.MaxConns {
+			continue
+		}
+
+		at := h.last.Add(delay)
+		if !ok || at.Before(readyAt) {
+			host, readyAt, ok = name, at, true
+		}
+	}
+	return host, readyAt, ok
+}
+
+func (s *state[T]) host(name string) *hostState[T] {
+	h, ok := s.hosts[name]
+	if !ok {
+		h = &hostState[T]{
+			pending:  make(map[int][]T),
+			inflight: make(map[uint64]time.Time),
+		}
+		s.hosts[name] = h
+	}
+	return h
+}
+
+// notify wakes up every goroutine blocked in Pop. s.mu must be held.
+func (s *state[T]) notify() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
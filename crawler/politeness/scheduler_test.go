@@ -0,0 +1,235 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timingServer records the arrival time of every request and the maximum
+// number of requests it served concurrently.
+type timingServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	hits     []time.Time
+	active   int
+	maxConns int
+}
+
+func newTimingServer(latency time.Duration) *timingServer {
+	ts := &timingServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.hits = append(ts.hits, time.Now())
+		ts.active++
+		if ts.active > ts.maxConns {
+			ts.maxConns = ts.active
+		}
+		ts.mu.Unlock()
+
+		time.Sleep(latency)
+
+		ts.mu.Lock()
+		ts.active--
+		ts.mu.Unlock()
+	}))
+	return ts
+}
+
+func Test_scheduler(t *testing.T) {
+	t.Run("min delay per host", test_min_delay)
+	t.Run("max connections per host", test_max_conns)
+	t.Run("crawl delay overrides min delay", test_crawl_delay)
+	t.Run("late done after slot timeout", test_late_done)
+	t.Run("lanes share hosts", test_lanes)
+	t.Run("bounded host label", test_host_label)
+}
+
+// crawl pops every pending url and requests it with the given number of
+// workers, releasing the host slot once the response has been read.
+func crawl(t *testing.T, s *Scheduler[string], workers int) {
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				slot, link, err := s.Pop(context.Background())
+				if errors.Is(err, ErrEmpty) {
+					return
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				res, err := http.Get(link)
+				if err != nil {
+					t.Error(err)
+				} else {
+					res.Body.Close()
+				}
+				s.Done(slot)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func push(s *Scheduler[string], srv *timingServer, n int) {
+	u, _ := url.Parse(srv.URL)
+	for i := 0; i < n; i++ {
+		s.Push(u.Host, srv.URL)
+	}
+}
+
+func assertMinGap(t *testing.T, srv *timingServer, minGap time.Duration) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for i := 1; i < len(srv.hits); i++ {
+		// allow a little slack for the time between Pop and the request
+		// reaching the server
+		if gap := srv.hits[i].Sub(srv.hits[i-1]); gap < minGap-5*time.Millisecond {
+			t.Fatalf("\ngot:%v \nexpect:>=%v \nmessage:%v", gap, minGap, "requests to the same host were too close")
+		}
+	}
+}
+
+func test_min_delay(t *testing.T) {
+	a, b := newTimingServer(0), newTimingServer(0)
+	defer a.Close()
+	defer b.Close()
+
+	delay := 50 * time.Millisecond
+	s := New[string](Config{MinDelay: delay, MaxConns: 4})
+	push(s, a, 5)
+	push(s, b, 5)
+
+	start := time.Now()
+	crawl(t, s, 4)
+	elapsed := time.Since(start)
+
+	assertMinGap(t, a, delay)
+	assertMinGap(t, b, delay)
+
+	// both hosts are crawled side by side, not one after the other
+	if max := 6 * delay; elapsed > max {
+		t.Fatalf("\ngot:%v \nexpect:<%v \nmessage:%v", elapsed, max, "hosts were not crawled in parallel")
+	}
+}
+
+func test_max_conns(t *testing.T) {
+	a := newTimingServer(30 * time.Millisecond)
+	defer a.Close()
+
+	s := New[string](Config{MaxConns: 2})
+	push(s, a, 10)
+	crawl(t, s, 8)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.hits) != 10 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(a.hits), 10)
+	}
+	if a.maxConns > 2 {
+		t.Fatalf("\ngot:%v \nexpect:<=%v \nmessage:%v", a.maxConns, 2, "too many concurrent requests")
+	}
+}
+
+func test_crawl_delay(t *testing.T) {
+	a := newTimingServer(0)
+	defer a.Close()
+
+	delay := 80 * time.Millisecond
+	s := New[string](Config{MinDelay: 10 * time.Millisecond, MaxConns: 4})
+	u, _ := url.Parse(a.URL)
+	s.SetCrawlDelay(u.Host, delay)
+	push(s, a, 3)
+	crawl(t, s, 2)
+
+	assertMinGap(t, a, delay)
+}
+
+func test_lanes(t *testing.T) {
+	a := newTimingServer(20 * time.Millisecond)
+	defer a.Close()
+
+	delay := 40 * time.Millisecond
+	s := New[string](Config{MinDelay: delay, MaxConns: 1})
+	lane := s.Lane()
+	push(s, a, 3)
+	push(lane, a, 3)
+	if s.Pending() != 3 || lane.Pending() != 3 {
+		t.Fatalf("\ngot:%v %v \nexpect:%v %v", s.Pending(), lane.Pending(), 3, 3)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); crawl(t, s, 2) }()
+	go func() { defer wg.Done(); crawl(t, lane, 2) }()
+	wg.Wait()
+
+	a.mu.Lock()
+	if len(a.hits) != 6 || a.maxConns > 1 {
+		t.Fatalf("\ngot:%v %v \nexpect:%v %v \nmessage:%v", len(a.hits), a.maxConns, 6, 1, "lanes did not share the limits of the host")
+	}
+	a.mu.Unlock()
+	assertMinGap(t, a, delay)
+}
+
+func test_late_done(t *testing.T) {
+	s := New[string](Config{MaxConns: 1, SlotTimeout: 20 * time.Millisecond})
+	s.Push("a", "1")
+	s.Push("a", "2")
+	s.Push("a", "3")
+
+	stale, _, err := s.Pop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the slot of the first request times out and is reclaimed
+	time.Sleep(30 * time.Millisecond)
+	_, _, err = s.Pop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// releasing the reclaimed slot late keeps the second request's slot
+	s.Done(stale)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.Pop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", err, context.DeadlineExceeded, "a late Done released another request's slot")
+	}
+}
+
+func test_host_label(t *testing.T) {
+	tests := map[string]string{
+		"www.example.co.uk:8080": "example.co.uk",
+		"blog.example.com":       "example.com",
+		"127.0.0.1:8080":         "127.0.0.1",
+		"localhost":              "localhost",
+	}
+	for host, expect := range tests {
+		if got := HostLabel(host); got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	}
+
+	for i := 0; i < maxHostLabels; i++ {
+		HostLabel(fmt.Sprintf("host%d.example.org", i))
+		HostLabel(fmt.Sprintf("example%d.org", i))
+	}
+	if got := HostLabel("www.example.net"); got != otherHost {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, otherHost, "the number of labels is not bounded")
+	}
+	if got := HostLabel("www.example.co.uk"); got != "example.co.uk" {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, "example.co.uk")
+	}
+}
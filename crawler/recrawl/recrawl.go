@@ -0,0 +1,99 @@
+// Package recrawl schedules the next crawl of a link from how often its
+// content was observed to change.
+package recrawl
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State is what the crawler remembers about a link between two crawls.
+type State struct {
+	// HTTP validators of the last response, sent back with the next request
+	// so unchanged pages can be answered with 304 Not Modified.
+	ETag         string
+	LastModified string
+
+	// Hash of the extracted text content of the last crawl.
+	ContentHash uint64
+
+	// The current time between two crawls of the link.
+	Interval time.Duration
+
+	// When the link is due to be crawled again.
+	NextCrawlAt time.Time
+}
+
+// Store is implemented by persistent recrawl state backends.
+type Store interface {
+	// LookupState returns the state of a link and whether one was found.
+	LookupState(linkID uuid.UUID) (State, bool, error)
+
+	// SaveState stores the state of a link.
+	SaveState(linkID uuid.UUID, s State) error
+}
+
+// Policy adapts the recrawl interval of a link. Pages that changed since the
+// last crawl are crawled twice as often, unchanged pages back off by half of
+// their current interval.
+type Policy struct {
+	// The interval of a link that was never crawled before.
+	Initial time.Duration
+
+	// The bounds of the interval.
+	Min time.Duration
+	Max time.Duration
+}
+
+// Next returns the interval until the next crawl after a crawl at which the
+// content was observed to have changed or not.
+func (p Policy) Next(prev State, changed bool) time.Duration {
+	interval := prev.Interval
+	if interval <= 0 {
+		return p.clamp(p.Initial)
+	}
+	if changed {
+		interval /= 2
+	} else {
+		interval += interval / 2
+	}
+	return p.clamp(interval)
+}
+
+// Schedule updates s after a crawl at now.
+func (p Policy) Schedule(s State, changed bool, now time.Time) State {
+	s.Interval = p.Next(s, changed)
+	s.NextCrawlAt = now.Add(s.Interval)
+	return s
+}
+
+func (p Policy) clamp(d time.Duration) time.Duration {
+	if p.Min > 0 && d < p.Min {
+		return p.Min
+	}
+	if p.Max > 0 && d > p.Max {
+		return p.Max
+	}
+	return d
+}
+
+// ContentHash returns the hash stored in State.ContentHash for content.
+func ContentHash(content string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(content))
+	return h.Sum64()
+}
+
+// Hint moves the next crawl of a link forward to now if lastMod, as
+// announced by a sitemap, is newer than the last crawl. It reports whether
+// the state was changed.
+func Hint(s State, lastMod, now time.Time) (State, bool) {
+	lastCrawl := s.NextCrawlAt.Add(-s.Interval)
+	if lastMod.IsZero() || !lastMod.After(lastCrawl) || !s.NextCrawlAt.After(now) {
+		return s, false
+	}
+	s.NextCrawlAt = now
+	return s, true
+}
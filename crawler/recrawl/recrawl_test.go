@@ -0,0 +1,60 @@
+package recrawl
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_policy(t *testing.T) {
+	p := Policy{Initial: 24 * time.Hour, Min: time.Hour, Max: 30 * 24 * time.Hour}
+	now := time.Now()
+
+	t.Run("test_first_crawl", func(t *testing.T) {
+		s := p.Schedule(State{}, true, now)
+		if s.Interval != p.Initial {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.Interval, p.Initial)
+		}
+		if !s.NextCrawlAt.Equal(now.Add(p.Initial)) {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.NextCrawlAt, now.Add(p.Initial))
+		}
+	})
+
+	t.Run("test_changing_page", func(t *testing.T) {
+		s := State{Interval: p.Initial}
+		for i := 0; i < 10; i++ {
+			s = p.Schedule(s, true, now)
+		}
+		if s.Interval != p.Min {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", s.Interval, p.Min, "frequently changing page should reach the minimum")
+		}
+	})
+
+	t.Run("test_static_page", func(t *testing.T) {
+		s := State{Interval: p.Initial}
+		prev := s.Interval
+		for i := 0; i < 20; i++ {
+			s = p.Schedule(s, false, now)
+			if s.Interval < prev {
+				t.Fatalf("\ngot:%v \nexpect:>=%v \nmessage:%v", s.Interval, prev, "unchanged page should back off")
+			}
+			prev = s.Interval
+		}
+		if s.Interval != p.Max {
+			t.Fatalf("\ngot:%v \nexpect:%v", s.Interval, p.Max)
+		}
+	})
+}
+
+func Test_hint(t *testing.T) {
+	now := time.Now()
+	s := State{Interval: 10 * 24 * time.Hour, NextCrawlAt: now.Add(5 * 24 * time.Hour)}
+
+	if _, ok := Hint(s, now.Add(-6*24*time.Hour), now); ok {
+		t.Fatal("page modified before the last crawl was rescheduled")
+	}
+
+	got, ok := Hint(s, now.Add(-time.Hour), now)
+	if !ok || !got.NextCrawlAt.Equal(now) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got.NextCrawlAt, now)
+	}
+}
@@ -0,0 +1,82 @@
+// Package report summarizes crawl passes so crawl throughput can be followed
+// over time.
+package report
+
+import "time"
+
+// Pass is the summary of a crawl pass.
+type Pass struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// Links handed to the crawl pipeline.
+	Links int64
+
+	// Pages that were requested and answered, failed requests and pages
+	// that did not change since their last crawl.
+	Fetched     int64
+	Failed      int64
+	NotModified int64
+
+	// Links that were skipped by robots.txt or the crawl scope.
+	Disallowed int64
+	OutOfScope int64
+
+	// Links found on crawled pages that were rejected as a crawler trap.
+	Trapped int64
+
+	// Pages that were indexed, recorded as a near-duplicate of another page,
+	// pointed to another canonical URL or redirected to another URL.
+	Indexed      int64
+	Duplicates   int64
+	NonCanonical int64
+	Redirects    int64
+
+	// Documents removed from the index because their page is dead.
+	Removed int64
+
+	// Links found on crawled pages that were not in the link graph before.
+	Discovered int64
+
+	// Response body bytes read by the crawler.
+	Bytes int64
+}
+
+// Duration returns how long the pass took.
+func (p *Pass) Duration() time.Duration {
+	return p.FinishedAt.Sub(p.StartedAt)
+}
+
+// PagesPerSecond returns the number of pages fetched per second of the pass.
+func (p *Pass) PagesPerSecond() float64 {
+	secs := p.Duration().Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(p.Fetched) / secs
+}
+
+// Add adds the counts of o to the counts of p, e.g. to continue the counts of
+// a pass that was resumed.
+func (p *Pass) Add(o *Pass) {
+	p.Links += o.Links
+	p.Fetched += o.Fetched
+	p.Failed += o.Failed
+	p.NotModified += o.NotModified
+	p.Disallowed += o.Disallowed
+	p.OutOfScope += o.OutOfScope
+	p.Trapped += o.Trapped
+	p.Indexed += o.Indexed
+	p.Duplicates += o.Duplicates
+	p.NonCanonical += o.NonCanonical
+	p.Redirects += o.Redirects
+	p.Removed += o.Removed
+	p.Discovered += o.Discovered
+	p.Bytes += o.Bytes
+}
+
+// Store is implemented by persistent pass report backends.
+type Store interface {
+	// SavePass stores the summary of a finished pass.
+	SavePass(p *Pass) error
+}
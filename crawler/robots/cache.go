@@ -0,0 +1,139 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// unreachableTTL is how long a DisallowAll verdict caused by a server or
+// network error is cached before the file is requested again.
+const unreachableTTL = 10 * time.Minute
+
+type entry struct {
+	ready   chan struct{}
+	robots  *Robots
+	expires time.Time
+}
+
+// Cache fetches robots.txt files and caches the parsed result per host for
+// a fixed TTL. It is safe for concurrent use.
+type Cache struct {
+	client    *http.Client
+	userAgent string
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewCache creates a robots.txt cache that identifies itself with userAgent
+// and keeps fetched files for ttl. If client is nil, http.DefaultClient is
+// used instead.
+func NewCache(client *http.Client, userAgent string, ttl time.Duration) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Cache{
+		client:    client,
+		userAgent: userAgent,
+		ttl:       ttl,
+		entries:   make(map[string]*entry),
+	}
+}
+
+// UserAgent returns the user agent used to evaluate and fetch robots.txt.
+func (c *Cache) UserAgent() string { return c.userAgent }
+
+// Allowed reports whether rawURL may be crawled according to the robots.txt
+// file of its host.
+func (c *Cache) Allowed(ctx context.Context, rawURL string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, fmt.Errorf("robots: %w", err)
+	}
+
+	r, err := c.Lookup(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	return r.Allowed(c.userAgent, u.RequestURI()), nil
+}
+
+// Lookup returns the robots.txt rules that apply to the host of u, fetching
+// the file if it is not cached or has expired.
+func (c *Cache) Lookup(ctx context.Context, u *url.URL) (*Robots, error) {
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("robots: unsupported url %q", u.String())
+	}
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.robots != nil && time.Now().After(e.expires) {
+		ok = false
+	}
+	if !ok {
+		// the first caller fetches the file, others wait for the result
+		e = &entry{ready: make(chan struct{})}
+		c.entries[key] = e
+		c.mu.Unlock()
+
+		r, ttl := c.fetch(ctx, key)
+
+		c.mu.Lock()
+		if err := ctx.Err(); err != nil {
+			// do not cache a verdict caused by the caller giving up
+			delete(c.entries, key)
+			c.mu.Unlock()
+			e.robots = DisallowAll
+			close(e.ready)
+			return nil, err
+		}
+		e.robots, e.expires = r, time.Now().Add(ttl)
+		c.mu.Unlock()
+		close(e.ready)
+		return r, nil
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.ready:
+		return e.robots, nil
+	}
+}
+
+// fetch downloads and parses the robots.txt file at origin. Following RFC
+// 9309, a 4xx response means there are no restrictions while a 5xx response
+// or a network error means the whole host is disallowed.
+func (c *Cache) fetch(ctx context.Context, origin string) (*Robots, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return DisallowAll, unreachableTTL
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return DisallowAll, unreachableTTL
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		r, err := Parse(res.Body)
+		if err != nil {
+			return DisallowAll, unreachableTTL
+		}
+		return r, c.ttl
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return AllowAll, c.ttl
+	default:
+		return DisallowAll, unreachableTTL
+	}
+}
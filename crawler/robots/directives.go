@@ -0,0 +1,68 @@
+package robots
+
+import "strings"
+
+// Directives are the indexing directives of a page, declared in
+// <meta name=robots> tags or X-Robots-Tag response headers.
+type Directives struct {
+	// The page is not indexed.
+	NoIndex bool
+
+	// The links of the page do not pass on rank.
+	NoFollow bool
+}
+
+// directives that take a value after a colon, which must not be mistaken
+// for a user agent prefix
+var valueDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// ParseDirectives returns the directives that apply to the crawler identified
+// by userAgent. Every value is a comma separated list of directives, such as
+// the content of a robots meta tag or an X-Robots-Tag header, optionally
+// prefixed by the user agent it applies to, e.g. "otherbot: noindex".
+func ParseDirectives(userAgent string, values ...string) Directives {
+	token := productToken(userAgent)
+
+	var d Directives
+	for _, value := range values {
+		applies := true
+		for _, part := range strings.Split(value, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			if name, rest, ok := strings.Cut(part, ":"); ok && !valueDirectives[strings.TrimSpace(name)] {
+				// the directives that follow apply to the named agent
+				applies = strings.TrimSpace(name) == token
+				part = strings.TrimSpace(rest)
+			}
+			if !applies {
+				continue
+			}
+
+			switch part {
+			case "noindex":
+				d.NoIndex = true
+			case "nofollow":
+				d.NoFollow = true
+			case "none":
+				d.NoIndex, d.NoFollow = true, true
+			}
+		}
+	}
+	return d
+}
+
+// NoFollowRel reports whether the rel attribute of a link asks for the link
+// not to pass on rank, e.g. "nofollow", "ugc" or "sponsored".
+func NoFollowRel(rel string) bool {
+	for _, f := range strings.Fields(rel) {
+		switch strings.ToLower(f) {
+		case "nofollow", "ugc", "sponsored":
+			return true
+		}
+	}
+	return false
+}
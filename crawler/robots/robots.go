@@ -0,0 +1,214 @@
+// Package robots implements parsing and evaluation of robots.txt files as
+// described in RFC 9309, including the widely supported Crawl-delay and
+// Sitemap extensions.
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFileSize is the maximum number of bytes of a robots.txt file that will
+// be parsed. RFC 9309 requires crawlers to parse at least 500 KiB.
+const maxFileSize = 512 * 1024
+
+var (
+	// AllowAll is returned for hosts that do not serve a robots.txt file.
+	AllowAll = &Robots{}
+
+	// DisallowAll is returned for hosts whose robots.txt file is
+	// unreachable because of a server or network error.
+	DisallowAll = &Robots{groups: []*group{{
+		agents: []string{"*"},
+		rules:  []rule{{allow: false, pattern: "/"}},
+	}}}
+)
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// Robots holds the parsed contents of a robots.txt file.
+type Robots struct {
+	groups []*group
+
+	// Sitemaps lists the sitemap URLs advertised by the file.
+	Sitemaps []string
+}
+
+// Parse reads a robots.txt file from r. Unknown directives and malformed
+// lines are ignored.
+func Parse(r io.Reader) (*Robots, error) {
+	var (
+		robots    Robots
+		cur       *group
+		lastWasUA bool
+	)
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxFileSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the same group
+			if cur == nil || !lastWasUA {
+				cur = &group{}
+				robots.groups = append(robots.groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+			lastWasUA = true
+			continue
+
+		case "allow", "disallow":
+			// an empty disallow matches nothing and can be dropped
+			if cur != nil && value != "" {
+				cur.rules = append(cur.rules, rule{allow: key == "allow", pattern: value})
+			}
+
+		case "crawl-delay":
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs >= 0 {
+					cur.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+		lastWasUA = false
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &robots, nil
+}
+
+// Allowed reports whether the crawler identified by userAgent may fetch
+// path. The path should include the query string, if any.
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	// The most specific (longest) matching rule wins, allow wins on ties.
+	var match *rule
+	for _, g := range r.match(userAgent) {
+		for i := range g.rules {
+			ru := &g.rules[i]
+			if !matchPattern(ru.pattern, path) {
+				continue
+			}
+			if match == nil ||
+				len(ru.pattern) > len(match.pattern) ||
+				(len(ru.pattern) == len(match.pattern) && ru.allow) {
+				match = ru
+			}
+		}
+	}
+
+	return match == nil || match.allow
+}
+
+// CrawlDelay returns the crawl delay requested for userAgent or zero if the
+// file does not specify one.
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+	for _, g := range r.match(userAgent) {
+		if g.crawlDelay > delay {
+			delay = g.crawlDelay
+		}
+	}
+	return delay
+}
+
+// match returns the groups that apply to userAgent. Groups naming the agent
+// explicitly take precedence over the wildcard group.
+func (r *Robots) match(userAgent string) []*group {
+	token := productToken(userAgent)
+
+	var named, wildcard []*group
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if agent == token {
+				named = append(named, g)
+				break
+			}
+		}
+	}
+
+	if len(named) != 0 {
+		return named
+	}
+	return wildcard
+}
+
+// productToken extracts the lower-cased product token from a user agent
+// string, e.g. "se-crawler" from "se-crawler/1.0 (+https://example.com)".
+func productToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// matchPattern reports whether path matches a robots.txt path pattern. The
+// '*' wildcard matches any sequence of characters and a trailing '$'
+// anchors the pattern to the end of the path.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
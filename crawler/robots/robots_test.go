@@ -0,0 +1,139 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsFile = `
+# comment line
+User-agent: se-crawler
+User-agent: other-bot
+Disallow: /private/
+Allow: /private/public
+Crawl-delay: 2.5
+
+User-agent: *
+Disallow: /tmp
+Disallow: /*.php$
+Disallow: /search*q=
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func Test_robots(t *testing.T) {
+	t.Run("parse and match rules", test_parse_rules)
+	t.Run("cache fetch policy", test_cache_fetch)
+	t.Run("page directives", test_directives)
+}
+
+func test_directives(t *testing.T) {
+	cases := []struct {
+		values []string
+		expect Directives
+	}{
+		{[]string{"noindex, nofollow"}, Directives{NoIndex: true, NoFollow: true}},
+		{[]string{"NONE"}, Directives{NoIndex: true, NoFollow: true}},
+		{[]string{"index", "nofollow"}, Directives{NoFollow: true}},
+		{[]string{"otherbot: noindex, nofollow"}, Directives{}},
+		{[]string{"otherbot: noindex, se-crawler: nofollow"}, Directives{NoFollow: true}},
+		{[]string{"unavailable_after: 25 Jun 2010 15:00:00 PST, noindex"}, Directives{NoIndex: true}},
+		{[]string{"max-snippet:20"}, Directives{}},
+	}
+	for _, c := range cases {
+		if got := ParseDirectives("se-crawler/1.0", c.values...); got != c.expect {
+			t.Errorf("\nvalues:%v \ngot:%+v \nexpect:%+v", c.values, got, c.expect)
+		}
+	}
+
+	if !NoFollowRel("external Sponsored") || NoFollowRel("noopener") {
+		t.Error("rel attribute not recognised")
+	}
+}
+
+func test_parse_rules(t *testing.T) {
+	r, err := Parse(strings.NewReader(robotsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		agent  string
+		path   string
+		expect bool
+	}{
+		{"se-crawler/1.0", "/private/secret", false},
+		{"se-crawler/1.0", "/private/public/page", true},
+		{"se-crawler/1.0", "/tmp/file", true}, // named group replaces the wildcard group
+		{"Other-Bot", "/private/", false},
+		{"unknown", "/tmp/file", false},
+		{"unknown", "/index.php", false},
+		{"unknown", "/index.php?x=1", true},
+		{"unknown", "/search?lang=en&q=go", false},
+		{"unknown", "/robots.txt", true},
+		{"unknown", "/", true},
+	}
+
+	for _, c := range cases {
+		if got := r.Allowed(c.agent, c.path); got != c.expect {
+			t.Errorf("\nagent:%v path:%v \ngot:%v \nexpect:%v", c.agent, c.path, got, c.expect)
+		}
+	}
+
+	if delay := r.CrawlDelay("se-crawler"); delay != 2500*time.Millisecond {
+		t.Errorf("\ngot:%v \nexpect:%v", delay, 2500*time.Millisecond)
+	}
+	if delay := r.CrawlDelay("unknown"); delay != 0 {
+		t.Errorf("\ngot:%v \nexpect:%v", delay, 0)
+	}
+
+	if len(r.Sitemaps) != 1 || r.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("\ngot:%v \nmessage:%v", r.Sitemaps, "sitemap line not parsed")
+	}
+}
+
+func test_cache_fetch(t *testing.T) {
+	var hits atomic.Int32
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(robotsFile))
+	}))
+	defer srv.Close()
+
+	cache := NewCache(srv.Client(), "se-crawler", time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ok, err := cache.Allowed(ctx, srv.URL+"/private/secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatalf("\ngot:%v \nexpect:%v", ok, false)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", n, 1, "robots.txt should be fetched once per TTL")
+	}
+
+	// missing robots.txt allows everything
+	status = http.StatusNotFound
+	cache = NewCache(srv.Client(), "se-crawler", time.Hour)
+	if ok, _ := cache.Allowed(ctx, srv.URL+"/private/secret"); !ok {
+		t.Fatalf("\ngot:%v \nexpect:%v", ok, true)
+	}
+
+	// server error disallows everything
+	status = http.StatusServiceUnavailable
+	cache = NewCache(srv.Client(), "se-crawler", time.Hour)
+	if ok, _ := cache.Allowed(ctx, srv.URL+"/"); ok {
+		t.Fatalf("\ngot:%v \nexpect:%v", ok, false)
+	}
+}
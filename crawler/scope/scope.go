@@ -0,0 +1,184 @@
+// Package scope decides which URLs are part of a crawl. Rules are read from a
+// JSON file, for example a crawl of Go documentation sites only:
+//
+//	{
+//		"allow_domains": ["go.dev", "golang.org"],
+//		"deny_domains": ["play.golang.org"],
+//		"include": [],
+//		"exclude": ["\\?tab=", "/search\\?"],
+//		"schemes": ["https"],
+//		"max_depth": 5,
+//		"max_url_length": 512
+//	}
+//
+// Every field is optional, an empty file puts the whole web in scope.
+package scope
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rules is the JSON representation of a scope.
+type Rules struct {
+	// Hosts that are in scope together with their subdomains. If empty,
+	// every host not denied is in scope.
+	AllowDomains []string `json:"allow_domains"`
+
+	// Hosts that are out of scope together with their subdomains. Deny
+	// rules take precedence over allow rules.
+	DenyDomains []string `json:"deny_domains"`
+
+	// Regular expressions matched against the full URL. If not empty, a URL
+	// must match at least one include pattern and no exclude pattern.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+
+	// The URL schemes that are in scope. If empty, every scheme is.
+	Schemes []string `json:"schemes"`
+
+	// The maximum number of hops from the seed link. Zero means unlimited.
+	// The depth of a link is only known to a crawler with a frontier.
+	MaxDepth int `json:"max_depth"`
+
+	// The maximum length of a URL in bytes. Zero means unlimited.
+	MaxURLLength int `json:"max_url_length"`
+}
+
+// Scope is a compiled set of rules.
+type Scope struct {
+	allow    []string
+	deny     []string
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+	schemes  map[string]bool
+	maxDepth int
+	maxLen   int
+}
+
+// Unrestricted puts every URL in scope.
+var Unrestricted = &Scope{}
+
+// Compile validates the rules and compiles their patterns.
+func Compile(r Rules) (*Scope, error) {
+	s := &Scope{
+		allow:    domains(r.AllowDomains),
+		deny:     domains(r.DenyDomains),
+		maxDepth: r.MaxDepth,
+		maxLen:   r.MaxURLLength,
+	}
+
+	var err error
+	if s.include, err = compile(r.Include); err != nil {
+		return nil, fmt.Errorf("scope include: %v", err)
+	}
+	if s.exclude, err = compile(r.Exclude); err != nil {
+		return nil, fmt.Errorf("scope exclude: %v", err)
+	}
+	if len(r.Schemes) > 0 {
+		s.schemes = make(map[string]bool, len(r.Schemes))
+		for _, scheme := range r.Schemes {
+			s.schemes[strings.ToLower(scheme)] = true
+		}
+	}
+	return s, nil
+}
+
+// Load reads and compiles the rules of a JSON file.
+func Load(path string) (*Scope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("scope %s: %v", path, err)
+	}
+	return Compile(r)
+}
+
+// MaxDepth returns the maximum number of hops from the seed link, zero if the
+// depth is unlimited.
+func (s *Scope) MaxDepth() int {
+	return s.maxDepth
+}
+
+// Allowed reports whether rawURL, found depth hops from its seed link, is in
+// scope.
+func (s *Scope) Allowed(rawURL string, depth int) bool {
+	if s.maxLen > 0 && len(rawURL) > s.maxLen {
+		return false
+	}
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return false
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if s.schemes != nil && !s.schemes[strings.ToLower(u.Scheme)] {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if matchDomain(s.deny, host) {
+		return false
+	}
+	if len(s.allow) > 0 && !matchDomain(s.allow, host) {
+		return false
+	}
+
+	for _, re := range s.exclude {
+		if re.MatchString(rawURL) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, re := range s.include {
+		if re.MatchString(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+func domains(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, d := range in {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// matchDomain reports whether host is one of the domains or a subdomain of
+// one of them.
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
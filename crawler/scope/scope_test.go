@@ -0,0 +1,123 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_scope_rules(t *testing.T) {
+	s, err := Compile(Rules{
+		AllowDomains: []string{"go.dev", "GoLang.org."},
+		DenyDomains:  []string{"play.golang.org"},
+		Exclude:      []string{`\?tab=`},
+		Schemes:      []string{"https"},
+		MaxDepth:     2,
+		MaxURLLength: 64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		depth  int
+		expect bool
+	}{
+		{"https://go.dev/doc/", 0, true},
+		{"https://pkg.go.dev/net/http", 2, true},
+		{"https://pkg.go.dev/net/http", 3, false},
+		{"https://golang.org/x/net", 1, true},
+		{"https://play.golang.org/p/abc", 1, false},
+		{"https://notgo.dev/", 1, false},
+		{"https://example.com/", 0, false},
+		{"http://go.dev/doc/", 0, false},
+		{"https://pkg.go.dev/net/http?tab=versions", 1, false},
+		{"https://go.dev/" + strings.Repeat("a", 64), 0, false},
+	}
+	for _, tt := range tests {
+		if got := s.Allowed(tt.url, tt.depth); got != tt.expect {
+			t.Fatalf("\nurl:%v depth:%v \ngot:%v \nexpect:%v", tt.url, tt.depth, got, tt.expect)
+		}
+	}
+
+	t.Run("test_include", func(t *testing.T) {
+		s, err := Compile(Rules{Include: []string{`^https://[^/]+/doc/`}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s.Allowed("https://example.com/doc/a", 10) || s.Allowed("https://example.com/blog/a", 0) {
+			t.Fatal("include pattern not applied")
+		}
+	})
+
+	t.Run("test_unrestricted", func(t *testing.T) {
+		if !Unrestricted.Allowed("ftp://anything.example.com/"+strings.Repeat("a", 4096), 1000) {
+			t.Fatal("unrestricted scope rejected a URL")
+		}
+	})
+
+	t.Run("test_invalid_pattern", func(t *testing.T) {
+		if _, err := Compile(Rules{Exclude: []string{"("}}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func Test_watcher_reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scope.json")
+	write := func(content string, mod time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	write(`{"allow_domains": ["go.dev"]}`, now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := Watch(ctx, path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Allowed("https://example.com/", 0) {
+		t.Fatal("initial rules not applied")
+	}
+
+	// a broken file keeps the previous rules
+	write(`{"allow_domains": [`, now.Add(time.Second))
+	time.Sleep(50 * time.Millisecond)
+	if w.Allowed("https://example.com/", 0) || !w.Allowed("https://go.dev/", 0) {
+		t.Fatal("broken rules file replaced the scope")
+	}
+
+	// rules that fail the required check keep the previous rules as well
+	w.Require(func(s *Scope) error {
+		if s.MaxDepth() > 0 {
+			return errors.New("max depth not supported")
+		}
+		return nil
+	})
+	write(`{"allow_domains": ["example.com"], "max_depth": 2}`, now.Add(2*time.Second))
+	time.Sleep(50 * time.Millisecond)
+	if w.Allowed("https://example.com/", 0) || w.MaxDepth() != 0 {
+		t.Fatal("rules failing the check replaced the scope")
+	}
+
+	write(`{"allow_domains": ["example.com"]}`, now.Add(3*time.Second))
+	deadline := time.Now().Add(2 * time.Second)
+	for !w.Allowed("https://example.com/", 0) {
+		if time.Now().After(deadline) {
+			t.Fatal("rules file was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
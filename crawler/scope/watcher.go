@@ -0,0 +1,94 @@
+package scope
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher keeps a scope in sync with its rules file. The file is checked for
+// modifications every interval, a file that fails to load keeps the previous
+// scope in place.
+type Watcher struct {
+	path    string
+	current atomic.Pointer[Scope]
+	check   atomic.Pointer[func(*Scope) error]
+	modTime time.Time
+	size    int64
+}
+
+// Watch loads the rules file at path and reloads it whenever it changes until
+// ctx is canceled.
+func Watch(ctx context.Context, path string, interval time.Duration) (*Watcher, error) {
+	w := &Watcher{path: path}
+	if _, err := w.reload(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloaded, err := w.reload()
+				if err != nil {
+					log.Println("scope: keeping previous rules:", err)
+				} else if reloaded {
+					log.Println("scope: reloaded", w.path)
+				}
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Scope returns the current scope.
+func (w *Watcher) Scope() *Scope {
+	return w.current.Load()
+}
+
+// Allowed reports whether rawURL is in the current scope.
+func (w *Watcher) Allowed(rawURL string, depth int) bool {
+	return w.Scope().Allowed(rawURL, depth)
+}
+
+// MaxDepth returns the maximum depth of the current scope.
+func (w *Watcher) MaxDepth() int {
+	return w.Scope().MaxDepth()
+}
+
+// Require sets a check every reloaded rules file must pass, e.g. because the
+// crawler can not apply some of the rules. A file that fails it keeps the
+// previous scope in place, like a file that fails to load.
+func (w *Watcher) Require(check func(*Scope) error) {
+	w.check.Store(&check)
+}
+
+// reload loads the rules file if it changed since the last load.
+func (w *Watcher) reload() (bool, error) {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if w.current.Load() != nil && fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false, nil
+	}
+
+	s, err := Load(w.path)
+	if err != nil {
+		return false, err
+	}
+	if check := w.check.Load(); check != nil {
+		if err := (*check)(s); err != nil {
+			return false, err
+		}
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	w.current.Store(s)
+	return true, nil
+}
@@ -0,0 +1,60 @@
+// Package simhash computes 64-bit SimHash fingerprints of text. Documents
+// with similar content have fingerprints that differ in only a few bits, so
+// near-duplicates can be found by comparing the Hamming distance.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// shingleSize is the number of consecutive words hashed together.
+const shingleSize = 3
+
+// MinWords is the minimum number of words a text needs for its fingerprint
+// to be meaningful. Shorter texts (e.g. empty or error pages) would collide
+// with each other.
+const MinWords = 20
+
+// Fingerprint returns the SimHash of text. ok is false if the text has fewer
+// than MinWords words.
+func Fingerprint(text string) (fp uint64, ok bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < MinWords {
+		return 0, false
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h.Reset()
+		for _, w := range words[i : i+shingleSize] {
+			_, _ = h.Write([]byte(w))
+			_, _ = h.Write([]byte{' '})
+		}
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	for bit, w := range weights {
+		if w > 0 {
+			fp |= 1 << bit
+		}
+	}
+	return fp, true
+}
+
+// Distance returns the number of bits in which a and b differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
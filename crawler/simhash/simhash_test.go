@@ -0,0 +1,43 @@
+package simhash
+
+import (
+	"strings"
+	"testing"
+)
+
+const article = `The crawler fetches every link from the graph, extracts the title and
+the text content of the page and writes the result to the index. Pages that
+were already crawled are refreshed once the recrawl interval has passed, so
+the search results stay reasonably fresh without downloading the same static
+pages over and over again. The PageRank service then ranks the documents.`
+
+func Test_simhash(t *testing.T) {
+	base, ok := Fingerprint(article)
+	if !ok {
+		t.Fatal("fingerprint of a full article should be ok")
+	}
+
+	// a print version with a different header and footer
+	mirror, _ := Fingerprint("Print version. " + article + " Copyright 2023.")
+	if d := Distance(base, mirror); d > 10 {
+		t.Fatalf("\ngot:%v \nexpect:<=%v \nmessage:%v", d, 10, "near duplicate too far apart")
+	}
+
+	// whitespace and case do not matter
+	same, _ := Fingerprint(strings.ToUpper(strings.Join(strings.Fields(article), "  ")))
+	if d := Distance(base, same); d != 0 {
+		t.Fatalf("\ngot:%v \nexpect:%v", d, 0)
+	}
+
+	other, _ := Fingerprint(`Recipes for a simple weeknight dinner: roast the vegetables
+	with olive oil and salt, cook the pasta until al dente, toss everything with
+	grated cheese and black pepper, then serve immediately with a green salad and
+	a glass of cold water for the whole family to enjoy together.`)
+	if d := Distance(base, other); d < 15 {
+		t.Fatalf("\ngot:%v \nexpect:>=%v \nmessage:%v", d, 15, "unrelated texts too close")
+	}
+
+	if _, ok := Fingerprint("not found"); ok {
+		t.Fatalf("\ngot:%v \nexpect:%v", ok, false)
+	}
+}
@@ -0,0 +1,159 @@
+// Package sitemap parses sitemap and sitemap index files as described on
+// sitemaps.org, plain or gzip compressed.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxURLs is the maximum number of URLs read from a single sitemap.
+	MaxURLs = 50000
+
+	// MaxSitemaps is the maximum number of sitemaps followed from a sitemap
+	// index.
+	MaxSitemaps = 50
+
+	// maximum uncompressed size of a sitemap
+	maxSize = 50 << 20
+)
+
+// Entry is a URL listed in a sitemap or a sitemap listed in a sitemap index.
+type Entry struct {
+	Loc string
+
+	// The time the page was last modified, zero if not specified.
+	LastMod time.Time
+}
+
+// Sitemap is a parsed sitemap file. Index sitemaps only list other sitemaps.
+type Sitemap struct {
+	URLs     []Entry
+	Sitemaps []Entry
+}
+
+type document struct {
+	XMLName  xml.Name
+	URLs     []entry `xml:"url"`
+	Sitemaps []entry `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// Parse reads a sitemap or sitemap index. Gzip compressed input is detected
+// and decompressed.
+func Parse(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	var doc document
+	if err := xml.NewDecoder(io.LimitReader(r, maxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("sitemap: %v", err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("sitemap: unexpected root element %q", doc.XMLName.Local)
+	}
+
+	sm := &Sitemap{
+		URLs:     entries(doc.URLs, MaxURLs),
+		Sitemaps: entries(doc.Sitemaps, MaxSitemaps),
+	}
+	return sm, nil
+}
+
+func entries(in []entry, max int) []Entry {
+	out := make([]Entry, 0, len(in))
+	for _, e := range in {
+		loc := strings.TrimSpace(e.Loc)
+		if loc == "" {
+			continue
+		}
+		if len(out) == max {
+			break
+		}
+		out = append(out, Entry{Loc: loc, LastMod: parseTime(e.LastMod)})
+	}
+	return out
+}
+
+// W3C datetime formats allowed for lastmod
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Fetch downloads the sitemap at rawURL and returns the URLs it lists. The
+// sitemaps of a sitemap index are fetched as well, nested indexes are not
+// followed.
+func Fetch(ctx context.Context, client *http.Client, userAgent, rawURL string) ([]Entry, error) {
+	sm, err := get(ctx, client, userAgent, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := sm.URLs
+	for _, child := range sm.Sitemaps {
+		csm, err := get(ctx, client, userAgent, child.Loc)
+		if err != nil {
+			if ctx.Err() != nil {
+				return urls, ctx.Err()
+			}
+			continue
+		}
+		urls = append(urls, csm.URLs...)
+	}
+	return urls, nil
+}
+
+func get(ctx context.Context, client *http.Client, userAgent, rawURL string) (*Sitemap, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s: unexpected status %s", rawURL, res.Status)
+	}
+	return Parse(res.Body)
+}
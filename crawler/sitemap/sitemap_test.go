@@ -0,0 +1,92 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc> https://example.com/a </loc><lastmod>2023-11-05</lastmod></url>
+	<url><loc>https://example.com/b</loc><lastmod>2023-11-05T10:30:00+01:00</lastmod></url>
+	<url><loc>https://example.com/c</loc></url>
+	<url><loc></loc></url>
+</urlset>`
+
+func Test_parse(t *testing.T) {
+	t.Run("test_urlset", func(t *testing.T) {
+		sm, err := Parse(strings.NewReader(urlset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sm.URLs) != 3 {
+			t.Fatalf("\ngot:%v \nexpect:%v", len(sm.URLs), 3)
+		}
+		if sm.URLs[0].Loc != "https://example.com/a" {
+			t.Fatalf("\ngot:%v \nexpect:%v", sm.URLs[0].Loc, "https://example.com/a")
+		}
+		expect := time.Date(2023, 11, 5, 9, 30, 0, 0, time.UTC)
+		if !sm.URLs[1].LastMod.Equal(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", sm.URLs[1].LastMod, expect)
+		}
+		if !sm.URLs[2].LastMod.IsZero() {
+			t.Fatalf("\ngot:%v \nexpect:%v", sm.URLs[2].LastMod, time.Time{})
+		}
+	})
+
+	t.Run("test_gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(urlset))
+		_ = zw.Close()
+
+		sm, err := Parse(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sm.URLs) != 3 {
+			t.Fatalf("\ngot:%v \nexpect:%v", len(sm.URLs), 3)
+		}
+	})
+
+	t.Run("test_not_a_sitemap", func(t *testing.T) {
+		if _, err := Parse(strings.NewReader(`<html><body></body></html>`)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func Test_fetch_index(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<sitemap><loc>%[1]s/posts.xml.gz</loc></sitemap>
+				<sitemap><loc>%[1]s/missing.xml</loc></sitemap>
+			</sitemapindex>`, srv.URL)
+		case "/posts.xml.gz":
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte(urlset))
+			_ = zw.Close()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	urls, err := Fetch(context.Background(), srv.Client(), "test", srv.URL+"/sitemap_index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 {
+		t.Fatalf("\ngot:%v \nexpect:%v", len(urls), 3)
+	}
+}
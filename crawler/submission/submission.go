@@ -0,0 +1,77 @@
+// Package submission defines the queue of links submitted by users. The
+// crawler drains the queue ahead of its regular passes, so a submitted link is
+// crawled within seconds, and records the progress of every submission for
+// the submitter to follow.
+package submission
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State is the progress of a submission.
+type State string
+
+const (
+	// The link waits to be crawled.
+	Queued State = "queued"
+
+	// The link was claimed by a crawler and is being crawled.
+	Fetching State = "fetching"
+
+	// The page of the link was crawled and indexed.
+	Indexed State = "indexed"
+
+	// The page of the link could not be crawled or was not indexed, see
+	// Submission.Reason.
+	Failed State = "failed"
+
+	// The page of the link did not change since its last crawl, it is only
+	// indexed if it was indexed after that crawl.
+	Unchanged State = "unchanged"
+)
+
+// Done reports whether the submission reached a final state.
+func (s State) Done() bool {
+	return s == Indexed || s == Failed || s == Unchanged
+}
+
+// Submission is a link submitted by a user.
+type Submission struct {
+	ID     uuid.UUID
+	LinkID uuid.UUID
+	URL    string
+
+	State State
+
+	// Why the submission failed or a note on how it was indexed, e.g. that
+	// the page did not change since its last crawl.
+	Reason string
+
+	SubmittedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// Store is implemented by persistent submission queues.
+type Store interface {
+	// Submit queues the link of linkID, which must already be part of the
+	// link graph.
+	Submit(linkID uuid.UUID, url string) (*Submission, error)
+
+	// Submission returns the submission of id, nil if there is none.
+	Submission(id uuid.UUID) (*Submission, error)
+
+	// Claim moves up to n queued submissions, oldest first, to Fetching and
+	// returns them. Submissions that are still Fetching after lease, e.g.
+	// because their crawler stopped, are claimed again.
+	Claim(n int, lease time.Duration) ([]Submission, error)
+
+	// Finish moves a claimed submission to a final state.
+	Finish(id uuid.UUID, state State, reason string) error
+
+	// Requeue moves claimed submissions back to Queued, e.g. because their
+	// crawl failed or was stopped, so they are claimed again without
+	// waiting for their lease to expire.
+	Requeue(ids []uuid.UUID) error
+}
@@ -0,0 +1,90 @@
+package submissionapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+var _ Queue = (*Client)(nil)
+
+// Client is a client of the API served by NewHandler.
+type Client struct {
+	addr   string
+	client *http.Client
+}
+
+// NewClient creates a client of the API served at addr, e.g.
+// "http://crawler:8585".
+func NewClient(addr string) *Client {
+	return &Client{
+		addr:   strings.TrimSuffix(addr, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Submit implements Queue.
+func (c *Client) Submit(linkID uuid.UUID, url string) (*submission.Submission, error) {
+	var res submissionResponse
+	if err := c.do(http.MethodPost, submissionsEndpoint, submitRequest{LinkID: linkID, URL: url}, &res); err != nil {
+		return nil, fmt.Errorf("submit: %v", err)
+	}
+	return res.Submission, nil
+}
+
+// Submission implements Queue.
+func (c *Client) Submission(id uuid.UUID) (*submission.Submission, error) {
+	var res submissionResponse
+	if err := c.do(http.MethodGet, submissionsEndpoint+"/"+id.String(), nil, &res); err != nil {
+		return nil, fmt.Errorf("submission: %v", err)
+	}
+	return res.Submission, nil
+}
+
+// SubmitSitemap implements Queue.
+func (c *Client) SubmitSitemap(url string) error {
+	if err := c.do(http.MethodPost, sitemapsEndpoint, sitemapRequest{URL: url}, nil); err != nil {
+		return fmt.Errorf("submit sitemap: %v", err)
+	}
+	return nil
+}
+
+// do sends body as JSON and decodes the JSON response into res, both may be
+// nil.
+func (c *Client) do(method, path string, body, res any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.addr+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,98 @@
+package submissionapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+var (
+	submissionsEndpoint = "/submissions"
+	sitemapsEndpoint    = "/sitemaps"
+)
+
+// submitRequest is the body of a request to queue a link.
+type submitRequest struct {
+	LinkID uuid.UUID
+	URL    string
+}
+
+// submissionResponse is the body of the response to a submitRequest or to a
+// request for a submission, Submission is nil if there is none.
+type submissionResponse struct {
+	Submission *submission.Submission
+}
+
+// sitemapRequest is the body of a request to queue a sitemap.
+type sitemapRequest struct {
+	URL string
+}
+
+// NewHandler returns the HTTP handler serving the API of q.
+func NewHandler(q Queue) http.Handler {
+	h := &handler{q: q}
+	r := chi.NewMux()
+	r.Post(submissionsEndpoint, h.submit)
+	r.Get(submissionsEndpoint+"/{id}", h.submission)
+	r.Post(sitemapsEndpoint, h.submitSitemap)
+	return r
+}
+
+type handler struct {
+	q Queue
+}
+
+func (h *handler) submit(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LinkID == uuid.Nil || req.URL == "" {
+		http.Error(w, "invalid submission", http.StatusBadRequest)
+		return
+	}
+	sub, err := h.q.Submit(req.LinkID, req.URL)
+	if err != nil {
+		log.Println("submit:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, submissionResponse{Submission: sub})
+}
+
+func (h *handler) submission(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid submission id", http.StatusBadRequest)
+		return
+	}
+	sub, err := h.q.Submission(id)
+	if err != nil {
+		log.Println("submission:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, submissionResponse{Submission: sub})
+}
+
+func (h *handler) submitSitemap(w http.ResponseWriter, r *http.Request) {
+	var req sitemapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		http.Error(w, "invalid sitemap", http.StatusBadRequest)
+		return
+	}
+	if err := h.q.SubmitSitemap(req.URL); err != nil {
+		log.Println("submit sitemap:", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("submissionapi:", err)
+	}
+}
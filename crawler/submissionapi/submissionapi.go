@@ -0,0 +1,26 @@
+// Package submissionapi is the API of the crawler for the links and sitemaps
+// submitted by users. It lets the UI queue a link for the submission lane of
+// the crawler and follow its progress, and queue a sitemap for the crawler to
+// ingest, without access to the crawler's database. It is served over HTTP by
+// the crawler.
+package submissionapi
+
+import (
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+// Queue is implemented by the submission queue of the crawler and the clients
+// of its API.
+type Queue interface {
+	// Submit queues the link of linkID, which must already be part of the
+	// link graph.
+	Submit(linkID uuid.UUID, url string) (*submission.Submission, error)
+
+	// Submission returns the submission of id, nil if there is none.
+	Submission(id uuid.UUID) (*submission.Submission, error)
+
+	// SubmitSitemap queues the sitemap at url, the URLs it lists are added
+	// to the link graph and crawled in the background.
+	SubmitSitemap(url string) error
+}
@@ -0,0 +1,106 @@
+package submissionapi
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/crawler/submission"
+)
+
+// memQueue keeps the submissions and sitemaps in memory.
+type memQueue struct {
+	subs     map[uuid.UUID]*submission.Submission
+	sitemaps []string
+}
+
+func (m *memQueue) Submit(linkID uuid.UUID, url string) (*submission.Submission, error) {
+	if url == "https://fail.example.com/" {
+		return nil, fmt.Errorf("queue is down")
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	sub := &submission.Submission{
+		ID:          uuid.New(),
+		LinkID:      linkID,
+		URL:         url,
+		State:       submission.Queued,
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	m.subs[sub.ID] = sub
+	return sub, nil
+}
+
+func (m *memQueue) Submission(id uuid.UUID) (*submission.Submission, error) {
+	return m.subs[id], nil
+}
+
+func (m *memQueue) SubmitSitemap(url string) error {
+	if len(m.sitemaps) == 1 {
+		return fmt.Errorf("sitemap queue is full")
+	}
+	m.sitemaps = append(m.sitemaps, url)
+	return nil
+}
+
+func Test_client(t *testing.T) {
+	q := &memQueue{subs: make(map[uuid.UUID]*submission.Submission)}
+	srv := httptest.NewServer(NewHandler(q))
+	defer srv.Close()
+	c := NewClient(srv.URL + "/")
+
+	linkID := uuid.New()
+	var submitted *submission.Submission
+
+	t.Run("test_submit", func(t *testing.T) {
+		sub, err := c.Submit(linkID, "https://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sub.ID == uuid.Nil || sub.LinkID != linkID || sub.State != submission.Queued {
+			t.Fatalf("\ngot:%+v \nexpect:%v", sub, "a queued submission of the link")
+		}
+		submitted = sub
+	})
+
+	t.Run("test_submission", func(t *testing.T) {
+		q.subs[submitted.ID].State = submission.Indexed
+		sub, err := c.Submission(submitted.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sub == nil || sub.State != submission.Indexed || !sub.SubmittedAt.Equal(submitted.SubmittedAt) {
+			t.Fatalf("\ngot:%+v \nexpect:%+v", sub, q.subs[submitted.ID])
+		}
+	})
+
+	t.Run("test_unknown_submission", func(t *testing.T) {
+		sub, err := c.Submission(uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sub != nil {
+			t.Fatalf("\ngot:%+v \nexpect:%v", sub, nil)
+		}
+	})
+
+	t.Run("test_error", func(t *testing.T) {
+		if _, err := c.Submit(linkID, "https://fail.example.com/"); err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "an error")
+		}
+	})
+
+	t.Run("test_submit_sitemap", func(t *testing.T) {
+		if err := c.SubmitSitemap("https://example.com/sitemap.xml"); err != nil {
+			t.Fatal(err)
+		}
+		if len(q.sitemaps) != 1 || q.sitemaps[0] != "https://example.com/sitemap.xml" {
+			t.Fatalf("\ngot:%v \nexpect:%v", q.sitemaps, "https://example.com/sitemap.xml")
+		}
+		if err := c.SubmitSitemap("https://example.com/other.xml"); err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "an error")
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package trap
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	rejectedURLs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "crawler",
+		Subsystem: "trap",
+		Name:      "rejected_urls_total",
+		Help:      "Number of discovered URLs rejected as a crawler trap by reason.",
+	}, []string{"reason"})
+
+	flaggedHosts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "crawler",
+		Subsystem: "trap",
+		Name:      "flagged_hosts",
+		Help:      "Number of hosts flagged as a crawler trap.",
+	})
+)
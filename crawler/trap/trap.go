@@ -0,0 +1,389 @@
+// Package trap detects crawler traps, URL spaces without end such as calendar
+// widgets that link to the next month forever or session IDs that give every
+// visit its own URLs. A detector is asked about every discovered URL before it
+// is added to the link graph. URLs that look like a trap are rejected and the
+// host they belong to is flagged, after which only a trickle of new URLs of
+// the host is accepted.
+package trap
+
+import (
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reason is the reason a URL is rejected, empty if it is accepted.
+type Reason string
+
+const (
+	// The URL is accepted.
+	Accepted Reason = ""
+
+	// The host reached its budget of distinct URLs.
+	HostBudget Reason = "host_budget"
+
+	// A segment occurs too often in the path of the URL, e.g. /a/b/a/b/a/b.
+	RepeatedSegments Reason = "repeated_segments"
+
+	// The URL has too many query parameters.
+	QueryParams Reason = "query_params"
+
+	// The path of the URL was seen with too many different queries.
+	QueryVariants Reason = "query_variants"
+
+	// Too many URLs of the host share the pattern of the URL, e.g.
+	// /calendar/#/#.
+	URLPattern Reason = "url_pattern"
+
+	// The host is flagged and used up the new URLs it may add in the
+	// current throttle interval.
+	Throttled Reason = "throttled"
+)
+
+// Flag records why and when a host was flagged.
+type Flag struct {
+	Host      string
+	Reason    Reason
+	FlaggedAt time.Time
+}
+
+// Store is implemented by persistent backends of flagged hosts, so that a
+// host stays flagged after a restart of the crawler.
+type Store interface {
+	// FlagHost saves a flagged host.
+	FlagHost(f Flag) error
+
+	// FlaggedHosts returns every flagged host.
+	FlaggedHosts() ([]Flag, error)
+}
+
+// Config encapsulates the settings for a Detector.
+type Config struct {
+	// The maximum number of distinct URLs accepted per host before the host
+	// is flagged. If not specified, a default value of 50000 will be used
+	// instead.
+	HostBudget int
+
+	// The maximum number of times a segment may occur in the path of a URL.
+	// If not specified, a default value of 2 will be used instead.
+	MaxRepeatedSegments int
+
+	// The maximum number of query parameters of a URL. If not specified, a
+	// default value of 10 will be used instead.
+	MaxQueryParams int
+
+	// The maximum number of different queries accepted for a path before
+	// its host is flagged. If not specified, a default value of 1000 will
+	// be used instead.
+	MaxQueryVariants int
+
+	// The maximum number of distinct URLs accepted per URL pattern of a host
+	// before the host is flagged. The pattern of a URL is its path with
+	// every segment that contains a digit replaced by '#', followed by the
+	// sorted names of its query parameters. If not specified, a default
+	// value of 10000 will be used instead.
+	MaxPatternCardinality int
+
+	// The number of new URLs of a flagged host accepted per ThrottleInterval.
+	// If not specified, default values of 10 URLs per hour will be used
+	// instead.
+	ThrottledURLs    int
+	ThrottleInterval time.Duration
+}
+
+func (cfg *Config) validate() {
+	if cfg.HostBudget <= 0 {
+		cfg.HostBudget = 50000
+	}
+	if cfg.MaxRepeatedSegments <= 0 {
+		cfg.MaxRepeatedSegments = 2
+	}
+	if cfg.MaxQueryParams <= 0 {
+		cfg.MaxQueryParams = 10
+	}
+	if cfg.MaxQueryVariants <= 0 {
+		cfg.MaxQueryVariants = 1000
+	}
+	if cfg.MaxPatternCardinality <= 0 {
+		cfg.MaxPatternCardinality = 10000
+	}
+	if cfg.ThrottledURLs <= 0 {
+		cfg.ThrottledURLs = 10
+	}
+	if cfg.ThrottleInterval <= 0 {
+		cfg.ThrottleInterval = time.Hour
+	}
+}
+
+type hostState struct {
+	// the hashes of the accepted URLs
+	seen map[uint64]struct{}
+
+	// the number of accepted URLs per URL pattern and per path with a query
+	patterns map[string]int
+	variants map[string]int
+
+	// nil unless the host is flagged
+	flag *Flag
+
+	// the start of the current throttle interval and the URLs accepted in it
+	window   time.Time
+	accepted int
+}
+
+// Detector tracks the URLs accepted per host. It is safe for concurrent use.
+type Detector struct {
+	cfg Config
+	now func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+
+	// set for a clone, which does not update the metrics
+	quiet bool
+}
+
+// New creates a detector with the specified config.
+func New(cfg Config) *Detector {
+	cfg.validate()
+	return &Detector{
+		cfg:   cfg,
+		now:   time.Now,
+		hosts: make(map[string]*hostState),
+	}
+}
+
+// Clone returns a copy of d that checks URLs against the state of d without
+// changing d or the metrics, e.g. for a dry run.
+func (d *Detector) Clone() *Detector {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := &Detector{cfg: d.cfg, now: d.now, hosts: make(map[string]*hostState, len(d.hosts)), quiet: true}
+	for name, h := range d.hosts {
+		hc := &hostState{
+			seen:     make(map[uint64]struct{}, len(h.seen)),
+			patterns: make(map[string]int, len(h.patterns)),
+			variants: make(map[string]int, len(h.variants)),
+			window:   h.window,
+			accepted: h.accepted,
+		}
+		for k := range h.seen {
+			hc.seen[k] = struct{}{}
+		}
+		for k, v := range h.patterns {
+			hc.patterns[k] = v
+		}
+		for k, v := range h.variants {
+			hc.variants[k] = v
+		}
+		if h.flag != nil {
+			f := *h.flag
+			hc.flag = &f
+		}
+		c.hosts[name] = hc
+	}
+	return c
+}
+
+// Restore flags the hosts of flags, e.g. the hosts loaded from a Store.
+func (d *Detector) Restore(flags []Flag) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range flags {
+		f := flags[i]
+		h := d.host(f.Host)
+		if h.flag == nil && !d.quiet {
+			flaggedHosts.Inc()
+		}
+		h.flag = &f
+	}
+}
+
+// Flagged returns the flagged hosts.
+func (d *Detector) Flagged() []Flag {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var flags []Flag
+	for _, h := range d.hosts {
+		if h.flag != nil {
+			flags = append(flags, *h.flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Host < flags[j].Host })
+	return flags
+}
+
+// Check decides whether the canonical URL rawURL is accepted. URLs accepted
+// before are always accepted again. If the URL gets its host flagged, the
+// new flag is returned as well.
+func (d *Detector) Check(rawURL string) (Reason, *Flag) {
+	if d == nil {
+		return Accepted, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Accepted, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h := d.host(u.Host)
+	sum := hash(rawURL)
+	if _, ok := h.seen[sum]; ok {
+		return Accepted, nil
+	}
+
+	// the URL itself looks like a trap
+	if repeatedSegments(u.EscapedPath()) > d.cfg.MaxRepeatedSegments {
+		return d.reject(RepeatedSegments), nil
+	}
+	query := u.Query()
+	if len(query) > d.cfg.MaxQueryParams {
+		return d.reject(QueryParams), nil
+	}
+
+	// the host generates URLs without end
+	var flag *Flag
+	pattern := patternOf(u.EscapedPath(), query)
+	switch {
+	case h.patterns[pattern] >= d.cfg.MaxPatternCardinality:
+		return d.reject(URLPattern), d.flag(u.Host, h, URLPattern)
+	case u.RawQuery != "" && h.variants[u.EscapedPath()] >= d.cfg.MaxQueryVariants:
+		return d.reject(QueryVariants), d.flag(u.Host, h, QueryVariants)
+	case len(h.seen) >= d.cfg.HostBudget:
+		flag = d.flag(u.Host, h, HostBudget)
+	}
+
+	// a flagged host only adds a few new URLs per interval
+	if h.flag != nil {
+		now := d.now()
+		if now.Sub(h.window) >= d.cfg.ThrottleInterval {
+			h.window, h.accepted = now, 0
+		}
+		if h.accepted >= d.cfg.ThrottledURLs {
+			if flag != nil {
+				return d.reject(flag.Reason), flag
+			}
+			return d.reject(Throttled), nil
+		}
+		h.accepted++
+	}
+
+	h.seen[sum] = struct{}{}
+	h.patterns[pattern]++
+	if u.RawQuery != "" {
+		h.variants[u.EscapedPath()]++
+	}
+	return Accepted, flag
+}
+
+// Known records the canonical URL rawURL as accepted without checking it, so
+// that it is always accepted from then on. It is meant for the URLs that are
+// already in the link graph when the crawler starts, a flagged host must not
+// throttle the links it already has.
+func (d *Detector) Known(rawURL string) {
+	if d == nil {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h := d.host(u.Host)
+	sum := hash(rawURL)
+	if _, ok := h.seen[sum]; ok {
+		return
+	}
+	h.seen[sum] = struct{}{}
+	h.patterns[patternOf(u.EscapedPath(), u.Query())]++
+	if u.RawQuery != "" {
+		h.variants[u.EscapedPath()]++
+	}
+}
+
+func (d *Detector) host(name string) *hostState {
+	h, ok := d.hosts[name]
+	if !ok {
+		h = &hostState{
+			seen:     make(map[uint64]struct{}),
+			patterns: make(map[string]int),
+			variants: make(map[string]int),
+		}
+		d.hosts[name] = h
+	}
+	return h
+}
+
+// flag flags host and returns the new flag, nil if host is already flagged.
+func (d *Detector) flag(host string, h *hostState, reason Reason) *Flag {
+	if h.flag != nil {
+		return nil
+	}
+	h.flag = &Flag{Host: host, Reason: reason, FlaggedAt: d.now()}
+	if !d.quiet {
+		flaggedHosts.Inc()
+	}
+	f := *h.flag
+	return &f
+}
+
+func (d *Detector) reject(reason Reason) Reason {
+	if !d.quiet {
+		rejectedURLs.WithLabelValues(string(reason)).Inc()
+	}
+	return reason
+}
+
+// repeatedSegments returns how often the most frequent segment of path occurs.
+func repeatedSegments(path string) int {
+	max := 0
+	counts := make(map[string]int)
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			continue
+		}
+		counts[s]++
+		if counts[s] > max {
+			max = counts[s]
+		}
+	}
+	return max
+}
+
+// patternOf returns the pattern of a URL, see Config.MaxPatternCardinality.
+func patternOf(path string, query url.Values) string {
+	var b strings.Builder
+	for _, s := range strings.Split(path, "/") {
+		if strings.ContainsAny(s, "0123456789") {
+			s = "#"
+		}
+		b.WriteString(s)
+		b.WriteByte('/')
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteByte('?')
+	b.WriteString(strings.Join(keys, "&"))
+	return b.String()
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,137 @@
+package trap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_trap(t *testing.T) {
+	t.Run("test_repeated_segments", func(t *testing.T) {
+		d := New(Config{})
+		if r, _ := d.Check("https://a.com/docs/api/docs/api/docs/api"); r != RepeatedSegments {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, RepeatedSegments)
+		}
+		if r, _ := d.Check("https://a.com/docs/api/docs"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+		if flags := d.Flagged(); len(flags) != 0 {
+			t.Fatalf("\ngot:%v \nmessage:%v", flags, "a single trap URL flagged the host")
+		}
+	})
+
+	t.Run("test_query_params", func(t *testing.T) {
+		d := New(Config{MaxQueryParams: 2})
+		if r, _ := d.Check("https://a.com/search?q=go&page=2&sort=asc"); r != QueryParams {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, QueryParams)
+		}
+		if r, _ := d.Check("https://a.com/search?q=go&page=2"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+	})
+
+	t.Run("test_query_variants", func(t *testing.T) {
+		d := New(Config{MaxQueryVariants: 3})
+		var flag *Flag
+		var r Reason
+		for i := 0; i < 4; i++ {
+			r, flag = d.Check(fmt.Sprintf("https://a.com/page?sid=s%c", 'a'+i))
+		}
+		if r != QueryVariants || flag == nil || flag.Host != "a.com" || flag.Reason != QueryVariants {
+			t.Fatalf("\ngot:%v %+v \nexpect:%v", r, flag, QueryVariants)
+		}
+		// a URL accepted before is still accepted
+		if r, _ := d.Check("https://a.com/page?sid=sa"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+	})
+
+	t.Run("test_url_pattern", func(t *testing.T) {
+		d := New(Config{MaxPatternCardinality: 12})
+		var r Reason
+		for month := 1; month <= 13; month++ {
+			r, _ = d.Check(fmt.Sprintf("https://a.com/calendar/2024/%02d", month))
+		}
+		if r != URLPattern {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, URLPattern)
+		}
+		// other pages of the flagged host are throttled, not rejected
+		if r, _ := d.Check("https://a.com/about"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+		if flags := d.Flagged(); len(flags) != 1 || flags[0].Reason != URLPattern {
+			t.Fatalf("\ngot:%v", flags)
+		}
+	})
+
+	t.Run("test_host_budget_and_throttle", func(t *testing.T) {
+		d := New(Config{HostBudget: 5, ThrottledURLs: 2, ThrottleInterval: time.Hour})
+		now := time.Now()
+		d.now = func() time.Time { return now }
+
+		accepted := 0
+		var flag *Flag
+		for i := 0; i < 10; i++ {
+			r, f := d.Check(fmt.Sprintf("https://a.com/page-%c", 'a'+i))
+			if r == Accepted {
+				accepted++
+			}
+			if f != nil {
+				flag = f
+			}
+		}
+		// the budget and the throttled URLs of the first interval
+		if accepted != 7 || flag == nil || flag.Reason != HostBudget {
+			t.Fatalf("\ngot:%v %+v \nexpect:%v", accepted, flag, 7)
+		}
+		if r, _ := d.Check("https://b.com/page-z"); r != Accepted {
+			t.Fatalf("\ngot:%v \nmessage:%v", r, "another host was throttled")
+		}
+
+		now = now.Add(time.Hour)
+		if r, _ := d.Check("https://a.com/page-z"); r != Accepted {
+			t.Fatalf("\ngot:%v \nmessage:%v", r, "throttle interval did not restart")
+		}
+	})
+
+	t.Run("test_restore", func(t *testing.T) {
+		d := New(Config{ThrottledURLs: 1})
+		d.Restore([]Flag{{Host: "a.com", Reason: HostBudget, FlaggedAt: time.Now()}})
+		if r, _ := d.Check("https://a.com/x"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+		if r, _ := d.Check("https://a.com/y"); r != Throttled {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Throttled)
+		}
+	})
+	t.Run("test_clone", func(t *testing.T) {
+		d := New(Config{ThrottledURLs: 1})
+		d.Restore([]Flag{{Host: "a.com", Reason: HostBudget, FlaggedAt: time.Now()}})
+		c := d.Clone()
+		if r, _ := c.Check("https://a.com/x"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+		if r, _ := c.Check("https://a.com/y"); r != Throttled {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", r, Throttled, "clone lost the flag")
+		}
+		// the checks of the clone do not count for the detector
+		if r, _ := d.Check("https://a.com/y"); r != Accepted {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Accepted)
+		}
+	})
+
+	t.Run("test_known", func(t *testing.T) {
+		d := New(Config{ThrottledURLs: 1})
+		d.Restore([]Flag{{Host: "a.com", Reason: HostBudget, FlaggedAt: time.Now()}})
+		d.Known("https://a.com/x")
+		d.Known("https://a.com/y")
+		for _, u := range []string{"https://a.com/x", "https://a.com/y", "https://a.com/z"} {
+			if r, _ := d.Check(u); r != Accepted {
+				t.Fatalf("\ngot:%v \nexpect:%v \nurl:%v", r, Accepted, u)
+			}
+		}
+		if r, _ := d.Check("https://a.com/w"); r != Throttled {
+			t.Fatalf("\ngot:%v \nexpect:%v", r, Throttled)
+		}
+	})
+}
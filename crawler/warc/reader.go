@@ -0,0 +1,114 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// the maximum size of a record block read into memory
+const maxBlockSize = 64 << 20
+
+// Reader reads the records of a WARC file, compressed with gzip or not.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a reader of the WARC file r. Gzip-compressed files are
+// detected by their magic number.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("warc reader: %v", err)
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		// the records are concatenated gzip members
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("warc reader: %v", err)
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, io.EOF at the end of the file.
+func (r *Reader) Next() (*Record, error) {
+	line, err := r.line()
+	for err == nil && line == "" {
+		// the end of the previous record
+		line, err = r.line()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "WARC/1.") {
+		return nil, fmt.Errorf("warc reader: unexpected version line %q", line)
+	}
+
+	rec := &Record{}
+	for {
+		line, err := r.line()
+		if err != nil {
+			return nil, fmt.Errorf("warc reader: %v", unexpectedEOF(err))
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("warc reader: malformed header field %q", line)
+		}
+		rec.Header = append(rec.Header, Field{Name: name, Value: strings.TrimSpace(value)})
+	}
+
+	n, err := strconv.ParseInt(rec.Header.Get("Content-Length"), 10, 64)
+	if err != nil || n < 0 || n > maxBlockSize {
+		return nil, fmt.Errorf("warc reader: invalid content length %q", rec.Header.Get("Content-Length"))
+	}
+	rec.Block = make([]byte, n)
+	if _, err := io.ReadFull(r.r, rec.Block); err != nil {
+		return nil, fmt.Errorf("warc reader: %v", unexpectedEOF(err))
+	}
+	return rec, nil
+}
+
+// line reads a line without its line ending.
+func (r *Reader) line() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// HTTPResponse parses the HTTP response of a response record. The request of
+// the response only carries the target URI of the record.
+func (r *Record) HTTPResponse() (*http.Response, error) {
+	if r.Type() != TypeResponse {
+		return nil, errors.New("warc: not a response record")
+	}
+	req, err := http.NewRequest(http.MethodGet, r.TargetURI(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("warc: %v", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), req)
+	if err != nil {
+		return nil, fmt.Errorf("warc: %v", err)
+	}
+	return res, nil
+}
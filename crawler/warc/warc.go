@@ -0,0 +1,99 @@
+// Package warc reads and writes WARC 1.1 files, the archive format of the
+// requests and responses of a web crawl.
+package warc
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the version line of the records written by this package
+const version = "WARC/1.1"
+
+// The record types written by the crawler.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// Field is a named field of a record header.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header holds the named fields of a record in the order they are written.
+type Header []Field
+
+// Get returns the value of the first field named name, ignoring case, or an
+// empty string if there is none.
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the field named name or adds the field.
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Record is a WARC record.
+type Record struct {
+	Header Header
+	Block  []byte
+}
+
+// NewRecord returns a record of the given type with a new record ID and the
+// digest of block. targetURI is omitted if empty.
+func NewRecord(recordType, targetURI string, date time.Time, contentType string, block []byte) *Record {
+	r := &Record{Block: block}
+	r.Header.Set("WARC-Type", recordType)
+	r.Header.Set("WARC-Record-ID", NewRecordID())
+	r.Header.Set("WARC-Date", date.UTC().Format(time.RFC3339Nano))
+	if targetURI != "" {
+		r.Header.Set("WARC-Target-URI", targetURI)
+	}
+	r.Header.Set("WARC-Block-Digest", Digest(block))
+	r.Header.Set("Content-Type", contentType)
+	return r
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string { return r.Header.Get("WARC-Type") }
+
+// TargetURI returns the WARC-Target-URI of the record.
+func (r *Record) TargetURI() string { return r.Header.Get("WARC-Target-URI") }
+
+// Date returns the WARC-Date of the record, the zero time if it is missing
+// or malformed.
+func (r *Record) Date() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, r.Header.Get("WARC-Date"))
+	return t
+}
+
+// NewRecordID returns a new globally unique record ID.
+func NewRecordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+// Digest returns the SHA-1 digest of b in the form used by the digest fields,
+// e.g. "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ".
+func Digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
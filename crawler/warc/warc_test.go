@@ -0,0 +1,153 @@
+package warc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_warc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>" + strings.Repeat("x", 100) + r.URL.Path + "</html>"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	w, err := NewWriter(dir, "test", 1, "se-crawler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/a", "/b"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limit := int64(1 << 20)
+		if path == "/b" {
+			limit = 10
+		}
+		if err := w.WriteExchange(res.Request, res, limit); err != nil {
+			t.Fatal(err)
+		}
+
+		// the body can still be read by the caller
+		body, _ := io.ReadAll(res.Body)
+		if path == "/a" && !strings.HasSuffix(string(body), "/a</html>") {
+			t.Fatalf("\ngot:%v", string(body))
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.open"))
+	if len(files) != 1 {
+		t.Fatalf("\ngot:%v \nmessage:%v", files, "expected one open file")
+	}
+
+	// the records of the open file are flushed as they are written
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for ; ; n++ {
+		if _, err := r.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+	if n != 3 {
+		t.Fatalf("\ngot:%v \nexpect:%v", n, 3)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// every exchange went to its own file as the maximum size is exceeded
+	// by the first record
+	files, _ = filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	if len(files) != 2 {
+		t.Fatalf("\ngot:%v \nexpect:%v", files, 2)
+	}
+
+	var records []*Record
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, rec)
+		}
+		f.Close()
+	}
+
+	var types []string
+	for _, rec := range records {
+		types = append(types, rec.Type())
+		if got := rec.Header.Get("WARC-Block-Digest"); got != Digest(rec.Block) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, Digest(rec.Block))
+		}
+	}
+	expect := "warcinfo request response warcinfo request response"
+	if strings.Join(types, " ") != expect {
+		t.Fatalf("\ngot:%v \nexpect:%v", types, expect)
+	}
+
+	t.Run("test_response", func(t *testing.T) {
+		if records[1].Header.Get("WARC-Concurrent-To") != records[2].Header.Get("WARC-Record-ID") {
+			t.Fatal("request does not refer to its response")
+		}
+		if !strings.HasPrefix(string(records[1].Block), "GET /a HTTP/1.1\r\n") {
+			t.Fatalf("\ngot:%q", records[1].Block)
+		}
+
+		res, err := records[2].HTTPResponse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/html" || !strings.HasSuffix(string(body), "/a</html>") {
+			t.Fatalf("\ngot:%v %v %q", res.StatusCode, res.Header, body)
+		}
+		if res.Request.URL.String() != srv.URL+"/a" || records[2].Date().IsZero() {
+			t.Fatalf("\ngot:%v %v", res.Request.URL, records[2].Date())
+		}
+	})
+
+	t.Run("test_truncated", func(t *testing.T) {
+		rec := records[5]
+		if rec.Header.Get("WARC-Truncated") != "length" {
+			t.Fatal("truncated body not marked")
+		}
+		res, err := rec.HTTPResponse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		if len(body) != 10 {
+			t.Fatalf("\ngot:%v \nexpect:%v", len(body), 10)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// the suffix of a file that is still written to, it is removed when the file
+// is complete
+const openSuffix = ".open"
+
+// Writer writes records to gzip-compressed WARC files in a directory. Every
+// record is compressed as its own gzip member so the records of a file can
+// be read independently. A new file is started when the current one exceeds
+// the maximum size, files that are still written to end in ".open".
+type Writer struct {
+	dir      string
+	prefix   string
+	maxSize  int64
+	software string
+
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	name   string
+	size   int64
+	serial int
+}
+
+// NewWriter returns a writer of the files named prefix-TIMESTAMP-SERIAL.warc.gz
+// in dir that start a new file after maxSize compressed bytes. The files
+// start with a warcinfo record naming software.
+func NewWriter(dir, prefix string, maxSize int64, software string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("warc writer: %v", err)
+	}
+	return &Writer{
+		dir:      dir,
+		prefix:   prefix,
+		maxSize:  maxSize,
+		software: software,
+	}, nil
+}
+
+// WriteRecord writes r to the current file, starting a new file first if the
+// current one is full.
+func (w *Writer) WriteRecord(r *Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(); err != nil {
+		return err
+	}
+	return w.write(r)
+}
+
+// WriteExchange writes a request record of req and a response record of res
+// that refer to each other. The body of res is read up to limit bytes and
+// replaced with what was read, so the caller can still read it. A longer body
+// is archived truncated.
+func (w *Writer) WriteExchange(req *http.Request, res *http.Response, limit int64) error {
+	date := time.Now()
+	body, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	res.Body.Close()
+	truncated := int64(len(body)) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+
+	var reqBlock bytes.Buffer
+	if err := req.Write(&reqBlock); err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+
+	// the body is archived as the client read it, without the transfer
+	// encoding and the content encoding the transport removed
+	archived := *res
+	archived.Body = io.NopCloser(bytes.NewReader(body))
+	archived.ContentLength = int64(len(body))
+	archived.TransferEncoding = nil
+	var resBlock bytes.Buffer
+	if err := archived.Write(&resBlock); err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+
+	target := req.URL.String()
+	response := NewRecord(TypeResponse, target, date, "application/http;msgtype=response", resBlock.Bytes())
+	response.Header.Set("WARC-Payload-Digest", Digest(body))
+	if truncated {
+		response.Header.Set("WARC-Truncated", "length")
+	}
+	request := NewRecord(TypeRequest, target, date, "application/http;msgtype=request", reqBlock.Bytes())
+	request.Header.Set("WARC-Concurrent-To", response.Header.Get("WARC-Record-ID"))
+
+	// both records of an exchange go to the same file
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(); err != nil {
+		return err
+	}
+	if err := w.write(request); err != nil {
+		return err
+	}
+	return w.write(response)
+}
+
+// Close completes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// rotate starts a new file if there is none or the current one is full,
+// w.mu must be held.
+func (w *Writer) rotate() error {
+	if w.f != nil && w.size >= w.maxSize {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	if w.f == nil {
+		return w.openFile()
+	}
+	return nil
+}
+
+// write writes r to the current file, w.mu must be held. The record is
+// flushed to the file so a crash does not lose the records written so far.
+func (w *Writer) write(r *Record) error {
+	n, err := writeMember(w.w, r)
+	w.size += n
+	if err == nil {
+		err = w.w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+	return nil
+}
+
+// openFile starts a new file with a warcinfo record, w.mu must be held.
+func (w *Writer) openFile() error {
+	w.serial++
+	w.name = filepath.Join(w.dir, fmt.Sprintf("%s-%s-%05d.warc.gz",
+		w.prefix, time.Now().UTC().Format("20060102150405"), w.serial))
+
+	f, err := os.OpenFile(w.name+openSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+	w.f, w.w, w.size = f, bufio.NewWriter(f), 0
+
+	info := "software: " + w.software + "\r\n" +
+		"format: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+	r := NewRecord(TypeWarcinfo, "", time.Now(), "application/warc-fields", []byte(info))
+	r.Header.Set("WARC-Filename", filepath.Base(w.name))
+	return w.write(r)
+}
+
+// closeFile completes the current file, w.mu must be held.
+func (w *Writer) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+	f := w.f
+	w.f = nil
+	if err := w.w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("warc writer: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+	if err := os.Rename(w.name+openSuffix, w.name); err != nil {
+		return fmt.Errorf("warc writer: %v", err)
+	}
+	return nil
+}
+
+// writeMember writes r as a gzip member and returns the number of compressed
+// bytes written. The member is closed even if the record fails to write, so
+// the records that follow it can still be read.
+func writeMember(w io.Writer, r *Record) (int64, error) {
+	cw := &countingWriter{w: w}
+	gz := gzip.NewWriter(cw)
+	err := writeRecord(gz, r)
+	if cerr := gz.Close(); err == nil {
+		err = cerr
+	}
+	return cw.n, err
+}
+
+// writeRecord writes the uncompressed record, the Content-Length field is
+// set to the length of the block.
+func writeRecord(w io.Writer, r *Record) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(version + "\r\n")
+	header := append(Header(nil), r.Header...)
+	header.Set("Content-Length", strconv.Itoa(len(r.Block)))
+	for _, f := range header {
+		bw.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	bw.WriteString("\r\n")
+	bw.Write(r.Block)
+	bw.WriteString("\r\n\r\n")
+	return bw.Flush()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
@@ -12,6 +12,7 @@ require (
 	github.com/odit-bit/webcrawler v0.0.1
 	github.com/prometheus/client_golang v1.17.0
 	go.uber.org/multierr v1.11.0
+	golang.org/x/net v0.17.0
 )
 
 require (
@@ -29,7 +30,6 @@ require (
 	github.com/prometheus/common v0.44.0 // indirect
 	github.com/prometheus/procfs v0.11.1 // indirect
 	golang.org/x/crypto v0.14.0 // indirect
-	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sync v0.5.0 // indirect
 	golang.org/x/sys v0.14.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
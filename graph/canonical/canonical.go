@@ -0,0 +1,224 @@
+// Package canonical normalizes URLs before they enter the link graph so that
+// different spellings of the same address end up as a single link.
+package canonical
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams are the query parameters stripped by Default. A
+// trailing '*' matches any parameter with that prefix.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// Default is a Canonicalizer stripping DefaultTrackingParams.
+var Default = New(DefaultTrackingParams)
+
+// URL canonicalizes rawURL using Default.
+func URL(rawURL string) (string, error) {
+	return Default.URL(rawURL)
+}
+
+// Canonicalizer rewrites http(s) URLs into their canonical form:
+//   - scheme and host are lowercased
+//   - default ports are removed
+//   - percent-encoded unreserved characters in the path are decoded and
+//     the hex digits of other escapes are uppercased
+//   - dot-segments in the path are resolved and an empty path becomes "/",
+//     empty segments are kept
+//   - tracking parameters are removed and the remaining query parameters
+//     are sorted by key, they are not re-encoded
+//   - the fragment is removed
+type Canonicalizer struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// New creates a Canonicalizer that strips the given tracking parameters. A
+// trailing '*' matches any parameter with that prefix, e.g. "utm_*".
+func New(trackingParams []string) *Canonicalizer {
+	c := &Canonicalizer{exact: make(map[string]bool)}
+	for _, p := range trackingParams {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if strings.HasSuffix(p, "*") {
+			c.prefixes = append(c.prefixes, strings.TrimSuffix(p, "*"))
+			continue
+		}
+		c.exact[p] = true
+	}
+	return c
+}
+
+// URL returns the canonical form of rawURL. Only absolute http and https
+// URLs are accepted.
+func (c *Canonicalizer) URL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if err := c.canonicalize(u); err != nil {
+		return "", fmt.Errorf("canonical %q: %v", rawURL, err)
+	}
+	return u.String(), nil
+}
+
+// Resolve resolves ref against base and returns the canonical form of the
+// result. It is used for relative references such as <link rel=canonical>.
+func (c *Canonicalizer) Resolve(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	return c.URL(b.ResolveReference(r).String())
+}
+
+func (c *Canonicalizer) canonicalize(u *url.URL) error {
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 literal
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && !isDefaultPort(u.Scheme, port) {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.RawPath = cleanPath(normalizeEscapes(u.EscapedPath()))
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	u.RawQuery = c.cleanQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	return nil
+}
+
+func isDefaultPort(scheme, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+// normalizeEscapes decodes the percent-encoded unreserved characters of an
+// escaped path and uppercases the hex digits of the other escapes. Reserved
+// characters stay encoded, "a%2Fb" is a different path than "a/b".
+func normalizeEscapes(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			b.WriteByte(p[i])
+			continue
+		}
+		c := unhex(p[i+1])<<4 | unhex(p[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(p[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isUnreserved reports whether c is an unreserved character of RFC 3986.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// cleanPath resolves the dot-segments of an escaped path as in RFC 3986,
+// unlike path.Clean it keeps empty segments, "/a//b" is a different path than
+// "/a/b". A trailing slash is kept, it is significant for most servers.
+func cleanPath(p string) string {
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	cleaned := make([]string, 0, len(segments))
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch seg {
+		case ".":
+		case "..":
+			if len(cleaned) > 0 {
+				cleaned = cleaned[:len(cleaned)-1]
+			}
+		default:
+			cleaned = append(cleaned, seg)
+			continue
+		}
+		if last {
+			cleaned = append(cleaned, "")
+		}
+	}
+	return "/" + strings.Join(cleaned, "/")
+}
+
+// cleanQuery removes tracking parameters from a raw query and sorts the rest
+// by key. The parameters are kept as they are written, including their
+// encoding, parameters without a value and ';' in a pair.
+func (c *Canonicalizer) cleanQuery(rawQuery string) string {
+	type param struct{ key, raw string }
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(raw, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if c.isTracking(key) {
+			continue
+		}
+		params = append(params, param{key: key, raw: raw})
+	}
+	sort.SliceStable(params, func(i, j int) bool { return params[i].key < params[j].key })
+
+	raw := make([]string, len(params))
+	for i, p := range params {
+		raw[i] = p.raw
+	}
+	return strings.Join(raw, "&")
+}
+
+func (c *Canonicalizer) isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if c.exact[key] {
+		return true
+	}
+	for _, p := range c.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package canonical
+
+import "testing"
+
+func Test_canonical_url(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect string
+	}{
+		{"HTTP://Example.COM", "http://example.com/"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"http://example.com/a/./b/../c", "http://example.com/a/c"},
+		{"http://example.com/a/b/", "http://example.com/a/b/"},
+		{"http://example.com/a/b/..", "http://example.com/a/"},
+		{"http://example.com/../a", "http://example.com/a"},
+		{"http://example.com/?b=2&a=1&a=0", "http://example.com/?a=1&a=0&b=2"},
+		{"http://example.com/?utm_source=x&utm_medium=y&id=1", "http://example.com/?id=1"},
+		{"http://example.com/?fbclid=1&GCLID=2", "http://example.com/"},
+		{"http://example.com/page#section", "http://example.com/page"},
+		{"http://example.com/page?", "http://example.com/page"},
+		{"http://Example.com./", "http://example.com/"},
+		{"http://[::1]:80/", "http://[::1]/"},
+		{"http://example.com/a%20b", "http://example.com/a%20b"},
+		{"http://example.com/a%2Fb", "http://example.com/a%2Fb"},
+		{"http://example.com/a%2fb/%7euser/%41", "http://example.com/a%2Fb/~user/A"},
+		{"http://example.com/a/%2E%2E/b", "http://example.com/b"},
+		{"http://example.com/a//b/", "http://example.com/a//b/"},
+		{"http://example.com/a//../b", "http://example.com/a/b"},
+		{"http://example.com/?b=1;c=2&a=1", "http://example.com/?a=1&b=1;c=2"},
+		{"http://example.com/?a", "http://example.com/?a"},
+		{"http://example.com/?q=a+b%20c&utm_source=x", "http://example.com/?q=a+b%20c"},
+	}
+
+	for _, tt := range tests {
+		got, err := URL(tt.in)
+		if err != nil {
+			t.Fatalf("\ninput:%v \nmessage:%v", tt.in, err)
+		}
+		if got != tt.expect {
+			t.Fatalf("\ninput:%v \ngot:%v \nexpect:%v", tt.in, got, tt.expect)
+		}
+	}
+
+	for _, in := range []string{"mailto:a@example.com", "javascript:void(0)", "/relative/path", "ftp://example.com"} {
+		if got, err := URL(in); err == nil {
+			t.Fatalf("\ninput:%v \ngot:%v \nmessage:%v", in, got, "expected an error")
+		}
+	}
+}
+
+func Test_canonical_config(t *testing.T) {
+	t.Run("test_custom_params", func(t *testing.T) {
+		c := New([]string{"ref", "mc_*"})
+		got, err := c.URL("http://example.com/?ref=x&mc_cid=1&utm_source=y")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := "http://example.com/?utm_source=y"
+		if got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	})
+
+	t.Run("test_resolve", func(t *testing.T) {
+		got, err := Default.Resolve("http://example.com/a/b?x=1", "../c?utm_campaign=z")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := "http://example.com/c"
+		if got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+	})
+}
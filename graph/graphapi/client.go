@@ -0,0 +1,124 @@
+package graphapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+var _ Graph = (*Client)(nil)
+
+// Client is a client of the API served by NewHandler.
+type Client struct {
+	addr   string
+	client *http.Client
+}
+
+// NewClient creates a client of the API served at addr, e.g.
+// "http://graph:8182".
+func NewClient(addr string) *Client {
+	return &Client{
+		addr:   strings.TrimSuffix(addr, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// UpsertOutlinks implements Graph.
+func (c *Client) UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error {
+	var res outlinksRequest
+	if err := c.do(http.MethodPost, outlinksEndpoint, outlinksRequest{Src: src, Links: links}, &res); err != nil {
+		return fmt.Errorf("upsert outlinks: %v", err)
+	}
+	if res.Src == nil || len(res.Links) != len(links) {
+		return fmt.Errorf("upsert outlinks: got %d links, expected %d", len(res.Links), len(links))
+	}
+	*src = *res.Src
+	for i, link := range res.Links {
+		links[i].Link, links[i].New = link.Link, link.New
+	}
+	return nil
+}
+
+// UpsertAnchoredEdge implements Graph.
+func (c *Client) UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error {
+	var res edgeResponse
+	if err := c.do(http.MethodPost, edgesEndpoint, edgeRequest{Edge: edge, Text: text, Rel: rel}, &res); err != nil {
+		return fmt.Errorf("upsert anchored edge: %v", err)
+	}
+	edge.ID, edge.UpdateAt = res.Edge.ID, res.Edge.UpdateAt
+	return nil
+}
+
+// AnchorText implements Graph.
+func (c *Client) AnchorText(dst uuid.UUID) (string, error) {
+	var res anchorTextResponse
+	if err := c.do(http.MethodGet, linksEndpoint+"/"+dst.String()+"/anchor-text", nil, &res); err != nil {
+		return "", fmt.Errorf("anchor text: %v", err)
+	}
+	return res.Text, nil
+}
+
+// UpsertAlias implements Graph.
+func (c *Client) UpsertAlias(edge *linkgraph.Edge) error {
+	var res edgeResponse
+	if err := c.do(http.MethodPost, aliasesEndpoint, edgeRequest{Edge: edge}, &res); err != nil {
+		return fmt.Errorf("upsert alias: %v", err)
+	}
+	edge.ID, edge.UpdateAt = res.Edge.ID, res.Edge.UpdateAt
+	return nil
+}
+
+// RecordFetch implements Graph.
+func (c *Client) RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error) {
+	req := fetchRequest{Code: code}
+	if fetchErr != nil {
+		req.Err = fetchErr.Error()
+	}
+	var res fetchResponse
+	if err := c.do(http.MethodPost, linksEndpoint+"/"+linkID.String()+"/fetches", req, &res); err != nil {
+		return linkstatus.Status{}, fmt.Errorf("record fetch: %v", err)
+	}
+	return res.Status, nil
+}
+
+// do sends body as JSON and decodes the JSON response into res, both may be
+// nil.
+func (c *Client) do(method, path string, body, res any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.addr+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,38 @@
+// Package graphapi is the API of the graph service for what the crawler knows
+// about links and edges beyond the linkgraph.Graph of the linkstore gRPC
+// service, such as the anchors of edges, the alias edges of redirects and
+// the fetch status of links, and writes the links of a crawled page in one
+// batch. It is served over HTTP next to the gRPC service.
+package graphapi
+
+import (
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+// Graph is implemented by the graph service and its clients.
+type Graph interface {
+	// UpsertOutlinks upserts src and the links found on its page and
+	// replaces the outgoing edges of src with edges to them and their
+	// anchors in a single transaction. The ID of src and of each link is
+	// set, like UpsertLink, and New is set for the links that were not in
+	// the graph before.
+	UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error
+
+	// UpsertAnchoredEdge is like UpsertEdge, it also stores the anchor text
+	// and rel attribute of the link the edge was found in.
+	UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error
+
+	// AnchorText returns the aggregated anchor text of the links pointing
+	// to dst.
+	AnchorText(dst uuid.UUID) (string, error)
+
+	// UpsertAlias records that edge.Src redirects to edge.Dst.
+	UpsertAlias(edge *linkgraph.Edge) error
+
+	// RecordFetch updates the status of a link with the outcome of a fetch
+	// that ended with the HTTP status code and fetchErr.
+	RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error)
+}
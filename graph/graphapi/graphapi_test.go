@@ -0,0 +1,162 @@
+package graphapi
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+// memGraph keeps the links, anchors, aliases and fetch statuses in memory.
+type memGraph struct {
+	links    map[string]uuid.UUID
+	outlinks map[uuid.UUID][]uuid.UUID
+	anchors  map[uuid.UUID][]string
+	aliases  map[uuid.UUID]uuid.UUID
+	statuses map[uuid.UUID]linkstatus.Status
+}
+
+func (m *memGraph) upsertLink(link *linkgraph.Link) bool {
+	_, known := m.links[link.URL]
+	if !known {
+		m.links[link.URL] = uuid.New()
+	}
+	link.ID = m.links[link.URL]
+	return !known
+}
+
+func (m *memGraph) UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error {
+	m.upsertLink(src)
+	m.outlinks[src.ID] = nil
+	for _, link := range links {
+		link.New = m.upsertLink(&link.Link)
+		m.outlinks[src.ID] = append(m.outlinks[src.ID], link.ID)
+		m.anchors[link.ID] = append(m.anchors[link.ID], link.Text)
+	}
+	return nil
+}
+
+func (m *memGraph) UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error {
+	if edge.Src == edge.Dst {
+		return fmt.Errorf("edge to itself")
+	}
+	edge.ID, edge.UpdateAt = uuid.New(), time.Now().UTC().Truncate(time.Second)
+	m.anchors[edge.Dst] = append(m.anchors[edge.Dst], text)
+	return nil
+}
+
+func (m *memGraph) AnchorText(dst uuid.UUID) (string, error) {
+	return strings.Join(m.anchors[dst], " "), nil
+}
+
+func (m *memGraph) UpsertAlias(edge *linkgraph.Edge) error {
+	edge.ID, edge.UpdateAt = uuid.New(), time.Now().UTC().Truncate(time.Second)
+	m.aliases[edge.Src] = edge.Dst
+	return nil
+}
+
+func (m *memGraph) RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error) {
+	s := m.statuses[linkID]
+	s.Code, s.Err = code, ""
+	if fetchErr != nil {
+		s.Err = fetchErr.Error()
+	}
+	if linkstatus.Failed(code, fetchErr) {
+		s.Failures++
+	} else {
+		s.Failures = 0
+	}
+	m.statuses[linkID] = s
+	return s, nil
+}
+
+func Test_client(t *testing.T) {
+	g := &memGraph{
+		links:    make(map[string]uuid.UUID),
+		outlinks: make(map[uuid.UUID][]uuid.UUID),
+		anchors:  make(map[uuid.UUID][]string),
+		aliases:  make(map[uuid.UUID]uuid.UUID),
+		statuses: make(map[uuid.UUID]linkstatus.Status),
+	}
+	srv := httptest.NewServer(NewHandler(g))
+	defer srv.Close()
+	c := NewClient(srv.URL + "/")
+
+	src, dst := uuid.New(), uuid.New()
+
+	t.Run("test_outlinks", func(t *testing.T) {
+		page := &linkgraph.Link{URL: "https://example.com/"}
+		g.links["https://example.com/a"] = uuid.New()
+		found := []*outlink.Link{
+			{Link: linkgraph.Link{URL: "https://example.com/a"}, Text: "a"},
+			{Link: linkgraph.Link{URL: "https://example.com/b"}, Rel: "nofollow"},
+		}
+		if err := c.UpsertOutlinks(page, found); err != nil {
+			t.Fatal(err)
+		}
+		if page.ID != g.links[page.URL] {
+			t.Fatalf("\ngot:%v \nexpect:%v", page.ID, g.links[page.URL])
+		}
+		expect := []uuid.UUID{g.links[found[0].URL], g.links[found[1].URL]}
+		if got := []uuid.UUID{found[0].ID, found[1].ID}; !reflect.DeepEqual(got, expect) || !reflect.DeepEqual(g.outlinks[page.ID], expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if found[0].New || !found[1].New {
+			t.Fatalf("\ngot:%v %v \nexpect:%v", found[0].New, found[1].New, "false true")
+		}
+	})
+
+	t.Run("test_anchored_edge", func(t *testing.T) {
+		edge := &linkgraph.Edge{Src: src, Dst: dst}
+		if err := c.UpsertAnchoredEdge(edge, "read the docs", "nofollow"); err != nil {
+			t.Fatal(err)
+		}
+		if edge.ID == uuid.Nil || edge.UpdateAt.IsZero() {
+			t.Fatalf("\ngot:%+v \nexpect:%v", edge, "the ID and UpdateAt of the edge")
+		}
+
+		text, err := c.AnchorText(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "read the docs" {
+			t.Fatalf("\ngot:%v \nexpect:%v", text, "read the docs")
+		}
+	})
+
+	t.Run("test_error", func(t *testing.T) {
+		if err := c.UpsertAnchoredEdge(&linkgraph.Edge{Src: src, Dst: src}, "", ""); err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "an error")
+		}
+	})
+
+	t.Run("test_alias", func(t *testing.T) {
+		if err := c.UpsertAlias(&linkgraph.Edge{Src: src, Dst: dst}); err != nil {
+			t.Fatal(err)
+		}
+		if g.aliases[src] != dst {
+			t.Fatalf("\ngot:%v \nexpect:%v", g.aliases[src], dst)
+		}
+	})
+
+	t.Run("test_record_fetch", func(t *testing.T) {
+		if _, err := c.RecordFetch(src, 0, fmt.Errorf("timeout")); err != nil {
+			t.Fatal(err)
+		}
+		s, err := c.RecordFetch(src, 503, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := linkstatus.Status{Code: 503, Failures: 2}
+		if !reflect.DeepEqual(s, expect) {
+			t.Fatalf("\ngot:%+v \nexpect:%+v", s, expect)
+		}
+	})
+}
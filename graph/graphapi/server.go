@@ -0,0 +1,163 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/linkstatus"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+var (
+	outlinksEndpoint = "/outlinks"
+	edgesEndpoint    = "/edges"
+	aliasesEndpoint  = "/aliases"
+	linksEndpoint    = "/links"
+)
+
+// outlinksRequest is the body of a request to upsert the links of a page, the
+// response has the same body with the IDs of the links set.
+type outlinksRequest struct {
+	Src   *linkgraph.Link
+	Links []*outlink.Link
+}
+
+// edgeRequest is the body of a request to upsert an anchored edge or an
+// alias edge.
+type edgeRequest struct {
+	Edge *linkgraph.Edge
+	Text string
+	Rel  string
+}
+
+// edgeResponse is the body of the response to an edgeRequest.
+type edgeResponse struct {
+	Edge *linkgraph.Edge
+}
+
+// anchorTextResponse is the body of the response to a request for the anchor
+// text of a link.
+type anchorTextResponse struct {
+	Text string
+}
+
+// fetchRequest is the body of a request to record the outcome of a fetch,
+// Err is empty if the fetch did not fail with an error.
+type fetchRequest struct {
+	Code int
+	Err  string
+}
+
+// fetchResponse is the body of the response to a fetchRequest.
+type fetchResponse struct {
+	Status linkstatus.Status
+}
+
+// NewHandler returns the HTTP handler serving the API of g.
+func NewHandler(g Graph) http.Handler {
+	h := &handler{g: g}
+	r := chi.NewMux()
+	r.Post(outlinksEndpoint, h.upsertOutlinks)
+	r.Post(edgesEndpoint, h.upsertAnchoredEdge)
+	r.Post(aliasesEndpoint, h.upsertAlias)
+	r.Get(linksEndpoint+"/{id}/anchor-text", h.anchorText)
+	r.Post(linksEndpoint+"/{id}/fetches", h.recordFetch)
+	return r
+}
+
+type handler struct {
+	g Graph
+}
+
+func (h *handler) upsertOutlinks(w http.ResponseWriter, r *http.Request) {
+	var req outlinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Src == nil {
+		http.Error(w, "invalid outlinks", http.StatusBadRequest)
+		return
+	}
+	if err := h.g.UpsertOutlinks(req.Src, req.Links); err != nil {
+		log.Println("upsert outlinks:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, req)
+}
+
+func (h *handler) upsertAnchoredEdge(w http.ResponseWriter, r *http.Request) {
+	var req edgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Edge == nil {
+		http.Error(w, "invalid edge", http.StatusBadRequest)
+		return
+	}
+	if err := h.g.UpsertAnchoredEdge(req.Edge, req.Text, req.Rel); err != nil {
+		log.Println("upsert anchored edge:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, edgeResponse{Edge: req.Edge})
+}
+
+func (h *handler) upsertAlias(w http.ResponseWriter, r *http.Request) {
+	var req edgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Edge == nil {
+		http.Error(w, "invalid edge", http.StatusBadRequest)
+		return
+	}
+	if err := h.g.UpsertAlias(req.Edge); err != nil {
+		log.Println("upsert alias:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, edgeResponse{Edge: req.Edge})
+}
+
+func (h *handler) anchorText(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid link id", http.StatusBadRequest)
+		return
+	}
+	text, err := h.g.AnchorText(id)
+	if err != nil {
+		log.Println("anchor text:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, anchorTextResponse{Text: text})
+}
+
+func (h *handler) recordFetch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid link id", http.StatusBadRequest)
+		return
+	}
+	var req fetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid fetch", http.StatusBadRequest)
+		return
+	}
+	var fetchErr error
+	if req.Err != "" {
+		fetchErr = errors.New(req.Err)
+	}
+	s, err := h.g.RecordFetch(id, req.Code, fetchErr)
+	if err != nil {
+		log.Println("record fetch:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, fetchResponse{Status: s})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("graphapi:", err)
+	}
+}
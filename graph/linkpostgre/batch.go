@@ -0,0 +1,233 @@
+package linkpostgre
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+// the number of rows written per INSERT statement, a statement takes at most
+// 65535 parameters
+var max_batch_rows = 1000
+
+// UpsertLinks upserts links in a single transaction and sets the ID and
+// RetrievedAt of each, like UpsertLink. Links with the same URL get the same
+// ID.
+func (p *postgre) UpsertLinks(links []*linkgraph.Link) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("upsert links: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := upsertLinks(tx, links); err != nil {
+		return fmt.Errorf("upsert links: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("upsert links: %v", err)
+	}
+	return nil
+}
+
+// upsertLinks upserts links in tx, see UpsertLinks. It returns the URLs that
+// were inserted rather than updated.
+func upsertLinks(tx *sqlx.Tx, links []*linkgraph.Link) (map[string]bool, error) {
+	byURL := make(map[string][]*linkgraph.Link, len(links))
+	for _, link := range links {
+		link.RetrievedAt = link.RetrievedAt.UTC()
+		byURL[link.URL] = append(byURL[link.URL], link)
+	}
+
+	// one row per URL, rows are written in URL order so concurrent batches
+	// lock the same rows in the same order
+	urls := make([]string, 0, len(byURL))
+	for u := range byURL {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	inserted := make(map[string]bool)
+	for len(urls) > 0 {
+		n := min(len(urls), max_batch_rows)
+		args := make([]any, 0, 2*n)
+		for _, u := range urls[:n] {
+			retrievedAt := byURL[u][0].RetrievedAt
+			for _, link := range byURL[u][1:] {
+				if link.RetrievedAt.After(retrievedAt) {
+					retrievedAt = link.RetrievedAt
+				}
+			}
+			args = append(args, u, retrievedAt)
+		}
+
+		rows, err := tx.Queryx(fmt.Sprintf(linksUpsertQuery, valuesList(n, "(%s, %s)")), args...)
+		if err != nil {
+			return nil, err
+		}
+		err = scanRows(rows, func() error {
+			var res linkgraph.Link
+			var isNew bool
+			if err := rows.Scan(&res.ID, &res.URL, &res.RetrievedAt, &isNew); err != nil {
+				return err
+			}
+			for _, link := range byURL[res.URL] {
+				link.ID, link.RetrievedAt = res.ID, res.RetrievedAt
+			}
+			inserted[res.URL] = isNew
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		urls = urls[n:]
+	}
+	return inserted, nil
+}
+
+// UpsertEdges upserts edges in a single transaction and sets the ID and
+// UpdateAt of each, like UpsertEdge.
+func (p *postgre) UpsertEdges(edges []*linkgraph.Edge) error {
+	type key struct{ src, dst uuid.UUID }
+	byKey := make(map[key][]*linkgraph.Edge, len(edges))
+	keys := make([]key, 0, len(edges))
+	for _, edge := range edges {
+		k := key{edge.Src, edge.Dst}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], edge)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].src != keys[j].src {
+			return keys[i].src.String() < keys[j].src.String()
+		}
+		return keys[i].dst.String() < keys[j].dst.String()
+	})
+
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("upsert edges: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for len(keys) > 0 {
+		n := min(len(keys), max_batch_rows)
+		args := make([]any, 0, 2*n)
+		for _, k := range keys[:n] {
+			args = append(args, k.src, k.dst)
+		}
+
+		rows, err := tx.Queryx(fmt.Sprintf(edgesUpsertQuery, valuesList(n, "(%s, %s, NOW())")), args...)
+		if err != nil {
+			return edgeUpsertError(err)
+		}
+		err = scanRows(rows, func() error {
+			var res linkgraph.Edge
+			if err := rows.Scan(&res.ID, &res.Src, &res.Dst, &res.UpdateAt); err != nil {
+				return err
+			}
+			for _, edge := range byKey[key{res.Src, res.Dst}] {
+				edge.ID, edge.UpdateAt = res.ID, res.UpdateAt
+			}
+			return nil
+		})
+		if err != nil {
+			return edgeUpsertError(err)
+		}
+		keys = keys[n:]
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("upsert edges: %v", err)
+	}
+	return nil
+}
+
+// UpsertOutlinks upserts src and the links found on its page and replaces the
+// outgoing edges of src with edges to them and their anchors, all in a single
+// transaction. Every other edge of src, including an alias edge, is removed.
+// The ID and RetrievedAt of src and of each link are set, like UpsertLink, and
+// New is set for the links that were not in the graph before.
+func (p *postgre) UpsertOutlinks(src *linkgraph.Link, links []*outlink.Link) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("upsert outlinks: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	all := make([]*linkgraph.Link, 0, len(links)+1)
+	all = append(all, src)
+	for _, link := range links {
+		all = append(all, &link.Link)
+	}
+	inserted, err := upsertLinks(tx, all)
+	if err != nil {
+		return fmt.Errorf("upsert outlinks: %v", err)
+	}
+	for _, link := range links {
+		link.New = inserted[link.URL]
+	}
+
+	// the anchor of the first link found to a destination
+	anchors := make(map[uuid.UUID]*outlink.Link, len(links))
+	dsts := make([]uuid.UUID, 0, len(links))
+	for _, link := range links {
+		if _, ok := anchors[link.ID]; !ok {
+			anchors[link.ID] = link
+			dsts = append(dsts, link.ID)
+		}
+	}
+	sort.Slice(dsts, func(i, j int) bool { return dsts[i].String() < dsts[j].String() })
+
+	for len(dsts) > 0 {
+		n := min(len(dsts), max_batch_rows)
+		args := make([]any, 0, 4*n)
+		for _, dst := range dsts[:n] {
+			args = append(args, src.ID, dst, anchors[dst].Text, anchors[dst].Rel)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(outlinksUpsertQuery, valuesList(n, "(%s, %s, NOW(), %s, %s)")), args...); err != nil {
+			return edgeUpsertError(err)
+		}
+		dsts = dsts[n:]
+	}
+
+	if _, err := tx.Exec(outlinksRemoveQuery, src.ID); err != nil {
+		return fmt.Errorf("upsert outlinks: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("upsert outlinks: %v", err)
+	}
+	return nil
+}
+
+// valuesList returns the VALUES list of a multi-row statement with n rows,
+// row is the format of one row with a %s verb per parameter, e.g.
+// "(%s, %s, NOW())". The parameters are numbered from $1 row by row.
+func valuesList(n int, row string) string {
+	cols := strings.Count(row, "%s")
+	rows := make([]string, n)
+	params := make([]any, cols)
+	for i := range rows {
+		for j := range params {
+			params[j] = fmt.Sprintf("$%d", i*cols+j+1)
+		}
+		rows[i] = fmt.Sprintf(row, params...)
+	}
+	return strings.Join(rows, ", ")
+}
+
+// scanRows calls scan for every row and closes rows.
+func scanRows(rows *sqlx.Rows, scan func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
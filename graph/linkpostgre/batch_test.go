@@ -0,0 +1,144 @@
+package linkpostgre
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/outlink"
+)
+
+func test_batch_writes(t *testing.T) {
+	pg.db.ExecContext(context.TODO(), linkTable.Create)
+	pg.db.ExecContext(context.TODO(), edgeTable.Create)
+	defer func() {
+		pg.db.ExecContext(context.TODO(), edgeTable.Drop)
+		pg.db.ExecContext(context.TODO(), linkTable.Drop)
+	}()
+	if err := pg.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// more links than fit in one statement
+	defer func(n int) { max_batch_rows = n }(max_batch_rows)
+	max_batch_rows = 2
+
+	retrievedAt := time.Now().Add(-time.Hour)
+	src := &linkgraph.Link{URL: "https://example.com/", RetrievedAt: retrievedAt}
+	links := []*linkgraph.Link{src}
+	for i := 0; i < 4; i++ {
+		links = append(links, &linkgraph.Link{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	// a duplicate URL gets the same ID
+	dup := &linkgraph.Link{URL: "https://example.com/0"}
+	links = append(links, dup)
+
+	t.Run("test_upsert_links", func(t *testing.T) {
+		if err := pg.UpsertLinks(links); err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range links {
+			got, err := pg.LookupLink(link.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.URL != link.URL {
+				t.Fatalf("\ngot:%v \nexpect:%v", got.URL, link.URL)
+			}
+		}
+		if dup.ID != links[1].ID {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", dup.ID, links[1].ID, "duplicate URL got another ID")
+		}
+		if !src.RetrievedAt.Equal(retrievedAt.UTC().Truncate(time.Microsecond)) {
+			t.Fatalf("\ngot:%v \nexpect:%v", src.RetrievedAt, retrievedAt.UTC())
+		}
+	})
+
+	outlinks := func() []string {
+		it, err := pg.Edges(uuid.Nil, uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff"), time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer it.Close()
+		var got []string
+		for it.Next() {
+			if e := it.Edge(); e.Src == src.ID {
+				for _, link := range links {
+					if link.ID == e.Dst {
+						got = append(got, link.URL)
+						break
+					}
+				}
+			}
+		}
+		if err := it.Error(); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	t.Run("test_upsert_edges", func(t *testing.T) {
+		edges := []*linkgraph.Edge{
+			{Src: src.ID, Dst: links[1].ID},
+			{Src: src.ID, Dst: links[2].ID},
+			{Src: src.ID, Dst: links[3].ID},
+		}
+		if err := pg.UpsertEdges(edges); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range edges {
+			if e.ID == uuid.Nil {
+				t.Fatalf("\ngot:%v \nmessage:%v", e, "edge ID not set")
+			}
+		}
+
+		err := pg.UpsertEdges([]*linkgraph.Edge{{Src: src.ID, Dst: uuid.New()}})
+		if err != linkgraph.ErrUnknownEdgeLinks {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, linkgraph.ErrUnknownEdgeLinks)
+		}
+	})
+
+	t.Run("test_upsert_outlinks", func(t *testing.T) {
+		page := &linkgraph.Link{URL: src.URL}
+		found := []*outlink.Link{
+			{Link: linkgraph.Link{URL: links[3].URL}, Text: "three"},
+			{Link: linkgraph.Link{URL: links[4].URL}, Text: "four", Rel: "nofollow"},
+			{Link: linkgraph.Link{URL: "https://example.com/new"}, Text: "new"},
+		}
+		if err := pg.UpsertOutlinks(page, found); err != nil {
+			t.Fatal(err)
+		}
+		if page.ID != src.ID || found[0].ID != links[3].ID || found[2].ID == uuid.Nil {
+			t.Fatalf("\ngot:%v %v %v \nmessage:%v", page.ID, found[0].ID, found[2].ID, "link IDs not set")
+		}
+		if got := fmt.Sprint(found[0].New, found[1].New, found[2].New); got != "false false true" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, "false false true")
+		}
+		links = append(links, &found[2].Link)
+
+		// the nofollow edge is kept with its anchor but not ranked
+		expect := []string{links[3].URL, "https://example.com/new"}
+		if got := outlinks(); fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		text, err := pg.AnchorText(links[4].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "four" {
+			t.Fatalf("\ngot:%v \nexpect:%v", text, "four")
+		}
+
+		if err := pg.UpsertOutlinks(page, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := outlinks(); len(got) != 0 {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, "no outlinks")
+		}
+	})
+}
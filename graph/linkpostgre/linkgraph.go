@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/graph/linkstatus"
 )
 
 var _ linkgraph.Graph = (*postgre)(nil)
@@ -52,6 +53,26 @@ func (p *postgre) Migrate() error {
 		return fmt.Errorf("create table: %v", err)
 	}
 
+	_, err = p.db.ExecContext(context.TODO(), alterLinkStatusQuery)
+	if err != nil {
+		return fmt.Errorf("alter link status columns: %v", err)
+	}
+
+	_, err = p.db.ExecContext(context.TODO(), alterEdgeKindQuery)
+	if err != nil {
+		return fmt.Errorf("alter edge kind column: %v", err)
+	}
+
+	_, err = p.db.ExecContext(context.TODO(), alterEdgeAnchorQuery)
+	if err != nil {
+		return fmt.Errorf("alter edge anchor columns: %v", err)
+	}
+
+	_, err = p.db.ExecContext(context.TODO(), alterEdgeNofollowQuery)
+	if err != nil {
+		return fmt.Errorf("alter edge nofollow column: %v", err)
+	}
+
 	return nil
 }
 
@@ -73,6 +94,45 @@ func (p *postgre) LookupLink(id uuid.UUID) (*linkgraph.Link, error) {
 	return &link, nil
 }
 
+// RecordFetch updates the status of a link with the outcome of a fetch that
+// ended with the HTTP status code and fetchErr, and returns the new status.
+func (p *postgre) RecordFetch(linkID uuid.UUID, code int, fetchErr error) (linkstatus.Status, error) {
+	var lastErr string
+	if fetchErr != nil {
+		lastErr = fetchErr.Error()
+	}
+	failed := linkstatus.Failed(code, fetchErr)
+
+	row := p.db.QueryRowx(linkStatusUpdateQuery, linkID, code, lastErr, failed, time.Now().UTC())
+	s, err := scanLinkStatus(row)
+	if err != nil {
+		return s, fmt.Errorf("record fetch: %v", err)
+	}
+	return s, nil
+}
+
+// LinkStatus returns the status of a link.
+func (p *postgre) LinkStatus(linkID uuid.UUID) (linkstatus.Status, error) {
+	s, err := scanLinkStatus(p.db.QueryRowx(linkStatusQuery, linkID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return s, linkgraph.ErrNotFound
+		}
+		return s, fmt.Errorf("link status: %v", err)
+	}
+	return s, nil
+}
+
+func scanLinkStatus(row *sqlx.Row) (linkstatus.Status, error) {
+	var s linkstatus.Status
+	var lastSuccess sql.NullTime
+	if err := row.Scan(&s.Code, &s.Err, &s.Failures, &lastSuccess); err != nil {
+		return s, err
+	}
+	s.LastSuccessAt = lastSuccess.Time
+	return s, nil
+}
+
 // RemoveStaleEdges implements graph.Graph.
 func (p *postgre) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
 	// queryCtx, cancel := context.WithCancel(p.ctx)
@@ -114,20 +174,60 @@ func (p *postgre) UpsertEdge(edge *linkgraph.Edge) error {
 
 	err := p.db.QueryRowx(edgeUpsertQuery, edge.Src, edge.Dst).Scan(&edge.ID, &edge.UpdateAt)
 	if err != nil {
-		pgErr, ok := err.(*pgconn.PgError)
-		if ok {
-			switch pgErr.Code {
-			case "23503":
-				return linkgraph.ErrUnknownEdgeLinks
-			}
-		}
+		return edgeUpsertError(err)
+	}
+	return nil
+}
+
+// UpsertAlias records that edge.Src redirects to edge.Dst. Links to an alias
+// are folded into its target when iterating edges.
+func (p *postgre) UpsertAlias(edge *linkgraph.Edge) error {
+	edge.UpdateAt = edge.UpdateAt.UTC()
+
+	err := p.db.QueryRowx(aliasUpsertQuery, edge.Src, edge.Dst).Scan(&edge.ID, &edge.UpdateAt)
+	if err != nil {
+		return edgeUpsertError(err)
+	}
+	return nil
+}
 
-		return fmt.Errorf("edge upsert: %v", err)
+// UpsertAnchoredEdge is like UpsertEdge, it also stores the anchor text and
+// rel attribute of the link the edge was found in.
+func (p *postgre) UpsertAnchoredEdge(edge *linkgraph.Edge, text, rel string) error {
+	edge.UpdateAt = edge.UpdateAt.UTC()
 
+	err := p.db.QueryRowx(edgeUpsertAnchorQuery, edge.Src, edge.Dst, text, rel).Scan(&edge.ID, &edge.UpdateAt)
+	if err != nil {
+		return edgeUpsertError(err)
 	}
 	return nil
 }
 
+// the number of distinct anchor texts aggregated for a link
+var max_anchor_texts = 100
+
+// AnchorText returns the distinct anchor texts of the links pointing to dst
+// from other pages, separated by spaces.
+func (p *postgre) AnchorText(dst uuid.UUID) (string, error) {
+	var text string
+	if err := p.db.QueryRowx(anchorTextQuery, dst, max_anchor_texts).Scan(&text); err != nil {
+		return "", fmt.Errorf("anchor text: %v", err)
+	}
+	return text, nil
+}
+
+func edgeUpsertError(err error) error {
+	pgErr, ok := err.(*pgconn.PgError)
+	if ok {
+		switch pgErr.Code {
+		case "23503":
+			return linkgraph.ErrUnknownEdgeLinks
+		}
+	}
+
+	return fmt.Errorf("edge upsert: %v", err)
+}
+
 // Links implements graph.Graph.
 func (p *postgre) Links(fromID uuid.UUID, toID uuid.UUID, accessBefore time.Time) (linkgraph.LinkIterator, error) {
 	// queryCtx, cancel := context.WithCancel(p.ctx)
@@ -70,6 +70,10 @@ func Test_postgredb(t *testing.T) {
 	t.Run("link iterator filter login logic", test_Link_iterator_Timefilter)
 
 	t.Run("edge upsert logic", test_upsert_edge)
+	t.Run("edge anchor text", test_anchor_text)
+	t.Run("link status", test_link_status)
+	t.Run("redirect alias", test_redirect_alias)
+	t.Run("batch writes", test_batch_writes)
 
 }
 
@@ -464,3 +468,166 @@ func partitionRange(t *testing.T, partition, numPartition int) (from, to uuid.UU
 	return from, to
 
 }
+
+func test_anchor_text(t *testing.T) {
+	pg.db.ExecContext(context.TODO(), linkTable.Create)
+	pg.db.ExecContext(context.TODO(), edgeTable.Create)
+	defer func() {
+		pg.db.ExecContext(context.TODO(), edgeTable.Drop)
+		pg.db.ExecContext(context.TODO(), linkTable.Drop)
+	}()
+	if err := pg.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	links := make([]uuid.UUID, 3)
+	for i := range links {
+		link := &linkgraph.Link{URL: fmt.Sprint(i)}
+		if err := pg.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		links[i] = link.ID
+	}
+
+	anchors := []struct {
+		src, dst uuid.UUID
+		text     string
+	}{
+		{links[0], links[2], "search engine"},
+		{links[1], links[2], "demo"},
+		{links[2], links[2], "self link"},
+	}
+	for _, a := range anchors {
+		edge := &linkgraph.Edge{Src: a.src, Dst: a.dst}
+		if err := pg.UpsertAnchoredEdge(edge, a.text, "nofollow"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a plain upsert keeps the stored anchor
+	if err := pg.UpsertEdge(&linkgraph.Edge{Src: links[1], Dst: links[2]}); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := pg.AnchorText(links[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "demo search engine" {
+		t.Fatalf("\ngot:%v \nexpect:%v", text, "demo search engine")
+	}
+}
+
+func test_link_status(t *testing.T) {
+	pg.db.ExecContext(context.TODO(), linkTable.Create)
+	pg.db.ExecContext(context.TODO(), edgeTable.Create)
+	defer func() {
+		pg.db.ExecContext(context.TODO(), edgeTable.Drop)
+		pg.db.ExecContext(context.TODO(), linkTable.Drop)
+	}()
+	if err := pg.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	link := &linkgraph.Link{URL: "https://example.com/"}
+	if err := pg.UpsertLink(link); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := pg.RecordFetch(link.ID, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Failures != 0 || s.LastSuccessAt.IsZero() {
+		t.Fatalf("\ngot:%v \nmessage:%v", s, "success not recorded")
+	}
+	lastSuccess := s.LastSuccessAt
+
+	if _, err := pg.RecordFetch(link.ID, 404, nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err = pg.RecordFetch(link.ID, 0, fmt.Errorf("no such host"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Failures != 2 || s.Code != 0 || s.Err != "no such host" || !s.LastSuccessAt.Equal(lastSuccess) {
+		t.Fatalf("\ngot:%v \nmessage:%v", s, "failures not recorded")
+	}
+
+	if _, err := pg.RecordFetch(link.ID, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err = pg.LinkStatus(link.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Failures != 0 || s.Err != "" || s.Code != 200 {
+		t.Fatalf("\ngot:%v \nmessage:%v", s, "failures not reset")
+	}
+}
+
+func test_redirect_alias(t *testing.T) {
+	pg.db.ExecContext(context.TODO(), linkTable.Create)
+	pg.db.ExecContext(context.TODO(), edgeTable.Create)
+	defer func() {
+		pg.db.ExecContext(context.TODO(), edgeTable.Drop)
+		pg.db.ExecContext(context.TODO(), linkTable.Drop)
+	}()
+	if err := pg.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]uuid.UUID)
+	for _, u := range []string{"http://a.com/", "http://b.com/", "https://b.com/"} {
+		link := &linkgraph.Link{URL: u}
+		if err := pg.UpsertLink(link); err != nil {
+			t.Fatal(err)
+		}
+		ids[u] = link.ID
+	}
+	src, alias, target := ids["http://a.com/"], ids["http://b.com/"], ids["https://b.com/"]
+
+	if err := pg.UpsertAnchoredEdge(&linkgraph.Edge{Src: src, Dst: alias}, "b site", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.UpsertEdge(&linkgraph.Edge{Src: src, Dst: target}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.UpsertAlias(&linkgraph.Edge{Src: alias, Dst: target}); err != nil {
+		t.Fatal(err)
+	}
+	// a link from the target back to its alias is not a self link
+	if err := pg.UpsertEdge(&linkgraph.Edge{Src: target, Dst: alias}); err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := pg.Edges(uuid.Nil, uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff"), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var got []string
+	for it.Next() {
+		e := it.Edge()
+		got = append(got, fmt.Sprintf("%v->%v", e.Src, e.Dst))
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+
+	expect := []string{fmt.Sprintf("%v->%v", src, target), fmt.Sprintf("%v->%v", alias, target)}
+	sort.Strings(expect)
+	if fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+	}
+
+	text, err := pg.AnchorText(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "b site" {
+		t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", text, "b site", "anchor text of the alias not folded")
+	}
+}
@@ -18,12 +18,61 @@ const createEdgeTableQuery = `
 		);
 `
 
+// the outcome of the recent fetches of a link
+const alterLinkStatusQuery = `
+		ALTER TABLE links
+		ADD COLUMN IF NOT EXISTS status_code int NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS consecutive_failures int NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS last_success_at TIMESTAMP;
+`
+
+// the anchor text and rel attribute of the link an edge was found in
+const alterEdgeAnchorQuery = `
+		ALTER TABLE edges
+		ADD COLUMN IF NOT EXISTS anchor_text text,
+		ADD COLUMN IF NOT EXISTS rel text;
+`
+
+// links the page asked not to be followed (rel=nofollow, ugc or sponsored) are
+// kept as flagged edges, they do not pass on rank. Edges upserted without an
+// anchor clear the rel attribute and are followed.
+const alterEdgeNofollowQuery = `
+		ALTER TABLE edges
+		ADD COLUMN IF NOT EXISTS nofollow boolean
+			GENERATED ALWAYS AS (coalesce(rel, '') ~* '(^| )(nofollow|ugc|sponsored)( |$)') STORED;
+`
+
+// plain links have kind 'link', a redirect from src to dst is recorded as an
+// alias edge of kind 'redirect'
+const alterEdgeKindQuery = `
+		ALTER TABLE edges
+		ADD COLUMN IF NOT EXISTS kind text NOT NULL DEFAULT 'link';
+`
+
 const lookupLinkQuery = `
 	SELECT id, url, retrieved_at
 	FROM links
 	WHERE id = $1
 `
 
+// $4 is true if the fetch failed, $5 the time of the fetch
+const linkStatusUpdateQuery = `
+	UPDATE links
+	SET status_code = $2,
+		last_error = $3,
+		consecutive_failures = CASE WHEN $4 THEN consecutive_failures + 1 ELSE 0 END,
+		last_success_at = CASE WHEN $4 THEN last_success_at ELSE $5 END
+	WHERE id = $1
+	RETURNING status_code, last_error, consecutive_failures, last_success_at
+`
+
+const linkStatusQuery = `
+	SELECT status_code, last_error, consecutive_failures, last_success_at
+	FROM links
+	WHERE id = $1
+`
+
 const edgeRemoveStaleQuery = `
 	DELETE FROM edges 
 	WHERE src=$1 and update_at < $2
@@ -32,10 +81,42 @@ const edgeRemoveStaleQuery = `
 const edgeUpsertQuery = `
 	INSERT INTO edges (src, dst, update_at) 
 	VALUES ($1, $2, NOW())
-	ON CONFLICT (src,dst) DO UPDATE SET update_at=NOW()
+	ON CONFLICT (src,dst) DO UPDATE SET update_at=NOW(), kind='link', rel=NULL
 	RETURNING id,update_at
 `
 
+const aliasUpsertQuery = `
+	INSERT INTO edges (src, dst, update_at, kind) 
+	VALUES ($1, $2, NOW(), 'redirect')
+	ON CONFLICT (src,dst) DO UPDATE SET update_at=NOW(), kind='redirect', rel=NULL
+	RETURNING id,update_at
+`
+
+const edgeUpsertAnchorQuery = `
+	INSERT INTO edges (src, dst, update_at, anchor_text, rel) 
+	VALUES ($1, $2, NOW(), $3, $4)
+	ON CONFLICT (src,dst) DO UPDATE 
+		SET update_at=NOW(),
+			anchor_text=EXCLUDED.anchor_text,
+			rel=EXCLUDED.rel,
+			kind='link'
+	RETURNING id,update_at
+`
+
+// the distinct anchor texts of the links pointing to $1, or to an alias of
+// $1, from other pages
+const anchorTextQuery = `
+	SELECT coalesce(string_agg(anchor_text, ' '), '')
+	FROM (
+		SELECT DISTINCT anchor_text
+		FROM edges
+		WHERE (dst = $1 OR dst IN (SELECT src FROM edges WHERE dst = $1 AND kind = 'redirect'))
+			AND src <> $1 AND anchor_text <> ''
+		ORDER BY anchor_text
+		LIMIT $2
+	) a
+`
+
 const linkUpsertQuery = `
 	INSERT INTO links (url, retrieved_at) 
 	VALUES ($1, $2)
@@ -43,14 +124,57 @@ const linkUpsertQuery = `
 	RETURNING id,retrieved_at
 `
 
+// links to an alias are returned as links to the redirect target, so the
+// rank an alias would receive is folded into its target. The alias edge
+// itself passes the rank of the alias on to the target. Flagged nofollow
+// edges are left out, PageRank only follows the links a page vouches for.
 const edgesIterationQuery = `
-	SELECT id, src, dst, update_at 
-	FROM edges 
-	WHERE src >= $1 AND src < $2 AND update_at < $3
+	SELECT DISTINCT ON (e.src, COALESCE(a.dst, e.dst)) e.id, e.src, COALESCE(a.dst, e.dst), e.update_at 
+	FROM edges e
+	LEFT JOIN edges a ON a.src = e.dst AND a.kind = 'redirect'
+	WHERE e.src >= $1 AND e.src < $2 AND e.update_at < $3
+		AND e.src <> COALESCE(a.dst, e.dst)
+		AND NOT e.nofollow
 `
 
+// links are iterated in ID order so an interrupted crawl pass can resume
+// after the last link it processed
 const linksIterationQuery = `
 	SELECT id, url, retrieved_at 
 	FROM links 
 	WHERE id >= $1 AND id < $2 AND retrieved_at < $3
+	ORDER BY id
 	`
+
+// %s is the VALUES list of (url, retrieved_at) rows
+const linksUpsertQuery = `
+	INSERT INTO links (url, retrieved_at)
+	VALUES %s
+	ON CONFLICT (url) DO UPDATE SET retrieved_at=GREATEST(links.retrieved_at, EXCLUDED.retrieved_at)
+	RETURNING id, url, retrieved_at, xmax = 0 AS inserted
+`
+
+// %s is the VALUES list of (src, dst, update_at) rows
+const edgesUpsertQuery = `
+	INSERT INTO edges (src, dst, update_at)
+	VALUES %s
+	ON CONFLICT (src,dst) DO UPDATE SET update_at=NOW(), kind='link', rel=NULL
+	RETURNING id, src, dst, update_at
+`
+
+// %s is the VALUES list of (src, dst, update_at, anchor_text, rel) rows
+const outlinksUpsertQuery = `
+	INSERT INTO edges (src, dst, update_at, anchor_text, rel)
+	VALUES %s
+	ON CONFLICT (src,dst) DO UPDATE 
+		SET update_at=NOW(),
+			anchor_text=EXCLUDED.anchor_text,
+			rel=EXCLUDED.rel,
+			kind='link'
+`
+
+// every edge upserted in the same transaction has update_at = NOW()
+const outlinksRemoveQuery = `
+	DELETE FROM edges
+	WHERE src=$1 AND update_at < NOW()
+`
@@ -0,0 +1,46 @@
+// Package linkstatus tracks whether the page of a link can still be fetched,
+// so the documents of dead pages can be removed from the index.
+package linkstatus
+
+import (
+	"net/http"
+	"time"
+)
+
+// Status is the outcome of the recent fetches of a link.
+type Status struct {
+	// The HTTP status of the last response, zero if the last request
+	// failed before a response was received.
+	Code int
+
+	// The error of the last fetch, empty if it succeeded.
+	Err string
+
+	// The number of consecutive failed fetches.
+	Failures int
+
+	// When the page was last fetched successfully, zero if never.
+	LastSuccessAt time.Time
+}
+
+// Failed reports whether a fetch that ended with the HTTP status code and
+// err failed.
+func Failed(code int, err error) bool {
+	return err != nil || code >= 400
+}
+
+// Policy decides when the page of a link is considered dead.
+type Policy struct {
+	// The number of consecutive failed fetches after which a page is dead.
+	// Zero means only pages answering 410 Gone are dead.
+	MaxFailures int
+}
+
+// Dead reports whether a page with status s is gone. A page that answers
+// 410 Gone is dead right away.
+func (p Policy) Dead(s Status) bool {
+	if s.Code == http.StatusGone {
+		return true
+	}
+	return p.MaxFailures > 0 && s.Failures >= p.MaxFailures
+}
@@ -0,0 +1,34 @@
+package linkstatus
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_policy_dead(t *testing.T) {
+	p := Policy{MaxFailures: 3}
+	tests := []struct {
+		name   string
+		status Status
+		expect bool
+	}{
+		{"test_gone", Status{Code: 410, Failures: 1}, true},
+		{"test_not_found_once", Status{Code: 404, Failures: 1}, false},
+		{"test_max_failures", Status{Code: 404, Failures: 3}, true},
+		{"test_dns_failures", Status{Err: "no such host", Failures: 4}, true},
+		{"test_recovered", Status{Code: 200}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Dead(tt.status); got != tt.expect {
+				t.Fatalf("\ngot:%v \nexpect:%v", got, tt.expect)
+			}
+		})
+	}
+
+	t.Run("test_failed", func(t *testing.T) {
+		if Failed(304, nil) || !Failed(500, nil) || !Failed(0, errors.New("timeout")) {
+			t.Fatal("wrong failure classification")
+		}
+	})
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/linkstore"
+	"github.com/odit-bit/se/graph/graphapi"
 	postgregraph "github.com/odit-bit/se/graph/linkpostgre"
 )
 
@@ -22,6 +24,18 @@ func main() {
 	}
 	db := postgregraph.New(dbConn)
 
+	// the crawler writes what the gRPC service does not know about links and
+	// edges, such as anchors and redirects, through the HTTP API
+	apiAddress := os.Getenv("GRAPHAPI_ADDRESS")
+	if apiAddress == "" {
+		apiAddress = ":8182"
+	}
+	go func() {
+		if err := http.ListenAndServe(apiAddress, graphapi.NewHandler(db)); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
 	srv := linkstore.Server{
 		Port:    8181,
 		Handler: db,
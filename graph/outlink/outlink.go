@@ -0,0 +1,19 @@
+// Package outlink describes the outgoing links of a crawled page as they are
+// written to the link graph in one batch.
+package outlink
+
+import "github.com/odit-bit/linkstore/linkgraph"
+
+// Link is a link found on a crawled page with the anchor it was found in.
+type Link struct {
+	linkgraph.Link
+
+	// The text and rel attribute of the <a> element, empty if the link was
+	// not found in one.
+	Text string
+	Rel  string
+
+	// Set by the graph if the link was not in the graph before the page
+	// was written.
+	New bool
+}
@@ -0,0 +1,120 @@
+// Package docmeta describes the metadata of a crawled page that is stored
+// next to its indexed document, such as the meta description, the headings
+// and the OpenGraph fields of an HTML page.
+package docmeta
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata is the metadata declared by a page.
+type Metadata struct {
+	// The content of <meta name=description>.
+	Description string
+
+	// The text of the <h1> to <h3> headings in order of appearance.
+	Headings []string
+
+	// The OpenGraph (<meta property="og:...">) and Twitter card
+	// (<meta name="twitter:...">) fields, keyed by their name without the
+	// prefix, e.g. "title" or "image".
+	OpenGraph map[string]string
+	Twitter   map[string]string
+
+	// The publication and last modification dates, zero if the page does
+	// not declare them.
+	Published time.Time
+	Modified  time.Time
+
+	Author string
+
+	// The URL of the icon declared by <link rel=icon>, empty if the page
+	// declares none. The crawler resolves it against the URL of the page
+	// before it is stored.
+	Favicon string
+
+	// The schema.org entities described by the page in JSON-LD or microdata
+	// blocks, e.g. an Article or a Product.
+	Entities []Entity
+}
+
+// Entity is a schema.org entity in the shape of expanded JSON-LD without
+// @context: "@type" holds the type, a string or a list of strings, without
+// the "https://schema.org/" prefix, every other key is a property. The values
+// are strings, numbers, booleans, nested entities or lists of them, as decoded
+// by encoding/json.
+type Entity map[string]any
+
+// Types returns the types of the entity.
+func (e Entity) Types() []string {
+	switch t := e["@type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// Is reports whether the entity is of type t, ignoring case.
+func (e Entity) Is(t string) bool {
+	for _, typ := range e.Types() {
+		if strings.EqualFold(typ, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the value of a property as text: the first value of a list,
+// the name of a nested entity or a number as written. It returns an empty
+// string if the entity has no such property.
+func (e Entity) Text(prop string) string {
+	return text(e[prop])
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		if len(v) > 0 {
+			return text(v[0])
+		}
+	case map[string]any:
+		return text(v["name"])
+	case Entity:
+		return text(v["name"])
+	}
+	return ""
+}
+
+// Property returns the nested entity of a property, the first one of a list,
+// nil if the property is not an entity.
+func (e Entity) Property(prop string) Entity {
+	return entity(e[prop])
+}
+
+func entity(v any) Entity {
+	switch v := v.(type) {
+	case Entity:
+		return v
+	case map[string]any:
+		return v
+	case []any:
+		if len(v) > 0 {
+			return entity(v[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package indexapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/index/docmeta"
+)
+
+var _ Indexer = (*Client)(nil)
+
+// Client is a client of the API served by NewHandler.
+type Client struct {
+	addr   string
+	client *http.Client
+}
+
+// NewClient creates a client of the API served at addr, e.g.
+// "http://index:8384".
+func NewClient(addr string) *Client {
+	return &Client{
+		addr:   strings.TrimSuffix(addr, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// IndexDocument implements Indexer.
+func (c *Client) IndexDocument(doc *Document, maxDistance int) (uuid.UUID, error) {
+	var res indexResponse
+	err := c.do(http.MethodPost, documentsEndpoint, indexRequest{Document: doc, MaxDistance: maxDistance}, &res)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("index document: %v", err)
+	}
+	return res.DuplicateOf, nil
+}
+
+// DeleteDocument implements Indexer.
+func (c *Client) DeleteDocument(linkID uuid.UUID) error {
+	if err := c.do(http.MethodDelete, documentsEndpoint+"/"+linkID.String(), nil, nil); err != nil {
+		return fmt.Errorf("delete document: %v", err)
+	}
+	return nil
+}
+
+// Pageranks implements Indexer.
+func (c *Client) Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	var res pageranksResponse
+	if err := c.do(http.MethodPost, pageranksEndpoint, linksRequest{LinkIDs: linkIDs}, &res); err != nil {
+		return nil, fmt.Errorf("pageranks: %v", err)
+	}
+	return res.Pageranks, nil
+}
+
+// MediaTypes implements Indexer.
+func (c *Client) MediaTypes(linkIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	var res mediaTypesResponse
+	if err := c.do(http.MethodPost, mediaTypesEndpoint, linksRequest{LinkIDs: linkIDs}, &res); err != nil {
+		return nil, fmt.Errorf("media types: %v", err)
+	}
+	return res.MediaTypes, nil
+}
+
+// Metadata implements Indexer.
+func (c *Client) Metadata(linkIDs []uuid.UUID) (map[uuid.UUID]*docmeta.Metadata, error) {
+	var res metadataResponse
+	if err := c.do(http.MethodPost, metadataEndpoint, linksRequest{LinkIDs: linkIDs}, &res); err != nil {
+		return nil, fmt.Errorf("metadata: %v", err)
+	}
+	return res.Metadata, nil
+}
+
+// do sends body as JSON and decodes the JSON response into res, both may be
+// nil.
+func (c *Client) do(method, path string, body, res any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.addr+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
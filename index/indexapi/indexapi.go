@@ -0,0 +1,62 @@
+// Package indexapi is the API of the index service for the documents of
+// crawled pages. The index.Indexer of the indexstore gRPC service only knows
+// the text of a document, this API also carries what the crawler knows about
+// its page, such as its language, media type and metadata, the anchor text of
+// the links pointing to it and the fingerprint of its content. It is served
+// over HTTP next to the gRPC service.
+package indexapi
+
+import (
+	"github.com/google/uuid"
+	"github.com/odit-bit/indexstore/index"
+	"github.com/odit-bit/se/index/docmeta"
+)
+
+// Document is an indexed document along with what is known about its page.
+type Document struct {
+	index.Document
+
+	// The ISO 639-1 code of the language of the document, empty if it is
+	// unknown.
+	Language string
+
+	// The media type of the page, e.g. application/pdf for a document
+	// extracted from a PDF file. Empty for an HTML page.
+	MediaType string
+
+	// The metadata declared by the page.
+	Meta docmeta.Metadata
+
+	// The aggregated anchor text of the links pointing to the page.
+	AnchorText string
+
+	// The SimHash fingerprint of the content, zero if the content is too
+	// short to be fingerprinted.
+	Fingerprint uint64
+}
+
+// Indexer is implemented by the index service and its clients.
+type Indexer interface {
+	// IndexDocument indexes doc unless its fingerprint differs from the
+	// fingerprint of another indexed document in at most maxDistance bits.
+	// Such a near-duplicate is recorded as an alias of that document instead,
+	// whose ID is returned. A negative maxDistance disables the check.
+	IndexDocument(doc *Document, maxDistance int) (duplicateOf uuid.UUID, err error)
+
+	// DeleteDocument removes the document of linkID from the index, along
+	// with any alias recorded for it.
+	DeleteDocument(linkID uuid.UUID) error
+
+	// Pageranks returns the PageRank scores of the documents of the links
+	// relative to the best ranked document. Links without a document are
+	// left out.
+	Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+
+	// MediaTypes returns the media types of the documents of the links.
+	// Links without a document are left out.
+	MediaTypes(linkIDs []uuid.UUID) (map[uuid.UUID]string, error)
+
+	// Metadata returns the metadata of the pages of the documents of the
+	// links. Links without a document are left out.
+	Metadata(linkIDs []uuid.UUID) (map[uuid.UUID]*docmeta.Metadata, error)
+}
@@ -0,0 +1,174 @@
+package indexapi
+
+import (
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/odit-bit/indexstore/index"
+	"github.com/odit-bit/se/index/docmeta"
+)
+
+// memIndex keeps the indexed documents in memory, documents with the same
+// fingerprint are duplicates.
+type memIndex struct {
+	docs map[uuid.UUID]*Document
+}
+
+func (m *memIndex) IndexDocument(doc *Document, maxDistance int) (uuid.UUID, error) {
+	if doc.URL == "" {
+		return uuid.Nil, fmt.Errorf("url is empty")
+	}
+	for id, d := range m.docs {
+		if maxDistance >= 0 && id != doc.LinkID && d.Fingerprint == doc.Fingerprint {
+			return id, nil
+		}
+	}
+	m.docs[doc.LinkID] = doc
+	return uuid.Nil, nil
+}
+
+func (m *memIndex) DeleteDocument(linkID uuid.UUID) error {
+	delete(m.docs, linkID)
+	return nil
+}
+
+func (m *memIndex) Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	var best float64
+	for _, d := range m.docs {
+		best = math.Max(best, d.Pagerank)
+	}
+	ranks := make(map[uuid.UUID]float64)
+	for _, id := range linkIDs {
+		if d, ok := m.docs[id]; ok && best > 0 {
+			ranks[id] = d.Pagerank / best
+		}
+	}
+	return ranks, nil
+}
+
+func (m *memIndex) MediaTypes(linkIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	types := make(map[uuid.UUID]string)
+	for _, id := range linkIDs {
+		if d, ok := m.docs[id]; ok {
+			types[id] = d.MediaType
+		}
+	}
+	return types, nil
+}
+
+func (m *memIndex) Metadata(linkIDs []uuid.UUID) (map[uuid.UUID]*docmeta.Metadata, error) {
+	metas := make(map[uuid.UUID]*docmeta.Metadata)
+	for _, id := range linkIDs {
+		if d, ok := m.docs[id]; ok {
+			metas[id] = &d.Meta
+		}
+	}
+	return metas, nil
+}
+
+func Test_client(t *testing.T) {
+	idx := &memIndex{docs: make(map[uuid.UUID]*Document)}
+	srv := httptest.NewServer(NewHandler(idx))
+	defer srv.Close()
+	c := NewClient(srv.URL + "/")
+
+	doc := &Document{
+		Document: index.Document{
+			LinkID:    uuid.New(),
+			URL:       "https://example.com/",
+			Title:     "Example",
+			Content:   "content",
+			IndexedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		Language:   "en",
+		MediaType:  "application/pdf",
+		AnchorText: "example",
+		Meta: docmeta.Metadata{
+			Description: "description",
+			Entities:    []docmeta.Entity{{"@type": "Article", "name": "Example"}},
+		},
+		Fingerprint: 1<<63 | 1,
+	}
+
+	t.Run("test_index_document", func(t *testing.T) {
+		duplicateOf, err := c.IndexDocument(doc, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if duplicateOf != uuid.Nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", duplicateOf, uuid.Nil)
+		}
+		if got := idx.docs[doc.LinkID]; !reflect.DeepEqual(got, doc) {
+			t.Fatalf("\ngot:%+v \nexpect:%+v", got, doc)
+		}
+	})
+
+	t.Run("test_duplicate", func(t *testing.T) {
+		dup := *doc
+		dup.LinkID = uuid.New()
+		duplicateOf, err := c.IndexDocument(&dup, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if duplicateOf != doc.LinkID {
+			t.Fatalf("\ngot:%v \nexpect:%v", duplicateOf, doc.LinkID)
+		}
+	})
+
+	t.Run("test_error", func(t *testing.T) {
+		if _, err := c.IndexDocument(&Document{}, 3); err == nil {
+			t.Fatalf("\ngot:%v \nexpect:%v", err, "an error")
+		}
+	})
+
+	t.Run("test_pageranks", func(t *testing.T) {
+		ranked := &Document{Document: index.Document{LinkID: uuid.New(), URL: "https://example.com/ranked", Pagerank: 0.8}}
+		idx.docs[ranked.LinkID] = ranked
+		idx.docs[doc.LinkID].Pagerank = 0.2
+
+		ranks, err := c.Pageranks([]uuid.UUID{doc.LinkID, ranked.LinkID, uuid.New()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := map[uuid.UUID]float64{doc.LinkID: 0.25, ranked.LinkID: 1}
+		if !reflect.DeepEqual(ranks, expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", ranks, expect)
+		}
+	})
+
+	t.Run("test_media_types", func(t *testing.T) {
+		types, err := c.MediaTypes([]uuid.UUID{doc.LinkID, uuid.New()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := map[uuid.UUID]string{doc.LinkID: "application/pdf"}
+		if !reflect.DeepEqual(types, expect) {
+			t.Fatalf("\ngot:%v \nexpect:%v", types, expect)
+		}
+	})
+
+	t.Run("test_metadata", func(t *testing.T) {
+		metas, err := c.Metadata([]uuid.UUID{doc.LinkID, uuid.New()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := map[uuid.UUID]*docmeta.Metadata{doc.LinkID: &doc.Meta}
+		if !reflect.DeepEqual(metas, expect) {
+			t.Fatalf("\ngot:%+v \nexpect:%+v", metas, expect)
+		}
+	})
+
+	t.Run("test_delete_document", func(t *testing.T) {
+		if err := c.DeleteDocument(doc.LinkID); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := idx.docs[doc.LinkID]; ok {
+			t.Fatalf("\ngot:%v \nexpect:%v", ok, false)
+		}
+	})
+}
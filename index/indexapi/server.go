@@ -0,0 +1,149 @@
+package indexapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/odit-bit/se/index/docmeta"
+)
+
+var (
+	documentsEndpoint  = "/documents"
+	pageranksEndpoint  = "/pageranks"
+	mediaTypesEndpoint = "/media-types"
+	metadataEndpoint   = "/metadata"
+)
+
+// indexRequest is the body of a request to index a document.
+type indexRequest struct {
+	Document    *Document
+	MaxDistance int
+}
+
+// indexResponse is the body of the response to an indexRequest.
+type indexResponse struct {
+	DuplicateOf uuid.UUID
+}
+
+// linksRequest is the body of a request for what is known about the
+// documents of links, such as their PageRank scores.
+type linksRequest struct {
+	LinkIDs []uuid.UUID
+}
+
+// pageranksResponse is the body of the response to a request for PageRank
+// scores.
+type pageranksResponse struct {
+	Pageranks map[uuid.UUID]float64
+}
+
+// mediaTypesResponse is the body of the response to a request for media
+// types.
+type mediaTypesResponse struct {
+	MediaTypes map[uuid.UUID]string
+}
+
+// metadataResponse is the body of the response to a request for metadata.
+type metadataResponse struct {
+	Metadata map[uuid.UUID]*docmeta.Metadata
+}
+
+// NewHandler returns the HTTP handler serving the API of idx.
+func NewHandler(idx Indexer) http.Handler {
+	h := &handler{idx: idx}
+	r := chi.NewMux()
+	r.Post(documentsEndpoint, h.indexDocument)
+	r.Delete(documentsEndpoint+"/{id}", h.deleteDocument)
+	r.Post(pageranksEndpoint, h.pageranks)
+	r.Post(mediaTypesEndpoint, h.mediaTypes)
+	r.Post(metadataEndpoint, h.metadata)
+	return r
+}
+
+type handler struct {
+	idx Indexer
+}
+
+func (h *handler) indexDocument(w http.ResponseWriter, r *http.Request) {
+	var req indexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Document == nil {
+		http.Error(w, "invalid document", http.StatusBadRequest)
+		return
+	}
+	duplicateOf, err := h.idx.IndexDocument(req.Document, req.MaxDistance)
+	if err != nil {
+		log.Println("index document:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, indexResponse{DuplicateOf: duplicateOf})
+}
+
+func (h *handler) deleteDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid document id", http.StatusBadRequest)
+		return
+	}
+	if err := h.idx.DeleteDocument(id); err != nil {
+		log.Println("delete document:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) pageranks(w http.ResponseWriter, r *http.Request) {
+	var req linksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid link ids", http.StatusBadRequest)
+		return
+	}
+	ranks, err := h.idx.Pageranks(req.LinkIDs)
+	if err != nil {
+		log.Println("pageranks:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, pageranksResponse{Pageranks: ranks})
+}
+
+func (h *handler) mediaTypes(w http.ResponseWriter, r *http.Request) {
+	var req linksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid link ids", http.StatusBadRequest)
+		return
+	}
+	types, err := h.idx.MediaTypes(req.LinkIDs)
+	if err != nil {
+		log.Println("media types:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, mediaTypesResponse{MediaTypes: types})
+}
+
+func (h *handler) metadata(w http.ResponseWriter, r *http.Request) {
+	var req linksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid link ids", http.StatusBadRequest)
+		return
+	}
+	metas, err := h.idx.Metadata(req.LinkIDs)
+	if err != nil {
+		log.Println("metadata:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, metadataResponse{Metadata: metas})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("indexapi:", err)
+	}
+}
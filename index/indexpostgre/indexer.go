@@ -2,17 +2,24 @@ package indexpostgre
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/indexstore/index"
+	"github.com/odit-bit/se/index/docmeta"
+	"github.com/odit-bit/se/index/indexapi"
 )
 
 // it is like page-size
 var batchSize int = 10
 
 var _ index.Indexer = (*indexer)(nil)
+var _ indexapi.Indexer = (*indexer)(nil)
 
 type indexer struct {
 	db *sqlx.DB
@@ -35,18 +42,80 @@ func New(db *sqlx.DB) (*indexer, error) {
 // Index implements index.Indexer.
 // it uses to insert new document
 func (i *indexer) Index(doc *index.Document) error {
+	return i.IndexLanguage(doc, "")
+}
+
+// IndexLanguage inserts a document written in lang, an ISO 639-1 code. The
+// document text is analyzed with the text search configuration of lang.
+func (i *indexer) IndexLanguage(doc *index.Document, lang string) error {
 
 	if doc.LinkID == uuid.Nil {
 		return fmt.Errorf("indexer insert document: uuid cannot be nil")
 	}
 	doc.IndexedAt = doc.IndexedAt.UTC()
-	_, err := i.db.ExecContext(context.TODO(), insertDocumentQuery, doc.LinkID, doc.URL, doc.Title, doc.Content, doc.IndexedAt, doc.Pagerank)
+	_, err := i.db.ExecContext(context.TODO(), insertDocumentQuery, doc.LinkID, doc.URL, doc.Title, doc.Content, doc.IndexedAt, doc.Pagerank, lang, tsConfig(lang))
 	if err != nil {
 		return fmt.Errorf("indexer insert document error: %v, doc detail: %v", err, doc.URL)
 	}
 	return nil
 }
 
+// IndexDocument implements indexapi.Indexer. The document is written with a
+// single statement in the transaction of the near-duplicate lookup. The
+// transaction holds a lock on every band of the fingerprint, so of two
+// near-duplicates indexed at the same time only one is indexed.
+func (idx *indexer) IndexDocument(doc *indexapi.Document, maxDistance int) (uuid.UUID, error) {
+	if doc.LinkID == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("indexer index document: uuid cannot be nil")
+	}
+	meta, err := metadataArgs(&doc.Meta)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("indexer index document: %v", err)
+	}
+
+	tx, err := idx.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("indexer index document: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if doc.Fingerprint != 0 && maxDistance >= 0 {
+		if err := lockBands(tx, doc.Fingerprint, maxDistance); err != nil {
+			return uuid.Nil, fmt.Errorf("indexer index document: %v", err)
+		}
+		canonical, found, err := nearDuplicate(tx, doc.LinkID, doc.Fingerprint, maxDistance)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if found {
+			if err := markAlias(tx, doc.LinkID, canonical); err != nil {
+				return uuid.Nil, fmt.Errorf("indexer index document: %v", err)
+			}
+			return canonical, tx.Commit()
+		}
+	}
+
+	// a document without fingerprint is never found as a near-duplicate
+	var fingerprint, b0, b1, b2, b3 any
+	if doc.Fingerprint != 0 {
+		b := bands(doc.Fingerprint)
+		fingerprint, b0, b1, b2, b3 = int64(doc.Fingerprint), b[0], b[1], b[2], b[3]
+	}
+	args := []any{
+		doc.LinkID, doc.URL, doc.Title, doc.Content, doc.IndexedAt.UTC(), doc.Pagerank,
+		doc.Language, tsConfig(doc.Language), doc.AnchorText, doc.MediaType,
+		fingerprint, b0, b1, b2, b3,
+	}
+	if _, err := tx.ExecContext(context.TODO(), upsertDocumentQuery, append(args, meta...)...); err != nil {
+		return uuid.Nil, fmt.Errorf("indexer index document: %v, doc detail: %v", err, doc.URL)
+	}
+	// the document is indexed in its own right, it is no longer an alias
+	if _, err := tx.ExecContext(context.TODO(), deleteAliasQuery, doc.LinkID); err != nil {
+		return uuid.Nil, fmt.Errorf("indexer index document: %v", err)
+	}
+	return uuid.Nil, tx.Commit()
+}
+
 // Find implements index.Indexer.
 func (idx *indexer) Find(linkID uuid.UUID) (*index.Document, error) {
 	var doc index.Document
@@ -74,10 +143,11 @@ func (idx *indexer) Search(query index.Query) (index.Iterator, error) {
 
 	pageSize := batchSize
 	offset := query.Offset
+	search := parseSearch(query.Expression)
 
-	if query.Expression == "" {
-		queryDoc = searchAllQuery
-		queryCount = searchAllCountQuery
+	if search.terms == "" {
+		queryDoc = fmt.Sprintf(searchAllQuery, search.filter())
+		queryCount = fmt.Sprintf(searchAllCountQuery, search.filter())
 
 		//get the matchedCount document
 		err := idx.db.QueryRowxContext(context.TODO(), queryCount).Scan(&matchedCount)
@@ -91,22 +161,23 @@ func (idx *indexer) Search(query index.Query) (index.Iterator, error) {
 		}
 
 	} else {
+		var tsquery string
 		switch query.Type {
 		case 1:
-			queryDoc = searchPhraseQuery
-			queryCount = searchPhraseCountQuery
+			tsquery = search.tsquery("phraseto_tsquery")
 		default:
-			queryDoc = searchMatchQuery
-			queryCount = searchMatchCountQuery
+			tsquery = search.tsquery("websearch_to_tsquery")
 		}
+		queryDoc = fmt.Sprintf(searchTermsQuery, tsquery, search.filter())
+		queryCount = fmt.Sprintf(searchTermsCountQuery, tsquery, search.filter())
 
 		//get the matchedCount document
-		err := idx.db.QueryRowxContext(context.TODO(), queryCount, query.Expression).Scan(&matchedCount)
+		err := idx.db.QueryRowxContext(context.TODO(), queryCount, search.terms).Scan(&matchedCount)
 		if err != nil {
 			return nil, fmt.Errorf("index search documents matched count: %v", err)
 		}
 
-		rows, err = idx.db.QueryxContext(context.TODO(), queryDoc, query.Expression, offset, pageSize)
+		rows, err = idx.db.QueryxContext(context.TODO(), queryDoc, search.terms, offset, pageSize)
 		if err != nil {
 			return nil, fmt.Errorf("index search documents: %v", err)
 		}
@@ -133,6 +204,34 @@ func (idx *indexer) UpdateRank(linkID uuid.UUID, score float64) error {
 	return nil
 }
 
+// Pageranks implements indexapi.Indexer.
+func (idx *indexer) Pageranks(linkIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	ids := make([]string, len(linkIDs))
+	for i, id := range linkIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := idx.db.QueryxContext(context.TODO(), pageranksQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("indexer pageranks: %v", err)
+	}
+	defer rows.Close()
+
+	ranks := make(map[uuid.UUID]float64, len(linkIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var rank float64
+		if err := rows.Scan(&id, &rank); err != nil {
+			return nil, fmt.Errorf("indexer pageranks: %v", err)
+		}
+		ranks[id] = rank
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("indexer pageranks: %v", err)
+	}
+	return ranks, nil
+}
+
 // ================= iterator
 
 var _ index.Iterator = (*iterator)(nil)
@@ -203,3 +302,242 @@ func (it *iterator) fecthDoc() bool {
 	it.latchedDoc = &doc
 	return true
 }
+
+// ================= near-duplicates
+
+// nearDuplicate returns the indexed document whose content fingerprint is
+// closest to fingerprint, if it differs in at most maxDistance bits. Distances
+// below fingerprintBands are looked up by band, larger ones scan every
+// fingerprint.
+func nearDuplicate(q sqlx.QueryerContext, linkID uuid.UUID, fingerprint uint64, maxDistance int) (uuid.UUID, bool, error) {
+	var row *sqlx.Row
+	if maxDistance < fingerprintBands {
+		b := bands(fingerprint)
+		row = q.QueryRowxContext(context.TODO(), nearDuplicateQuery, linkID, int64(fingerprint), maxDistance, b[0], b[1], b[2], b[3])
+	} else {
+		row = q.QueryRowxContext(context.TODO(), nearDuplicateScanQuery, linkID, int64(fingerprint), maxDistance)
+	}
+	var canonical uuid.UUID
+	err := row.Scan(&canonical)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, false, nil
+		}
+		return uuid.Nil, false, fmt.Errorf("indexer near duplicate: %v", err)
+	}
+	return canonical, true, nil
+}
+
+// fingerprintBands is the number of 16-bit bands a fingerprint is split into.
+// Two fingerprints that differ in fewer bits than there are bands share at
+// least one band.
+const fingerprintBands = 4
+
+// bands splits fingerprint into its bands, the most significant bits first.
+func bands(fingerprint uint64) [fingerprintBands]int32 {
+	var b [fingerprintBands]int32
+	for i := range b {
+		b[i] = int32(fingerprint >> (16 * (fingerprintBands - 1 - i)) & 0xffff)
+	}
+	return b
+}
+
+// lockBands takes the advisory lock of every band of fingerprint for the rest
+// of tx, near-duplicates within fewer bits than there are bands share a band.
+// Larger distances are not covered by the bands and take a single lock.
+func lockBands(tx *sqlx.Tx, fingerprint uint64, maxDistance int) error {
+	if maxDistance >= fingerprintBands {
+		_, err := tx.ExecContext(context.TODO(), lockBandQuery, fingerprintLockClass, -1)
+		return err
+	}
+	// the keys increase with the band, so every transaction takes the locks
+	// in the same order
+	for i, b := range bands(fingerprint) {
+		if _, err := tx.ExecContext(context.TODO(), lockBandQuery, fingerprintLockClass, int32(i)<<16|b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markAlias records linkID as a near-duplicate of canonicalID and removes any
+// document previously indexed for linkID.
+func markAlias(tx *sqlx.Tx, linkID, canonicalID uuid.UUID) error {
+	if _, err := tx.ExecContext(context.TODO(), upsertAliasQuery, linkID, canonicalID, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(context.TODO(), deleteDocumentQuery, linkID)
+	return err
+}
+
+// ================= dead pages
+
+// DeleteDocument removes the document of linkID from the index, along with
+// any alias recorded for it.
+func (idx *indexer) DeleteDocument(linkID uuid.UUID) error {
+	tx, err := idx.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return fmt.Errorf("indexer delete document: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(context.TODO(), deleteDocumentQuery, linkID); err != nil {
+		return fmt.Errorf("indexer delete document: %v", err)
+	}
+	if _, err := tx.ExecContext(context.TODO(), deleteAliasQuery, linkID); err != nil {
+		return fmt.Errorf("indexer delete document: %v", err)
+	}
+	return tx.Commit()
+}
+
+// ================= media type
+
+// MediaTypes returns the media types of the indexed documents of linkIDs.
+func (idx *indexer) MediaTypes(linkIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	ids := make([]string, len(linkIDs))
+	for i, id := range linkIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := idx.db.QueryxContext(context.TODO(), mediaTypesQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("indexer media types: %v", err)
+	}
+	defer rows.Close()
+
+	types := make(map[uuid.UUID]string, len(linkIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var mediaType string
+		if err := rows.Scan(&id, &mediaType); err != nil {
+			return nil, fmt.Errorf("indexer media types: %v", err)
+		}
+		types[id] = mediaType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("indexer media types: %v", err)
+	}
+	return types, nil
+}
+
+// ================= metadata
+
+// metadataArgs returns the values of the metadata columns, in the order of
+// upsertDocumentQuery.
+func metadataArgs(meta *docmeta.Metadata) ([]any, error) {
+	opengraph, err := jsonFields(meta.OpenGraph)
+	if err != nil {
+		return nil, err
+	}
+	twitter, err := jsonFields(meta.Twitter)
+	if err != nil {
+		return nil, err
+	}
+	entities, types, err := jsonEntities(meta.Entities)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		meta.Description,
+		strings.Join(meta.Headings, "\n"),
+		opengraph,
+		twitter,
+		nullTime(meta.Published),
+		nullTime(meta.Modified),
+		meta.Author,
+		meta.Favicon,
+		entities,
+		types,
+	}, nil
+}
+
+// Metadata returns the metadata of the indexed documents of linkIDs.
+func (idx *indexer) Metadata(linkIDs []uuid.UUID) (map[uuid.UUID]*docmeta.Metadata, error) {
+	ids := make([]string, len(linkIDs))
+	for i, id := range linkIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := idx.db.QueryxContext(context.TODO(), metadataQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("indexer metadata: %v", err)
+	}
+	defer rows.Close()
+
+	metas := make(map[uuid.UUID]*docmeta.Metadata, len(linkIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var meta docmeta.Metadata
+		var headings string
+		var opengraph, twitter, entities []byte
+		var published, modified sql.NullTime
+		if err := rows.Scan(&id, &meta.Description, &headings, &opengraph, &twitter, &published, &modified, &meta.Author, &meta.Favicon, &entities); err != nil {
+			return nil, fmt.Errorf("indexer metadata: %v", err)
+		}
+		if headings != "" {
+			meta.Headings = strings.Split(headings, "\n")
+		}
+		if opengraph != nil {
+			if err := json.Unmarshal(opengraph, &meta.OpenGraph); err != nil {
+				return nil, fmt.Errorf("indexer metadata: %v", err)
+			}
+		}
+		if twitter != nil {
+			if err := json.Unmarshal(twitter, &meta.Twitter); err != nil {
+				return nil, fmt.Errorf("indexer metadata: %v", err)
+			}
+		}
+		if entities != nil {
+			if err := json.Unmarshal(entities, &meta.Entities); err != nil {
+				return nil, fmt.Errorf("indexer metadata: %v", err)
+			}
+		}
+		meta.Published = published.Time
+		meta.Modified = modified.Time
+		metas[id] = &meta
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("indexer metadata: %v", err)
+	}
+	return metas, nil
+}
+
+// jsonFields encodes fields as a json object, nil if there are none.
+func jsonFields(fields map[string]string) (any, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// jsonEntities encodes entities as a json array, nil if there are none, and
+// returns their distinct types in lower case.
+func jsonEntities(entities []docmeta.Entity) (any, []string, error) {
+	types := []string{}
+	if len(entities) == 0 {
+		return nil, types, nil
+	}
+	b, err := json.Marshal(entities)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool)
+	for _, e := range entities {
+		for _, t := range e.Types() {
+			if t = strings.ToLower(t); !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	return string(b), types, nil
+}
+
+// nullTime is NULL for the zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
@@ -11,6 +11,8 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/indexstore/index"
+	"github.com/odit-bit/se/index/docmeta"
+	"github.com/odit-bit/se/index/indexapi"
 )
 
 func Test_postgre_indexer(t *testing.T) {
@@ -126,6 +128,327 @@ func Test_postgre_indexer(t *testing.T) {
 		t.Fatal("failed update pager rank score", idx1.Pagerank)
 	}
 
+	ranks, err := pgIndex.Pageranks([]uuid.UUID{idx1.LinkID, uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// relative to the best ranked document
+	if rank, ok := ranks[idx1.LinkID]; len(ranks) != 1 || !ok || rank <= 0 || rank > 1 {
+		t.Fatalf("\ngot:%v \nmessage:%v", ranks, "expected one rank in (0, 1]")
+	}
+
+	//=================== near-duplicates
+	fingerprinted := func(fingerprint uint64) *indexapi.Document {
+		return &indexapi.Document{
+			Document: index.Document{
+				LinkID:    uuid.New(),
+				URL:       "www.original.com",
+				Title:     "original",
+				Content:   "an original page",
+				IndexedAt: time.Now().UTC(),
+			},
+			Fingerprint: fingerprint,
+		}
+	}
+	original := fingerprinted(0xF0F0)
+	if canonical, err := pgIndex.IndexDocument(original, 3); err != nil || canonical != uuid.Nil {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", canonical, err, uuid.Nil)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		fingerprint uint64
+		maxDistance int
+		expect      uuid.UUID
+	}{
+		{"near", 0xF0F1, 3, original.LinkID},
+		{"distant", 0x0F0F, 3, uuid.Nil},
+		// the differing bits fall into three bands, the fourth is shared
+		{"other bands", 0xF0F0 ^ (1 | 1<<20 | 1<<40), 3, original.LinkID},
+		// larger distances than the bands cover scan every fingerprint
+		{"no shared band", 0xF0F0 ^ (1 | 1<<20 | 1<<40 | 1<<60), 4, original.LinkID},
+	} {
+		doc := fingerprinted(tc.fingerprint)
+		canonical, err := pgIndex.IndexDocument(doc, tc.maxDistance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canonical != tc.expect {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", canonical, tc.expect, tc.name)
+		}
+		// a near-duplicate is recorded as an alias instead of being indexed
+		if _, err := pgIndex.Find(doc.LinkID); (err == nil) != (tc.expect == uuid.Nil) {
+			t.Fatalf("\ngot:%v \nmessage:%v", err, tc.name)
+		}
+	}
+
+	// a document that becomes a near-duplicate is no longer indexed
+	changed := fingerprinted(0x00FF)
+	if canonical, err := pgIndex.IndexDocument(changed, 3); err != nil || canonical != uuid.Nil {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", canonical, err, uuid.Nil)
+	}
+	changed.Fingerprint = 0xF0F2
+	if canonical, err := pgIndex.IndexDocument(changed, 3); err != nil || canonical != original.LinkID {
+		t.Fatalf("\ngot:%v %v \nexpect:%v", canonical, err, original.LinkID)
+	}
+	if _, err := pgIndex.Find(changed.LinkID); err == nil {
+		t.Fatal("aliased document is still indexed")
+	}
+
+	//=================== anchor text
+	thin := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "www.thin.com",
+		Title:     "home",
+		Content:   "welcome",
+		IndexedAt: time.Now().UTC(),
+	}
+	mention := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "www.mention.com",
+		Title:     "notes",
+		Content:   "notes on kubernetes",
+		IndexedAt: time.Now().UTC(),
+	}
+	for _, doc := range []*indexapi.Document{
+		{Document: *thin, AnchorText: "kubernetes operator"},
+		{Document: *mention},
+	} {
+		if _, err := pgIndex.IndexDocument(doc, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the anchor text is weighted above the text of the page
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "kubernetes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	var ranked []uuid.UUID
+	for docIt.Next() {
+		ranked = append(ranked, docIt.Document().LinkID)
+	}
+	if expect := []uuid.UUID{thin.LinkID, mention.LinkID}; fmt.Sprint(ranked) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", ranked, expect)
+	}
+
+	// the anchor text survives re-indexing the document
+	if err := pgIndex.Index(thin); err != nil {
+		t.Fatal(err)
+	}
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "kubernetes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	if !docIt.Next() || docIt.Document().LinkID != thin.LinkID {
+		t.Fatal("anchor text lost on re-index")
+	}
+
+	//=================== language
+	id := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "www.resep.co.id",
+		Title:     "resep",
+		Content:   "kumpulan makanan khas daerah",
+		IndexedAt: time.Now().UTC(),
+	}
+	if err := pgIndex.IndexLanguage(id, "id"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the indonesian stemmer reduces "makanan" to "makan"
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "makan lang:id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	if !docIt.Next() || docIt.Document().LinkID != id.LinkID {
+		t.Fatal("document not found in its language")
+	}
+
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "lang:en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	for docIt.Next() {
+		if docIt.Document().LinkID == id.LinkID {
+			t.Fatal("language filter not applied")
+		}
+	}
+
+	// without lang: the terms are parsed in the language of each document
+	en := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "www.running.com",
+		Title:     "running",
+		Content:   "gardening and running",
+		IndexedAt: time.Now().UTC(),
+	}
+	if err := pgIndex.IndexLanguage(en, "en"); err != nil {
+		t.Fatal(err)
+	}
+	for expression, expect := range map[string]uint64{
+		"makan":                     1,
+		"gardening running":         1,
+		"gardening -running":        0,
+		"gardening -runs":           0,
+		"gardening -the -and":       1,
+		"makan -kumpulan":           0,
+		"gardening lang:id":         0,
+		"\"gardening and running\"": 1,
+	} {
+		docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: expression})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer docIt.Close()
+		if got := docIt.TotalCount(); got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, expect, expression)
+		}
+	}
+
+	//=================== media type
+	pdf := &indexapi.Document{
+		Document: index.Document{
+			LinkID:    uuid.New(),
+			URL:       "www.paper.com/paper.pdf",
+			Title:     "paper",
+			Content:   "a paper",
+			IndexedAt: time.Now().UTC(),
+		},
+		MediaType: "application/pdf",
+	}
+	if _, err := pgIndex.IndexDocument(pdf, 3); err != nil {
+		t.Fatal(err)
+	}
+	// documents without a media type are HTML pages
+	types, err := pgIndex.MediaTypes([]uuid.UUID{pdf.LinkID, thin.LinkID, id.LinkID, uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[uuid.UUID]string{pdf.LinkID: "application/pdf", thin.LinkID: "text/html", id.LinkID: "text/html"}
+	if fmt.Sprint(types) != fmt.Sprint(expect) {
+		t.Fatalf("\ngot:%v \nexpect:%v", types, expect)
+	}
+
+	//=================== metadata
+	published := time.Date(2023, 11, 2, 8, 0, 0, 0, time.UTC)
+	meta := &docmeta.Metadata{
+		Description: "recipes of regional dishes",
+		Headings:    []string{"Regional dishes", "Desserts"},
+		OpenGraph:   map[string]string{"title": "Dishes"},
+		Published:   published,
+		Author:      "Jo Doe",
+		Entities: []docmeta.Entity{
+			{"@type": "Recipe", "name": "Klepon", "recipeYield": "12 pieces"},
+		},
+	}
+	recipes := &indexapi.Document{
+		Document: index.Document{
+			LinkID:    uuid.New(),
+			URL:       "www.recipes.com",
+			Title:     "recipes",
+			Content:   "recipes",
+			IndexedAt: time.Now().UTC(),
+		},
+		Meta: *meta,
+	}
+	if _, err := pgIndex.IndexDocument(recipes, 3); err != nil {
+		t.Fatal(err)
+	}
+	metas, err := pgIndex.Metadata([]uuid.UUID{recipes.LinkID, id.LinkID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metas[recipes.LinkID]; got == nil || fmt.Sprint(got) != fmt.Sprint(meta) {
+		t.Fatalf("\ngot:%+v \nexpect:%+v", got, meta)
+	}
+	if got := metas[id.LinkID]; got == nil || got.Description != "" || !got.Published.IsZero() {
+		t.Fatalf("\ngot:%+v \nmessage:%v", got, "document without metadata")
+	}
+
+	// the headings are searched along with the document text
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "desserts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	if !docIt.Next() || docIt.Document().LinkID != recipes.LinkID {
+		t.Fatal("document not found by its headings")
+	}
+
+	// the type: operator matches the types of the entities
+	for expression, expect := range map[string]uint64{
+		"desserts type:recipe":  1,
+		"type:Recipe":           1,
+		"desserts type:Product": 0,
+	} {
+		docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: expression})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer docIt.Close()
+		if got := docIt.TotalCount(); got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v \nmessage:%v", got, expect, expression)
+		}
+	}
+
+	//=================== index document
+	page := &indexapi.Document{
+		Document: index.Document{
+			LinkID:    uuid.New(),
+			URL:       "www.page.com",
+			Title:     "page",
+			Content:   "a page about gardening",
+			IndexedAt: time.Now().UTC(),
+		},
+		Language:    "en",
+		MediaType:   "text/plain",
+		AnchorText:  "allotment",
+		Meta:        docmeta.Metadata{Description: "gardening tips"},
+		Fingerprint: 0xABCD,
+	}
+	duplicateOf, err := pgIndex.IndexDocument(page, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicateOf != uuid.Nil {
+		t.Fatalf("\ngot:%v \nexpect:%v", duplicateOf, uuid.Nil)
+	}
+	docIt, err = pgIndex.Search(index.Query{Type: 0, Expression: "allotment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docIt.Close()
+	if !docIt.Next() || docIt.Document().LinkID != page.LinkID {
+		t.Fatal("document not found by its anchor text")
+	}
+
+	// a near-duplicate is recorded as an alias instead of being indexed
+	copied := *page
+	copied.LinkID, copied.Fingerprint = uuid.New(), 0xABCF
+	duplicateOf, err = pgIndex.IndexDocument(&copied, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicateOf != page.LinkID {
+		t.Fatalf("\ngot:%v \nexpect:%v", duplicateOf, page.LinkID)
+	}
+	if _, err := pgIndex.Find(copied.LinkID); err == nil {
+		t.Fatal("near-duplicate document is indexed")
+	}
+
+	//=================== dead pages
+	if err := pgIndex.DeleteDocument(id.LinkID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pgIndex.Find(id.LinkID); err == nil {
+		t.Fatal("deleted document is still indexed")
+	}
+
 }
 
 func asserDocIterator(expect []index.Document, docIt index.Iterator, t *testing.T) {
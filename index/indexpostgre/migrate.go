@@ -2,18 +2,29 @@ package indexpostgre
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-)
+	"strings"
 
-var tsvector = "to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content,''))"
+	"github.com/jmoiron/sqlx"
+)
 
+// the text of the document, the anchor text of the links pointing to it and
+// the headings and meta description of the page. Anchor text and headings are
+// weighted highest, the description higher than the text of the page itself.
+// All are analyzed with the text search configuration of the document
+// language.
+var tsvector = "to_tsvector(ts_config, coalesce(title, '') || ' ' || coalesce(content,''))" +
+	" || setweight(to_tsvector(ts_config, coalesce(anchor_text, '')), 'B')" +
+	" || setweight(to_tsvector(ts_config, coalesce(headings, '')), 'B')" +
+	" || setweight(to_tsvector(ts_config, coalesce(description, '')), 'C')"
 
 const dropDocumentsTable = `
-	DROP TABLE IF EXISTS documents;
+	DROP TABLE IF EXISTS documents, document_aliases;
 `
 
 const dropDocumentsIndex = `
-	DROP INDEX ts_idx;
+	DROP INDEX IF EXISTS ts_idx;
 `
 
 func (idx *indexer) drop() error {
@@ -43,36 +54,239 @@ const createDocumentsTable = `
 
 // full text search implementation
 
+// aggregated anchor text of the links pointing to the document
+const alterColumnAnchorText = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS anchor_text text;
+`
+
+// the media type of the document, e.g. application/pdf for documents
+// extracted from PDF files
+const alterColumnMediaType = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS media_type text NOT NULL DEFAULT 'text/html';
+`
+
+// the metadata declared by the page, headings are separated by newlines and
+// the OpenGraph and Twitter card fields are kept as json objects
+const alterColumnMetadata = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS description text,
+	ADD COLUMN IF NOT EXISTS headings text,
+	ADD COLUMN IF NOT EXISTS opengraph jsonb,
+	ADD COLUMN IF NOT EXISTS twitter jsonb,
+	ADD COLUMN IF NOT EXISTS published_at TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS modified_at TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS author text,
+	ADD COLUMN IF NOT EXISTS favicon text;
+`
+
+// the schema.org entities declared by the page as a json array, and their
+// types in lower case for the type: search operator
+const alterColumnEntities = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS entities jsonb,
+	ADD COLUMN IF NOT EXISTS entity_types text[] NOT NULL DEFAULT '{}';
+`
+
+const createEntityTypesIndex = `
+	CREATE INDEX IF NOT EXISTS entity_types_idx ON documents USING gin(entity_types)
+`
+
+// the detected language of the document (ISO 639-1 code, empty if unknown) and
+// the text search configuration it is analyzed with
+var alterColumnLanguage = fmt.Sprintf(`
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS language text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS ts_config regconfig NOT NULL DEFAULT '%s';
+`, default_config)
+
 var (
-	//generate text-search column for table
+	//generate text-search column for table, the expression is kept as the
+	//column comment so a changed expression can be detected
 	alterColumnSearch = fmt.Sprintf(`
 	ALTER TABLE documents
-	ADD COLUMN IF NOT EXISTS ts tsvector GENERATED ALWAYS AS (%s) STORED;
+	ADD COLUMN ts tsvector GENERATED ALWAYS AS (%s) STORED;
 `, tsvector)
 
+	commentColumnSearch = fmt.Sprintf(`
+	COMMENT ON COLUMN documents.ts IS '%s';
+`, strings.ReplaceAll(tsvector, "'", "''"))
+
+	//the expression the existing ts column was generated with
+	searchColumnExpression = `
+	SELECT coalesce(col_description(attrelid, attnum), '')
+	FROM pg_attribute
+	WHERE attrelid = 'documents'::regclass AND attname = 'ts' AND NOT attisdropped
+`
+
+	dropColumnSearch = `
+	ALTER TABLE documents
+	DROP COLUMN IF EXISTS ts;
+`
+
 	//create index for text-search
-	createSearchIndex = fmt.Sprintf(`
-	CREATE INDEX IF NOT EXISTS ts_idx ON documents USING gin(%v)
-`, tsvector)
+	createSearchIndex = `
+	CREATE INDEX IF NOT EXISTS ts_idx ON documents USING gin(ts)
+`
 )
 
+// migrateSearch (re)creates the ts column and its index when the column does
+// not exist yet or was generated with another expression.
+func migrateSearch(tx *sqlx.Tx) error {
+	var expr string
+	err := tx.QueryRowxContext(context.TODO(), searchColumnExpression).Scan(&expr)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("lookup ts column: %v", err)
+	}
+	if err == nil && expr == tsvector {
+		return nil
+	}
+
+	for _, query := range []string{dropDocumentsIndex, dropColumnSearch, alterColumnSearch, commentColumnSearch} {
+		if _, err := tx.ExecContext(context.TODO(), query); err != nil {
+			return fmt.Errorf("rebuild ts column: %v", err)
+		}
+	}
+	return nil
+}
+
+// near-duplicate detection
+
+// simhash of the document content
+const alterColumnFingerprint = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS fingerprint bigint;
+`
+
+// the fingerprint split into fingerprintBands bands of 16 bits, each indexed,
+// so that near-duplicates are looked up without scanning every fingerprint
+const alterColumnFingerprintBands = `
+	ALTER TABLE documents
+	ADD COLUMN IF NOT EXISTS fp_band0 integer,
+	ADD COLUMN IF NOT EXISTS fp_band1 integer,
+	ADD COLUMN IF NOT EXISTS fp_band2 integer,
+	ADD COLUMN IF NOT EXISTS fp_band3 integer;
+`
+
+// the bands of the fingerprints stored before the bands were
+const backfillFingerprintBands = `
+	UPDATE documents
+	SET fp_band0 = (fingerprint >> 48) & 65535,
+		fp_band1 = (fingerprint >> 32) & 65535,
+		fp_band2 = (fingerprint >> 16) & 65535,
+		fp_band3 = fingerprint & 65535
+	WHERE fingerprint IS NOT NULL AND fp_band0 IS NULL
+`
+
+// %d is the number of the band
+const createFingerprintBandIndex = `
+	CREATE INDEX IF NOT EXISTS documents_fp_band%[1]d_idx ON documents (fp_band%[1]d)
+	WHERE fp_band%[1]d IS NOT NULL
+`
+
+// the class of the advisory locks taken on the bands of a fingerprint while
+// its document is indexed
+const fingerprintLockClass = 0x5e1d0d
+
+// documents that are near-duplicates of a canonical document are recorded
+// here instead of being indexed
+const createAliasesTable = `
+	CREATE TABLE IF NOT EXISTS document_aliases(
+		linkID uuid PRIMARY KEY,
+		canonical_linkID uuid NOT NULL,
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+`
+
+// the key of the advisory lock the migrations run under, so that replicas
+// started at the same time do not migrate the tables concurrently
+const migrationLockKey = 0x5e1d0c
+
+const migrationLockQuery = `
+	SELECT pg_advisory_xact_lock($1)
+`
+
+// migrate creates and alters the tables in a single transaction holding the
+// migration lock.
 func (idx *indexer) migrate() error {
+	tx, err := idx.db.BeginTxx(context.TODO(), nil)
+	if err != nil {
+		return fmt.Errorf("begin migration: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(context.TODO(), migrationLockQuery, migrationLockKey); err != nil {
+		return fmt.Errorf("lock migration: %v", err)
+	}
+
 	//create table
-	_, err := idx.db.ExecContext(context.TODO(), createDocumentsTable)
+	_, err = tx.ExecContext(context.TODO(), createDocumentsTable)
 	if err != nil {
 		return fmt.Errorf("create table: %v", err)
 	}
 
-	// alter columns ts
-	_, err = idx.db.ExecContext(context.TODO(), alterColumnSearch)
+	_, err = tx.ExecContext(context.TODO(), alterColumnAnchorText)
+	if err != nil {
+		return fmt.Errorf("alter anchor_text column: %v", err)
+	}
+
+	_, err = tx.ExecContext(context.TODO(), alterColumnLanguage)
 	if err != nil {
-		return fmt.Errorf("alter ts column: %v", err)
+		return fmt.Errorf("alter language column: %v", err)
+	}
+
+	_, err = tx.ExecContext(context.TODO(), alterColumnMediaType)
+	if err != nil {
+		return fmt.Errorf("alter media_type column: %v", err)
+	}
+
+	_, err = tx.ExecContext(context.TODO(), alterColumnMetadata)
+	if err != nil {
+		return fmt.Errorf("alter metadata columns: %v", err)
+	}
+
+	_, err = tx.ExecContext(context.TODO(), alterColumnEntities)
+	if err != nil {
+		return fmt.Errorf("alter entities columns: %v", err)
+	}
+
+	_, err = tx.ExecContext(context.TODO(), createEntityTypesIndex)
+	if err != nil {
+		return fmt.Errorf("create entity_types index: %v", err)
+	}
+
+	// alter columns ts
+	if err := migrateSearch(tx); err != nil {
+		return err
 	}
 
 	//create index search
-	_, err = idx.db.ExecContext(context.TODO(), createSearchIndex)
+	_, err = tx.ExecContext(context.TODO(), createSearchIndex)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// near-duplicate detection
+	_, err = tx.ExecContext(context.TODO(), alterColumnFingerprint)
+	if err != nil {
+		return fmt.Errorf("alter fingerprint column: %v", err)
+	}
+
+	for _, query := range []string{alterColumnFingerprintBands, backfillFingerprintBands} {
+		if _, err := tx.ExecContext(context.TODO(), query); err != nil {
+			return fmt.Errorf("fingerprint bands: %v", err)
+		}
+	}
+	for band := 0; band < fingerprintBands; band++ {
+		if _, err := tx.ExecContext(context.TODO(), fmt.Sprintf(createFingerprintBandIndex, band)); err != nil {
+			return fmt.Errorf("create fingerprint band index: %v", err)
+		}
+	}
+
+	_, err = tx.ExecContext(context.TODO(), createAliasesTable)
+	if err != nil {
+		return fmt.Errorf("create aliases table: %v", err)
+	}
+	return tx.Commit()
 }
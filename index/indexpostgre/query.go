@@ -1,55 +1,40 @@
 package indexpostgre
 
-// like matchDocQuery but whe $1 is blank or space it will return all
-const searchMatchCountQuery = `
-	SELECT COUNT(*) FROM documents
-	WHERE ts @@ websearch_to_tsquery('english', $1)
-`
-
-// search words match
-var searchMatchQuery = `
-	SELECT linkID, url, title, content, indexed_at, pagerank
-	FROM documents
-	WHERE ts @@ websearch_to_tsquery('english', $1)
-	ORDER BY
-		ts_rank(ts, websearch_to_tsquery('english', $1)),
-		pagerank DESC	
-
-	OFFSET $2 ROWS
-	LIMIT $3; --FETCH FIRST ($3) ROWS ONLY;
-`
-
-// search phrase match
-var searchPhraseQuery = `
+// search words or phrase match, formatted with the tsquery matched against
+// and the filter condition of the search operators. The best matches come
+// first, e.g. a match in the anchor text before one in the text of the page.
+var searchTermsQuery = `
 	SELECT linkID, url, title, content, indexed_at, pagerank
 	FROM documents
-	WHERE
-		ts @@ phraseto_tsquery('english', $1)
+	WHERE ts @@ %[1]s AND %[2]s
 	ORDER BY
-		ts_rank(ts, phraseto_tsquery('english', $1)),	
+		ts_rank(ts, %[1]s) DESC,
 		pagerank DESC
 
 	OFFSET $2 ROWS
 	LIMIT $3; --FETCH FIRST ($3) ROWS ONLY;
 `
 
-const searchPhraseCountQuery = `
+var searchTermsCountQuery = `
 	SELECT COUNT(*) FROM documents
-	WHERE ts @@ phraseto_tsquery('english', $1)
+	WHERE ts @@ %[1]s AND %[2]s
 `
 
-// match anything (select all)
+// match anything (select all), formatted with the filter condition of the
+// search operators
 var searchAllQuery = `
 	SELECT linkID, url, title, content, indexed_at, pagerank
 	FROM documents
+	WHERE %[1]s
 	ORDER BY linkID
 
 	OFFSET $1 ROWS
 	LIMIT $2; --FETCH FIRST ($2) ROWS ONLY;
 `
 
-const searchAllCountQuery = `
+var searchAllCountQuery = `
 	SELECT COUNT(*) FROM documents
+	WHERE %[1]s
 `
 
 const updateScoreQuery = `
@@ -59,18 +44,122 @@ const updateScoreQuery = `
 
 `
 
+// the pagerank of the documents relative to the best ranked document
+const pageranksQuery = `
+	SELECT linkID, COALESCE(pagerank / (SELECT NULLIF(MAX(pagerank), 0) FROM documents), 0)
+	FROM documents
+	WHERE linkID = ANY($1::uuid[])
+`
+
+// the document along with everything known about its page, $10 the media type
+// or empty for an HTML page, $11..$15 the fingerprint and its bands and
+// $16..$25 the metadata. The search column is computed once per write.
+const upsertDocumentQuery = `
+	INSERT INTO documents (linkID, url, title, content, indexed_at, pagerank, language, ts_config,
+		anchor_text, media_type, fingerprint, fp_band0, fp_band1, fp_band2, fp_band3,
+		description, headings, opengraph, twitter, published_at, modified_at, author, favicon, entities, entity_types)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::regconfig,
+		$9, COALESCE(NULLIF($10, ''), 'text/html'), $11, $12, $13, $14, $15,
+		$16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
+	ON CONFLICT (linkID) DO
+	UPDATE
+		SET url = EXCLUDED.url,
+			title = EXCLUDED.title,
+			content = EXCLUDED.content,
+			language = EXCLUDED.language,
+			ts_config = EXCLUDED.ts_config,
+			indexed_at = NOW(),
+			anchor_text = EXCLUDED.anchor_text,
+			media_type = EXCLUDED.media_type,
+			fingerprint = EXCLUDED.fingerprint,
+			fp_band0 = EXCLUDED.fp_band0,
+			fp_band1 = EXCLUDED.fp_band1,
+			fp_band2 = EXCLUDED.fp_band2,
+			fp_band3 = EXCLUDED.fp_band3,
+			description = EXCLUDED.description,
+			headings = EXCLUDED.headings,
+			opengraph = EXCLUDED.opengraph,
+			twitter = EXCLUDED.twitter,
+			published_at = EXCLUDED.published_at,
+			modified_at = EXCLUDED.modified_at,
+			author = EXCLUDED.author,
+			favicon = EXCLUDED.favicon,
+			entities = EXCLUDED.entities,
+			entity_types = EXCLUDED.entity_types
+`
+
+// $1 the lock class of fingerprint bands, $2 the band
+const lockBandQuery = `
+	SELECT pg_advisory_xact_lock($1, $2)
+`
+
 const findDocumentQuery = `
 	SELECT linkID, url, title, content, indexed_at, pagerank FROM documents
 	WHERE linkID = $1
 `
 
 const insertDocumentQuery = `
-	INSERT INTO documents (linkID, url, title, content, indexed_at, pagerank)
-	VALUES($1,$2,$3,$4, $5, $6)
+	INSERT INTO documents (linkID, url, title, content, indexed_at, pagerank, language, ts_config)
+	VALUES($1,$2,$3,$4, $5, $6, $7, $8::regconfig)
 	ON CONFLICT (linkID) DO 
 	UPDATE
 		SET url = EXCLUDED.url,
 			title = EXCLUDED.title,
 			content = EXCLUDED.content,
+			language = EXCLUDED.language,
+			ts_config = EXCLUDED.ts_config,
 			indexed_at = NOW();
 `
+
+// the closest document whose fingerprint differs in at most $3 bits, the
+// candidates are the documents sharing a band ($4..$7) with the fingerprint
+const nearDuplicateQuery = `
+	SELECT linkID FROM documents
+	WHERE linkID <> $1
+		AND (fp_band0 = $4 OR fp_band1 = $5 OR fp_band2 = $6 OR fp_band3 = $7)
+		AND bit_count((fingerprint # $2)::bit(64)) <= $3
+	ORDER BY bit_count((fingerprint # $2)::bit(64)), indexed_at
+	LIMIT 1
+`
+
+// like nearDuplicateQuery for distances the bands do not cover, it scans
+// every fingerprint
+const nearDuplicateScanQuery = `
+	SELECT linkID FROM documents
+	WHERE linkID <> $1
+		AND fingerprint IS NOT NULL
+		AND bit_count((fingerprint # $2)::bit(64)) <= $3
+	ORDER BY bit_count((fingerprint # $2)::bit(64)), indexed_at
+	LIMIT 1
+`
+
+const deleteAliasQuery = `
+	DELETE FROM document_aliases
+	WHERE linkID = $1
+`
+
+const upsertAliasQuery = `
+	INSERT INTO document_aliases (linkID, canonical_linkID, updated_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (linkID) DO
+	UPDATE
+		SET canonical_linkID = EXCLUDED.canonical_linkID,
+			updated_at = EXCLUDED.updated_at;
+`
+
+const mediaTypesQuery = `
+	SELECT linkID, media_type FROM documents
+	WHERE linkID = ANY($1::uuid[])
+`
+
+const metadataQuery = `
+	SELECT linkID, coalesce(description, ''), coalesce(headings, ''), opengraph, twitter,
+		published_at, modified_at, coalesce(author, ''), coalesce(favicon, ''), entities
+	FROM documents
+	WHERE linkID = ANY($1::uuid[])
+`
+
+const deleteDocumentQuery = `
+	DELETE FROM documents
+	WHERE linkID = $1
+`
@@ -0,0 +1,101 @@
+package indexpostgre
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// the text search configuration of each language documents are analyzed in,
+// by ISO 639-1 code. Documents of an unknown language are analyzed as English.
+var languageConfigs = map[string]string{
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fr": "french",
+	"id": "indonesian",
+	"it": "italian",
+	"nl": "dutch",
+	"pt": "portuguese",
+}
+
+var default_config = "english"
+
+// tsConfig returns the text search configuration of a language.
+func tsConfig(lang string) string {
+	if config, ok := languageConfigs[lang]; ok {
+		return config
+	}
+	return default_config
+}
+
+// the schema.org types the type: operator accepts, e.g. "Article"
+var schemaType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// search is a query expression split into its search terms and operators,
+// e.g. "kucing lucu lang:id type:Recipe".
+type search struct {
+	terms string
+
+	// the lang: operator, empty to search every language
+	language string
+
+	// the type: operators in lower case, documents must declare an entity
+	// of every type
+	types []string
+}
+
+func parseSearch(expression string) search {
+	var s search
+	terms := make([]string, 0)
+	for _, field := range strings.Fields(expression) {
+		key, value, ok := strings.Cut(field, ":")
+		if ok && value != "" && strings.EqualFold(key, "lang") {
+			s.language = strings.ToLower(value)
+			continue
+		}
+		if ok && value != "" && strings.EqualFold(key, "type") {
+			s.types = append(s.types, strings.ToLower(value))
+			continue
+		}
+		terms = append(terms, field)
+	}
+	s.terms = strings.Join(terms, " ")
+	return s
+}
+
+// tsquery returns the tsquery of the search terms ($1) built with fn, e.g.
+// websearch_to_tsquery. Without a language filter the terms are parsed with
+// the configuration of each document, so they match documents of any language
+// the way a search in that language does, negated terms and stopwords
+// included.
+func (s search) tsquery(fn string) string {
+	if s.language != "" {
+		return fmt.Sprintf("%s('%s', $1)", fn, tsConfig(s.language))
+	}
+	return fmt.Sprintf("%s(ts_config, $1)", fn)
+}
+
+// filter returns the condition documents must match besides the search terms.
+// Only known values are written into the query.
+func (s search) filter() string {
+	var conds []string
+	if s.language != "" {
+		if _, ok := languageConfigs[s.language]; !ok {
+			return "FALSE"
+		}
+		conds = append(conds, fmt.Sprintf("language = '%s'", s.language))
+	}
+	if len(s.types) > 0 {
+		for _, t := range s.types {
+			if !schemaType.MatchString(t) {
+				return "FALSE"
+			}
+		}
+		conds = append(conds, fmt.Sprintf("entity_types @> ARRAY['%s']", strings.Join(s.types, "', '")))
+	}
+	if len(conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conds, " AND ")
+}
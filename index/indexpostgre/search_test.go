@@ -0,0 +1,66 @@
+package indexpostgre
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_parse_search(t *testing.T) {
+	tests := []struct {
+		expression string
+		terms      string
+		language   string
+	}{
+		{"kucing lucu", "kucing lucu", ""},
+		{"kucing LANG:ID lucu", "kucing lucu", "id"},
+		{"lang:en", "", "en"},
+		{`"lang:" url:example`, `"lang:" url:example`, ""},
+	}
+	for _, tt := range tests {
+		s := parseSearch(tt.expression)
+		if s.terms != tt.terms || s.language != tt.language {
+			t.Fatalf("\ngot:%v %v \nexpect:%v %v", s.terms, s.language, tt.terms, tt.language)
+		}
+	}
+
+	t.Run("test_tsquery", func(t *testing.T) {
+		s := parseSearch("kucing lang:id")
+		if got := s.tsquery("websearch_to_tsquery"); got != "websearch_to_tsquery('indonesian', $1)" {
+			t.Fatalf("\ngot:%v", got)
+		}
+		if got := s.filter(); got != "language = 'id'" {
+			t.Fatalf("\ngot:%v", got)
+		}
+
+		// every document is matched in its own language
+		all := parseSearch("kucing").tsquery("phraseto_tsquery")
+		expect := "phraseto_tsquery(ts_config, $1)"
+		if all != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", all, expect)
+		}
+	})
+
+	t.Run("test_type", func(t *testing.T) {
+		s := parseSearch("kue type:Recipe lang:id TYPE:howto")
+		if s.terms != "kue" || fmt.Sprint(s.types) != "[recipe howto]" {
+			t.Fatalf("\ngot:%v %v", s.terms, s.types)
+		}
+		expect := "language = 'id' AND entity_types @> ARRAY['recipe', 'howto']"
+		if got := s.filter(); got != expect {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, expect)
+		}
+		if got := parseSearch("kue type:x'];--").filter(); got != "FALSE" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, "FALSE")
+		}
+	})
+
+	t.Run("test_unknown_language", func(t *testing.T) {
+		s := parseSearch("kucing lang:xx'; DROP TABLE documents; --")
+		if got := s.filter(); got != "FALSE" {
+			t.Fatalf("\ngot:%v \nexpect:%v", got, "FALSE")
+		}
+		if got := s.tsquery("websearch_to_tsquery"); got != "websearch_to_tsquery('english', $1)" {
+			t.Fatalf("\ngot:%v", got)
+		}
+	})
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/odit-bit/indexstore"
+	"github.com/odit-bit/se/index/indexapi"
 	"github.com/odit-bit/se/index/indexpostgre"
 )
 
@@ -20,7 +22,23 @@ func main() {
 		log.Fatal(err)
 	}
 
-	indexer, _ := indexpostgre.New(db)
+	indexer, err := indexpostgre.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the documents of crawled pages are written through the HTTP API, it
+	// carries what the gRPC service does not know about a page
+	apiAddress := os.Getenv("INDEXAPI_ADDRESS")
+	if apiAddress == "" {
+		apiAddress = ":8384"
+	}
+	go func() {
+		if err := http.ListenAndServe(apiAddress, indexapi.NewHandler(indexer)); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
 	idxSrv := indexstore.Server{
 		Port:    8383,
 		Handler: indexer,
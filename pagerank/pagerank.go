@@ -255,6 +255,10 @@ func (svc *Service) loadLinks(fromID, toID uuid.UUID, filter time.Time) error {
 	return linkIt.Close()
 }
 
+// loadEdges adds the edges of the graph to the calculator. The edges of links
+// a page asked not to be followed (rel=nofollow, ugc or sponsored and pages
+// declaring nofollow) are flagged in the graph and not returned by it, they do
+// not pass on rank.
 func (svc *Service) loadEdges(fromID, toID uuid.UUID, filter time.Time) error {
 	edgeIt, err := svc.cfg.GraphAPI.Edges(fromID, toID, filter)
 	if err != nil {
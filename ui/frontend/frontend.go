@@ -9,13 +9,18 @@ import (
 	"log"
 	"net"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/odit-bit/indexstore/index"
 	"github.com/odit-bit/linkstore/linkgraph"
+	"github.com/odit-bit/se/crawler/extract"
+	"github.com/odit-bit/se/crawler/submission"
+	"github.com/odit-bit/se/graph/canonical"
+	"github.com/odit-bit/se/index/docmeta"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/multierr"
 )
@@ -23,6 +28,7 @@ import (
 var (
 	searchEndpoint     = "/search"
 	submitLinkEndpoint = "/submit/site"
+	submissionEndpoint = "/submit/status"
 	indexEndpoint      = "/"
 	metricEndpoint     = "/prom"
 
@@ -38,6 +44,29 @@ type IndexAPI interface {
 	Search(index.Query) (index.Iterator, error)
 }
 
+// MediaTypeFinder is implemented by an IndexAPI that knows the media type of
+// the indexed documents, e.g. one backed by an indexapi.Client. Without it the
+// media type of a result is guessed from its URL.
+type MediaTypeFinder interface {
+	MediaTypes(linkIDs []uuid.UUID) (map[uuid.UUID]string, error)
+}
+
+// MetadataFinder is implemented by an IndexAPI that knows the metadata of the
+// indexed pages, e.g. one backed by an indexapi.Client. With it the meta
+// description of a result is shown when its text does not match the search
+// terms, along with its publish date.
+type MetadataFinder interface {
+	Metadata(linkIDs []uuid.UUID) (map[uuid.UUID]*docmeta.Metadata, error)
+}
+
+// SubmissionQueue is the queue of links and sitemaps submitted by users that
+// the crawler drains ahead of its passes, e.g. a submissionapi.Client.
+type SubmissionQueue interface {
+	Submit(linkID uuid.UUID, url string) (*submission.Submission, error)
+	Submission(id uuid.UUID) (*submission.Submission, error)
+	SubmitSitemap(url string) error
+}
+
 // Config encapsulates the settings for configuring the front-end service.
 type Config struct {
 	// An API for adding links to the link graph.
@@ -46,6 +75,12 @@ type Config struct {
 	// An API for executing queries against indexed documents.
 	IndexAPI IndexAPI
 
+	// A queue submitted web sites and sitemaps are added to. If specified, a
+	// submitted web site is crawled within seconds and the submitter is shown
+	// the progress of its crawl. Otherwise it is only added to the link graph
+	// and sitemaps can not be submitted.
+	Submissions SubmissionQueue
+
 	// The port to listen for incoming requests.
 	ListenAddr string
 
@@ -58,6 +93,11 @@ type Config struct {
 	// instead.
 	MaxSummaryLength int
 
+	// The query parameters removed from submitted URLs. A trailing '*'
+	// matches any parameter with that prefix. If not specified,
+	// canonical.DefaultTrackingParams will be used instead.
+	TrackingParams []string
+
 	// The logger to use. If not defined an output-discarding logger will
 	// be used instead.
 	// Logger *logrus.Entry
@@ -74,6 +114,9 @@ func (cfg *Config) validate() error {
 	if cfg.MaxSummaryLength <= 0 {
 		cfg.MaxSummaryLength = defaultMaxSummaryLength
 	}
+	if len(cfg.TrackingParams) == 0 {
+		cfg.TrackingParams = canonical.DefaultTrackingParams
+	}
 	if cfg.IndexAPI == nil {
 		err = multierr.Append(err, fmt.Errorf("index API has not been provided"))
 	}
@@ -89,6 +132,7 @@ func (cfg *Config) validate() error {
 type API struct {
 	router       *chi.Mux
 	cfg          Config
+	canon        *canonical.Canonicalizer
 	templateFunc func(tpl *template.Template, w io.Writer, data map[string]interface{}) error
 }
 
@@ -107,6 +151,11 @@ func NewDefault(graphDB GraphAPI, indexDB IndexAPI) *API {
 	return fr
 }
 
+// NewWithConfig creates a front-end service with the specified config.
+func NewWithConfig(cfg Config) (*API, error) {
+	return new(cfg)
+}
+
 func new(cfg Config) (*API, error) {
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -115,6 +164,7 @@ func new(cfg Config) (*API, error) {
 	a := API{
 		router: chi.NewMux(),
 		cfg:    cfg,
+		canon:  canonical.New(cfg.TrackingParams),
 		templateFunc: func(tpl *template.Template, w io.Writer, data map[string]interface{}) error {
 			return tpl.Execute(w, data)
 		},
@@ -128,6 +178,8 @@ func new(cfg Config) (*API, error) {
 
 	a.router.Post(submitLinkEndpoint, a.submitLink)
 
+	a.router.Get(submissionEndpoint+"/{id}", a.renderSubmission)
+
 	a.router.Get(metricEndpoint, a.metricPrometheus())
 
 	a.router.HandleFunc("/index/json", a.indexJSON())
@@ -245,7 +297,11 @@ func (a *API) renderSearchErrorPage(w http.ResponseWriter, searchTerms string) {
 
 func (a *API) submitLink(w http.ResponseWriter, r *http.Request) {
 	var msg string
+	var redirected bool
 	defer func() {
+		if redirected {
+			return
+		}
 		_ = a.templateFunc(submitLinkPageTemplate, w, map[string]interface{}{
 			"indexEndpoint":      indexEndpoint,
 			"submitLinkEndpoint": submitLinkEndpoint,
@@ -259,27 +315,105 @@ func (a *API) submitLink(w http.ResponseWriter, r *http.Request) {
 			msg = "Invalid web site URL."
 			return
 		}
-		link, err := url.Parse(r.Form.Get("link"))
-		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+		link, err := a.canon.URL(r.Form.Get("link"))
+		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			msg = "Invalid web site URL."
 			return
 		}
 
-		link.Fragment = ""
-		if err = a.cfg.GraphAPI.UpsertLink(&linkgraph.Link{URL: link.String()}); err != nil {
+		if r.Form.Get("sitemap") != "" {
+			msg = a.submitSitemap(w, link)
+			return
+		}
+
+		l := &linkgraph.Link{URL: link}
+		if err = a.cfg.GraphAPI.UpsertLink(l); err != nil {
 			// a.cfg.Logger.WithField("err", err).Errorf("could not upsert link into link graph")
 			w.WriteHeader(http.StatusInternalServerError)
 			msg = "An error occurred while adding web site to our index; please try again later."
 			return
 		}
 
+		// the submitter follows the crawl of the web site on its status page
+		if a.cfg.Submissions != nil {
+			sub, err := a.cfg.Submissions.Submit(l.ID, link)
+			if err != nil {
+				log.Println("submit link:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				msg = "An error occurred while adding web site to our index; please try again later."
+				return
+			}
+			redirected = true
+			http.Redirect(w, r, submissionEndpoint+"/"+sub.ID.String(), http.StatusSeeOther)
+			return
+		}
+
 		msg = "Web site was successfully submitted!"
 	} else {
 		w.WriteHeader(http.StatusBadRequest)
 	}
 }
 
+// renderSubmission renders the progress of a submitted web site, the page
+// reloads itself until the crawl of the web site is done.
+func (a *API) renderSubmission(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil || a.cfg.Submissions == nil {
+		a.render404Page(w, r)
+		return
+	}
+	sub, err := a.cfg.Submissions.Submission(id)
+	if err != nil {
+		log.Println("submission:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = a.templateFunc(msgPageTemplate, w, map[string]interface{}{
+			"indexEndpoint":  indexEndpoint,
+			"searchEndpoint": searchEndpoint,
+			"messageTitle":   "Error",
+			"messageContent": "An error occurred; please try again later.",
+		})
+		return
+	}
+	if sub == nil {
+		a.render404Page(w, r)
+		return
+	}
+
+	_ = a.templateFunc(submissionPageTemplate, w, map[string]interface{}{
+		"indexEndpoint":      indexEndpoint,
+		"submitLinkEndpoint": submitLinkEndpoint,
+		"submission":         sub,
+		"done":               sub.State.Done(),
+		"state":              submissionStates[sub.State],
+	})
+}
+
+// the descriptions of the submission states shown to the submitter
+var submissionStates = map[submission.State]string{
+	submission.Queued:    "Waiting to be crawled",
+	submission.Fetching:  "Crawling",
+	submission.Indexed:   "Indexed",
+	submission.Failed:    "Not indexed",
+	submission.Unchanged: "Not changed since its last crawl",
+}
+
+// submitSitemap queues the sitemap at link for the crawler, which reads it
+// and adds the URLs it lists to the link graph in the background, and returns
+// the message for the submit page.
+func (a *API) submitSitemap(w http.ResponseWriter, link string) string {
+	if a.cfg.Submissions == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return "Sitemaps can not be submitted at the moment."
+	}
+	if err := a.cfg.Submissions.SubmitSitemap(link); err != nil {
+		log.Println("submit sitemap:", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return "An error occurred while adding the sitemap to our index; please try again later."
+	}
+	return "Sitemap was successfully submitted, its pages will be crawled shortly!"
+}
+
 func (a *API) renderSearchResults(w http.ResponseWriter, r *http.Request) {
 	searchTerms := r.URL.Query().Get("q")
 	offset, _ := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
@@ -337,6 +471,8 @@ func (a *API) runQuery(searchTerms string, offset uint64) ([]matchedDoc, *pagina
 	if err = resultIt.Error(); err != nil {
 		return nil, nil, err
 	}
+	a.setMediaTypes(matchedDocs)
+	a.setMetadata(matchedDocs, highlighter)
 
 	// Setup paginator and generate prev/next links
 	pagination := &paginationDetails{
@@ -357,6 +493,60 @@ func (a *API) runQuery(searchTerms string, offset uint64) ([]matchedDoc, *pagina
 	return matchedDocs, pagination, nil
 }
 
+// setMediaTypes sets the media type of the matched documents.
+func (a *API) setMediaTypes(docs []matchedDoc) {
+	var types map[uuid.UUID]string
+	if finder, ok := a.cfg.IndexAPI.(MediaTypeFinder); ok && len(docs) > 0 {
+		ids := make([]uuid.UUID, len(docs))
+		for i, d := range docs {
+			ids[i] = d.doc.LinkID
+		}
+		var err error
+		if types, err = finder.MediaTypes(ids); err != nil {
+			log.Println("media types:", err)
+		}
+	}
+
+	for i, d := range docs {
+		mediaType, ok := types[d.doc.LinkID]
+		if !ok {
+			mediaType = extract.Default().MediaType("", d.doc.URL)
+		}
+		docs[i].mediaType = mediaType
+	}
+}
+
+// setMetadata sets the publish date and the rich card facts of the matched
+// documents and falls back to their meta description when their text has no
+// matching summary.
+func (a *API) setMetadata(docs []matchedDoc, highlighter *matchHighlighter) {
+	finder, ok := a.cfg.IndexAPI.(MetadataFinder)
+	if !ok || len(docs) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.doc.LinkID
+	}
+	metas, err := finder.Metadata(ids)
+	if err != nil {
+		log.Println("metadata:", err)
+		return
+	}
+
+	for i, d := range docs {
+		meta, ok := metas[d.doc.LinkID]
+		if !ok {
+			continue
+		}
+		docs[i].published = meta.Published
+		docs[i].facts = cardFacts(meta.Entities)
+		if d.summary == "" && meta.Description != "" {
+			docs[i].summary = highlighter.Highlight(template.HTMLEscapeString(meta.Description))
+		}
+	}
+}
+
 // paginationDetails encapsulates the details for rendering a paginator component.
 type paginationDetails struct {
 	From     int
@@ -369,8 +559,15 @@ type paginationDetails struct {
 // mathcedDoc wraps an index.Document and provides convenience methods for
 // rendering its contents in a search results view.
 type matchedDoc struct {
-	doc     *index.Document
-	summary string
+	doc       *index.Document
+	summary   string
+	mediaType string
+
+	// zero if the page declares no publish date
+	published time.Time
+
+	// the facts of the schema.org entity the page describes, see cardFacts
+	facts []string
 }
 
 func (d *matchedDoc) HighlightedSummary() template.HTML { return template.HTML(d.summary) }
@@ -381,3 +578,30 @@ func (d *matchedDoc) Title() string {
 	}
 	return d.doc.URL
 }
+
+// Published returns the publish date shown next to the URL of a document,
+// empty if it is unknown.
+func (d *matchedDoc) Published() string {
+	if d.published.IsZero() {
+		return ""
+	}
+	return d.published.Format("Jan 2, 2006")
+}
+
+// Facts returns the facts of the rich card of a document, empty if the page
+// describes no entity of a known type.
+func (d *matchedDoc) Facts() []string { return d.facts }
+
+// Label returns the label shown next to the title of a document that is not
+// an HTML page.
+func (d *matchedDoc) Label() string {
+	switch d.mediaType {
+	case "application/pdf":
+		return "PDF"
+	case "text/markdown", "text/x-markdown":
+		return "Markdown"
+	case "text/plain":
+		return "Text"
+	}
+	return ""
+}
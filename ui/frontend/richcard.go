@@ -0,0 +1,121 @@
+package frontend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/odit-bit/se/index/docmeta"
+)
+
+// the maximum number of facts shown on the card of a result
+const maxCardFacts = 4
+
+// an ISO 8601 duration of hours and minutes, e.g. "PT1H30M"
+var isoDuration = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?`)
+
+// cardFacts returns the facts shown below the title of a result that
+// describes an entity, e.g. the price and rating of a product. The first
+// entity of a known type is shown, nil if the entities include none.
+func cardFacts(entities []docmeta.Entity) []string {
+	for _, e := range entities {
+		var facts []string
+		switch {
+		case e.Is("Product"):
+			facts = append(facts, "Product", offerOf(e), ratingOf(e), schemaEnum(e.Property("offers").Text("availability")))
+		case e.Is("Recipe"):
+			facts = append(facts, "Recipe", ratingOf(e), durationOf(e.Text("totalTime")), e.Text("recipeYield"))
+		case e.Is("Article"), e.Is("NewsArticle"), e.Is("BlogPosting"):
+			facts = append(facts, "Article", e.Text("author"), e.Property("publisher").Text("name"))
+		case e.Is("FAQPage"):
+			facts = append(facts, "FAQ", countOf(e["mainEntity"], "question"))
+		case e.Is("Event"):
+			facts = append(facts, "Event", e.Text("startDate"), e.Text("location"))
+		default:
+			continue
+		}
+		return compact(facts)
+	}
+	return nil
+}
+
+// offerOf returns the price of the offer of e, e.g. "19.90 EUR".
+func offerOf(e docmeta.Entity) string {
+	offer := e.Property("offers")
+	price := offer.Text("price")
+	if price == "" {
+		price = offer.Text("lowPrice")
+	}
+	if price == "" {
+		return ""
+	}
+	return strings.TrimSpace(price + " " + offer.Text("priceCurrency"))
+}
+
+// ratingOf returns the aggregate rating of e, e.g. "4.5/5 (120 reviews)".
+func ratingOf(e docmeta.Entity) string {
+	rating := e.Property("aggregateRating")
+	value := rating.Text("ratingValue")
+	if value == "" {
+		return ""
+	}
+	if best := rating.Text("bestRating"); best != "" {
+		value += "/" + best
+	}
+	count := rating.Text("reviewCount")
+	if count == "" {
+		count = rating.Text("ratingCount")
+	}
+	if count != "" {
+		value += " (" + count + " reviews)"
+	}
+	return "Rating " + value
+}
+
+// durationOf formats an ISO 8601 duration, e.g. "PT1H30M" as "1 h 30 min".
+func durationOf(s string) string {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || m[1] == "" && m[2] == "" {
+		return ""
+	}
+	var parts []string
+	if m[1] != "" {
+		parts = append(parts, m[1]+" h")
+	}
+	if m[2] != "" {
+		parts = append(parts, m[2]+" min")
+	}
+	return strings.Join(parts, " ")
+}
+
+// countOf returns the number of values of a property, e.g. "3 questions".
+func countOf(v any, noun string) string {
+	n := 1
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []any:
+		n = len(v)
+	}
+	if n != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("%d %s", n, noun)
+}
+
+// schemaEnum returns the name of a schema.org enumeration member, e.g.
+// "InStock" for "https://schema.org/InStock".
+func schemaEnum(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
+
+// compact drops the empty facts and keeps at most maxCardFacts.
+func compact(facts []string) []string {
+	kept := facts[:0]
+	for _, f := range facts {
+		if f != "" && len(kept) < maxCardFacts {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
@@ -75,7 +75,7 @@ var (
       </section>
       <section class="is">
       <form action="{{.searchEndpoint}}">
-        <input class="t" type="text" name="q" placeholder="Enter search ex: github" value="{{.searchTerms}}"/>
+        <input class="t" type="text" name="q" placeholder="Enter search ex: github lang:en" value="{{.searchTerms}}"/>
         <input class="sb" type="submit" value="Search"/>
       </form>
       </section>
@@ -109,8 +109,10 @@ var (
       hr{border:1px solid gray;}
       .rc{padding:10px 20px;}
       .rc .rt {color:grey;font-size:0.9em;}
+			.rc .mt {font-weight:normal;color:#70757a;}
 			.rc .ml {text-decoration:none;display:inline-block;font-size:1.0em;font-weight:bold;margin-bottom:0;text-overflow:ellipsis;white-space:nowrap;overflow:hidden;}
 			.rc cite{color:green;font-size:0.8em;display:block;margin-bottom:2px;}
+			.rc .rf {color:#70757a;font-size:0.8em;margin-bottom:2px;}
 			.rc .ms {text-align:justify;font-size:0.9em;}
 			.rc .ms em{background-color:yellow;font-weight:bold;}
 			.nb{padding:15px 20px;border-top:1px solid gray;}
@@ -141,8 +143,9 @@ var (
     </section>
 		{{range .results}}
     <section class="rc">
-      <a class="ml" rel="nofollow" href="{{.URL}}">{{.Title}}</a>
-			<cite>{{.URL}}</cite>
+      <a class="ml" rel="nofollow" href="{{.URL}}">{{with .Label}}<span class="mt">[{{.}}]</span> {{end}}{{.Title}}</a>
+			<cite>{{.URL}}{{with .Published}} &middot; {{.}}{{end}}</cite>
+			{{with .Facts}}<div class="rf">{{range $i, $f := .}}{{if $i}} &middot; {{end}}<span>{{$f}}</span>{{end}}</div>{{end}}
       <section class="ms">{{.HighlightedSummary}}</section>
     </section>
 		{{end}}
@@ -195,11 +198,51 @@ var (
         <legend>submit a web site</legend>
         <input class="t" type="text" required="true" name="link" placeholder="https://"/>
 				<br/>
+        <label><input type="checkbox" name="sitemap"/> this is a sitemap</label>
+				<br/>
         <input class="sb" type="submit" value="Submit"/>
         </fieldset>
       </form>
     </section>
   </body>
 </html>
+`))
+
+	submissionPageTemplate = template.Must(template.New("submission").Parse(`
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    {{if not .done}}<meta http-equiv="refresh" content="2">{{end}}
+    <title>demo Seacrh-Engine | Submission</title>
+    <style>
+      .l{font-size:3em;font-weight:bold;text-align:center;text-shadow: 1px 1px 1px rgba(0,0,0,0.4);}
+      .l a{text-decoration: none;}
+      .r{color:red;}
+      .b{color:blue;}
+      .tc{margin-top:20px;text-align:center;}
+      .st{font-size:1.5em;margin:10px 0;}
+      .indexed{color:green;}
+      .failed{color:red;}
+      .u{color:#006621;}
+      .rs{color:#545454;font-size:0.9em;}
+  </style>
+  </head>
+  <body>
+    <header class="l">
+     <a href="{{.indexEndpoint}}">
+     <span class="b">demo</span> <span class="r"></span>
+     <span class="r">Search Engine</span>
+    </a>
+    </header>
+    <section class="tc">
+      <div class="u">{{.submission.URL}}</div>
+      <div class="st {{.submission.State}}">{{.state}}</div>
+      {{if .submission.Reason}}<div class="rs">{{.submission.Reason}}</div>{{end}}
+      <div class="rs">submitted {{.submission.SubmittedAt.Format "Jan 2, 2006 15:04:05 MST"}}</div>
+      {{if .done}}<br/><a href="{{.submitLinkEndpoint}}">Submit another web site</a>{{end}}
+    </section>
+  </body>
+</html>
 `))
 )
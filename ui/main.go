@@ -8,7 +8,10 @@ import (
 	"syscall"
 
 	"github.com/odit-bit/indexstore"
+	"github.com/odit-bit/indexstore/index"
 	"github.com/odit-bit/linkstore"
+	"github.com/odit-bit/se/crawler/submissionapi"
+	"github.com/odit-bit/se/index/indexapi"
 	"github.com/odit-bit/se/ui/frontend"
 )
 
@@ -33,11 +36,35 @@ func main() {
 		log.Fatal("failed connect to index server")
 	}
 
+	// the media types and metadata of the results are read through the
+	// HTTP API of the index service, the gRPC service only knows their text
+	var searchAPI frontend.IndexAPI = indexAPI
+	if addr := os.Getenv("INDEXAPI_SERVER_ADDRESS"); addr != "" {
+		searchAPI = indexClient{Indexer: indexAPI, Client: indexapi.NewClient(addr)}
+	}
+
 	// create frontend instance to server html for user
-	ui := frontend.NewDefault(graphAPI, indexAPI)
+	conf := frontend.Config{
+		GraphAPI:         graphAPI,
+		IndexAPI:         searchAPI,
+		ListenAddr:       ":8080",
+		ResultsPerPage:   10,
+		MaxSummaryLength: 256,
+	}
+
+	// through the HTTP API of the crawler submitted sites are queued for the
+	// crawler's submission lane and their progress is shown
+	if addr := os.Getenv("SUBMISSIONAPI_SERVER_ADDRESS"); addr != "" {
+		conf.Submissions = submissionapi.NewClient(addr)
+	}
+
+	ui, err := frontend.NewWithConfig(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	sigC := make(chan os.Signal, 1)
-	signal.Notify(sigC, syscall.SIGINT)
+	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-sigC
@@ -49,3 +76,15 @@ func main() {
 	}
 
 }
+
+var (
+	_ frontend.MediaTypeFinder = indexClient{}
+	_ frontend.MetadataFinder  = indexClient{}
+)
+
+// indexClient is the index service, the gRPC service along with the HTTP API
+// of what it does not know.
+type indexClient struct {
+	index.Indexer
+	*indexapi.Client
+}